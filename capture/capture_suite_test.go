@@ -0,0 +1,13 @@
+package capture_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Capture Suite")
+}
@@ -0,0 +1,123 @@
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+)
+
+const maxEnvelopeSize = 64 * 1024 * 1024
+
+// EnvelopeReader reads the envelopes written by an EnvelopeWriter, following its rotated
+// files in order. Gzip compressed files are detected automatically.
+type EnvelopeReader struct {
+	path     string
+	sequence int
+	file     *os.File
+	reader   *bufio.Reader
+}
+
+func NewEnvelopeReader(path string) (*EnvelopeReader, error) {
+	r := &EnvelopeReader{
+		path: path,
+	}
+
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Next returns the next envelope, or io.EOF when all files have been read.
+func (r *EnvelopeReader) Next() (*events.Envelope, error) {
+	for {
+		if r.file == nil {
+			return nil, io.EOF
+		}
+
+		size, err := binary.ReadUvarint(r.reader)
+		if err == io.EOF {
+			if err := r.next(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if size > maxEnvelopeSize {
+			return nil, fmt.Errorf("Envelope size %d in `%s` exceeds the maximum size", size, r.file.Name())
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r.reader, data); err != nil {
+			return nil, err
+		}
+
+		envelope := &events.Envelope{}
+		if err := proto.Unmarshal(data, envelope); err != nil {
+			return nil, err
+		}
+
+		return envelope, nil
+	}
+}
+
+func (r *EnvelopeReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+
+	return err
+}
+
+func (r *EnvelopeReader) open() error {
+	path := r.path
+	if r.sequence > 0 {
+		path = fmt.Sprintf("%s.%d", r.path, r.sequence)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	r.file = file
+	r.reader = bufio.NewReader(file)
+
+	magic, err := r.reader.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(r.reader)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		r.reader = bufio.NewReader(gzipReader)
+	}
+
+	return nil
+}
+
+func (r *EnvelopeReader) next() error {
+	if err := r.Close(); err != nil {
+		return err
+	}
+
+	r.sequence++
+	err := r.open()
+	if os.IsNotExist(err) {
+		return io.EOF
+	}
+
+	return err
+}
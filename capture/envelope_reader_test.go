@@ -0,0 +1,74 @@
+package capture_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/capture"
+)
+
+var _ = Describe("EnvelopeReader", func() {
+	var (
+		err  error
+		dir  string
+		path string
+	)
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "capture")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "envelopes.pb")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("when the file does not exist", func() {
+		It("returns an error", func() {
+			_, err := NewEnvelopeReader(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the file is empty", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(path, []byte{}, 0644)).To(Succeed())
+		})
+
+		It("returns io.EOF", func() {
+			envelopeReader, err := NewEnvelopeReader(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = envelopeReader.Next()
+			Expect(err).To(Equal(io.EOF))
+		})
+	})
+
+	Context("when the file is truncated", func() {
+		BeforeEach(func() {
+			envelopeWriter, err := NewEnvelopeWriter(path, false, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(envelopeWriter.Write(newValueMetricEnvelope(0))).To(Succeed())
+			Expect(envelopeWriter.Close()).To(Succeed())
+
+			data, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ioutil.WriteFile(path, data[:len(data)-1], 0644)).To(Succeed())
+		})
+
+		It("returns an error", func() {
+			envelopeReader, err := NewEnvelopeReader(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = envelopeReader.Next()
+			Expect(err).To(HaveOccurred())
+			Expect(err).ToNot(Equal(io.EOF))
+		})
+	})
+})
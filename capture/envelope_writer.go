@@ -0,0 +1,154 @@
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+)
+
+// EnvelopeWriter writes envelopes to a file as length delimited protobuf messages,
+// each one prefixed by its size encoded as an uvarint. When maxFileSize is reached,
+// the writer continues on a new file named after the original path and a sequence
+// number (`path.1`, `path.2`, ...). The size of a file is measured before compression.
+//
+// Rotated files left over by a previous capture to the same path are removed, so they
+// are not read back as part of the new capture.
+type EnvelopeWriter struct {
+	path        string
+	compress    bool
+	maxFileSize int64
+
+	lock     sync.Mutex
+	sequence int
+	file     *os.File
+	size     int64
+	gzip     *gzip.Writer
+	buffer   *bufio.Writer
+}
+
+func NewEnvelopeWriter(path string, compress bool, maxFileSize int64) (*EnvelopeWriter, error) {
+	w := &EnvelopeWriter{
+		path:        path,
+		compress:    compress,
+		maxFileSize: maxFileSize,
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *EnvelopeWriter) Write(envelope *events.Envelope) error {
+	data, err := proto.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return fmt.Errorf("Envelope writer `%s` is closed", w.path)
+	}
+
+	var size [binary.MaxVarintLen64]byte
+	n, err := w.buffer.Write(size[:binary.PutUvarint(size[:], uint64(len(data)))])
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	n, err = w.buffer.Write(data)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if w.maxFileSize > 0 && w.size >= w.maxFileSize {
+		return w.rotate()
+	}
+
+	return nil
+}
+
+func (w *EnvelopeWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.close()
+}
+
+func (w *EnvelopeWriter) open() error {
+	path := w.path
+	if w.sequence > 0 {
+		path = fmt.Sprintf("%s.%d", w.path, w.sequence)
+	} else if err := w.removeRotatedFiles(); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w.file = file
+	w.size = 0
+	var writer io.Writer = file
+	if w.compress {
+		w.gzip = gzip.NewWriter(file)
+		writer = w.gzip
+	}
+	w.buffer = bufio.NewWriter(writer)
+
+	return nil
+}
+
+func (w *EnvelopeWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	if err := w.buffer.Flush(); err != nil {
+		return err
+	}
+	if w.gzip != nil {
+		if err := w.gzip.Close(); err != nil {
+			return err
+		}
+		w.gzip = nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+func (w *EnvelopeWriter) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	w.sequence++
+	return w.open()
+}
+
+func (w *EnvelopeWriter) removeRotatedFiles() error {
+	for sequence := 1; ; sequence++ {
+		err := os.Remove(fmt.Sprintf("%s.%d", w.path, sequence))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
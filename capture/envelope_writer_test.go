@@ -0,0 +1,193 @@
+package capture_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/capture"
+)
+
+func newValueMetricEnvelope(i int) *events.Envelope {
+	return &events.Envelope{
+		Origin:     proto.String("fake-origin"),
+		EventType:  events.Envelope_ValueMetric.Enum(),
+		Timestamp:  proto.Int64(int64(1500000000000000000 + i)),
+		Deployment: proto.String("fake-deployment-name"),
+		Job:        proto.String("fake-job-name"),
+		Index:      proto.String("0"),
+		Ip:         proto.String("1.2.3.4"),
+		ValueMetric: &events.ValueMetric{
+			Name:  proto.String(fmt.Sprintf("fake-metric-%d", i)),
+			Value: proto.Float64(float64(i)),
+			Unit:  proto.String("counter"),
+		},
+	}
+}
+
+func readEnvelopes(path string) []*events.Envelope {
+	reader, err := NewEnvelopeReader(path)
+	Expect(err).ToNot(HaveOccurred())
+	defer reader.Close()
+
+	envelopes := []*events.Envelope{}
+	for {
+		envelope, err := reader.Next()
+		if err == io.EOF {
+			return envelopes
+		}
+		Expect(err).ToNot(HaveOccurred())
+		envelopes = append(envelopes, envelope)
+	}
+}
+
+var _ = Describe("EnvelopeWriter", func() {
+	var (
+		err          error
+		dir          string
+		path         string
+		compress     bool
+		maxFileSize  int64
+		numEnvelopes = 10
+
+		envelopeWriter *EnvelopeWriter
+	)
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "capture")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "envelopes.pb")
+		compress = false
+		maxFileSize = 0
+	})
+
+	JustBeforeEach(func() {
+		envelopeWriter, err = NewEnvelopeWriter(path, compress, maxFileSize)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < numEnvelopes; i++ {
+			Expect(envelopeWriter.Write(newValueMetricEnvelope(i))).To(Succeed())
+		}
+		Expect(envelopeWriter.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("writes the envelopes", func() {
+		envelopes := readEnvelopes(path)
+		Expect(envelopes).To(HaveLen(numEnvelopes))
+		for i, envelope := range envelopes {
+			Expect(envelope).To(Equal(newValueMetricEnvelope(i)))
+		}
+	})
+
+	It("does not rotate the file", func() {
+		Expect(path + ".1").ToNot(BeAnExistingFile())
+	})
+
+	It("returns an error when writing after close", func() {
+		Expect(envelopeWriter.Write(newValueMetricEnvelope(0))).ToNot(Succeed())
+	})
+
+	Context("when compression is enabled", func() {
+		BeforeEach(func() {
+			compress = true
+		})
+
+		It("writes a gzip file", func() {
+			data, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data[:2]).To(Equal([]byte{0x1f, 0x8b}))
+		})
+
+		It("writes the envelopes", func() {
+			envelopes := readEnvelopes(path)
+			Expect(envelopes).To(HaveLen(numEnvelopes))
+			for i, envelope := range envelopes {
+				Expect(envelope).To(Equal(newValueMetricEnvelope(i)))
+			}
+		})
+	})
+
+	Context("when a maximum file size is set", func() {
+		BeforeEach(func() {
+			maxFileSize = 256
+		})
+
+		It("rotates the file", func() {
+			Expect(path + ".1").To(BeAnExistingFile())
+
+			info, err := os.Stat(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size()).To(BeNumerically("<", 256+128))
+		})
+
+		It("writes the envelopes across the rotated files", func() {
+			envelopes := readEnvelopes(path)
+			Expect(envelopes).To(HaveLen(numEnvelopes))
+			for i, envelope := range envelopes {
+				Expect(envelope).To(Equal(newValueMetricEnvelope(i)))
+			}
+		})
+	})
+
+	Context("when a maximum file size is set with compression enabled", func() {
+		BeforeEach(func() {
+			compress = true
+			maxFileSize = 256
+		})
+
+		It("rotates the file once the uncompressed size is reached", func() {
+			Expect(path + ".1").To(BeAnExistingFile())
+			Expect(path + ".2").To(BeAnExistingFile())
+		})
+
+		It("writes the envelopes across the rotated files", func() {
+			envelopes := readEnvelopes(path)
+			Expect(envelopes).To(HaveLen(numEnvelopes))
+			for i, envelope := range envelopes {
+				Expect(envelope).To(Equal(newValueMetricEnvelope(i)))
+			}
+		})
+	})
+
+	Context("when capturing again to the same path", func() {
+		BeforeEach(func() {
+			maxFileSize = 256
+		})
+
+		JustBeforeEach(func() {
+			Expect(path + ".1").To(BeAnExistingFile())
+
+			envelopeWriter, err = NewEnvelopeWriter(path, false, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(envelopeWriter.Write(newValueMetricEnvelope(numEnvelopes))).To(Succeed())
+			Expect(envelopeWriter.Close()).To(Succeed())
+		})
+
+		It("removes the rotated files of the previous capture", func() {
+			Expect(path + ".1").ToNot(BeAnExistingFile())
+		})
+
+		It("only reads back the envelopes of the new capture", func() {
+			Expect(readEnvelopes(path)).To(Equal([]*events.Envelope{newValueMetricEnvelope(numEnvelopes)}))
+		})
+	})
+
+	Context("when the file cannot be created", func() {
+		It("returns an error", func() {
+			_, err := NewEnvelopeWriter(filepath.Join(dir, "missing", "envelopes.pb"), false, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,219 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var (
+	aggregationRuleNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	aggregationOperations = map[string]bool{"sum": true, "min": true, "max": true, "avg": true, "count": true}
+
+	containerMetricLabels = []string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "application_id", "instance_id"}
+	valueMetricLabels     = []string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "unit"}
+
+	containerMetricNames = map[string]bool{
+		"cpu_percentage":     true,
+		"memory_bytes":       true,
+		"disk_bytes":         true,
+		"memory_bytes_quota": true,
+		"disk_bytes_quota":   true,
+	}
+)
+
+// AggregationRules compute series aggregated across instances from the Container Metrics and Value
+// Metrics held by the store, optionally replacing the raw series they aggregate.
+type AggregationRules struct {
+	rules []aggregationRule
+}
+
+type aggregationRule struct {
+	Name       string   `yaml:"name"`
+	EventType  string   `yaml:"event_type"`
+	Origin     string   `yaml:"origin"`
+	Deployment string   `yaml:"deployment"`
+	Metric     string   `yaml:"metric"`
+	Operation  string   `yaml:"operation"`
+	By         []string `yaml:"by"`
+	Replace    bool     `yaml:"replace"`
+
+	patterns []*filters.PatternFilter
+}
+
+type aggregationRulesFile struct {
+	Rules []aggregationRule `yaml:"rules"`
+}
+
+// LoadAggregationRules reads the aggregation rules from a YAML file.
+func LoadAggregationRules(path string) (*AggregationRules, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAggregationRules(data)
+}
+
+// ParseAggregationRules decodes YAML aggregation rules.
+func ParseAggregationRules(data []byte) (*AggregationRules, error) {
+	file := aggregationRulesFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if len(file.Rules) == 0 {
+		return nil, errors.New("No aggregation rules found")
+	}
+
+	names := map[string]bool{}
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("Aggregation rule #%d: %v", i+1, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("Aggregation rule #%d: name `%s` is used by another rule", i+1, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+
+	return &AggregationRules{rules: file.Rules}, nil
+}
+
+func (r *aggregationRule) validate() error {
+	if !aggregationRuleNameRE.MatchString(r.Name) {
+		return fmt.Errorf("name `%s` is not a valid metric name", r.Name)
+	}
+
+	var labels []string
+	switch r.EventType {
+	case events.Envelope_ContainerMetric.String():
+		if !containerMetricNames[r.Metric] {
+			return fmt.Errorf("metric `%s` is not a container metric", r.Metric)
+		}
+		labels = containerMetricLabels
+	case events.Envelope_ValueMetric.String():
+		if r.Metric == "" {
+			return fmt.Errorf("metric must be set")
+		}
+		labels = valueMetricLabels
+	default:
+		return fmt.Errorf("event type `%s` is not supported, must be ContainerMetric or ValueMetric", r.EventType)
+	}
+
+	if !aggregationOperations[r.Operation] {
+		return fmt.Errorf("operation `%s` is not supported, must be sum, min, max, avg or count", r.Operation)
+	}
+
+	for i, label := range r.By {
+		if !containsString(labels, label) {
+			return fmt.Errorf("cannot aggregate %s by `%s`", r.EventType, label)
+		}
+		if containsString(r.By[:i], label) {
+			return fmt.Errorf("label `%s` is repeated", label)
+		}
+	}
+
+	r.patterns = nil
+	for _, pattern := range []string{r.Origin, r.Deployment, r.Metric} {
+		filter, err := filters.NewSinglePatternFilter(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern `%s` is invalid", pattern)
+		}
+		r.patterns = append(r.patterns, filter)
+	}
+
+	return nil
+}
+
+func (r aggregationRule) matches(eventType events.Envelope_EventType, origin string, deployment string, metric string) bool {
+	return r.EventType == eventType.String() &&
+		r.patterns[0].Enabled(origin) &&
+		r.patterns[1].Enabled(deployment) &&
+		r.patterns[2].Enabled(metric)
+}
+
+func (r aggregationRule) description() string {
+	eventName := "container metric"
+	if r.EventType == events.Envelope_ValueMetric.String() {
+		eventName = "value metric"
+	}
+
+	if len(r.By) == 0 {
+		return fmt.Sprintf("Cloud Foundry Firehose %s of the '%s' %s.", r.Operation, r.Metric, eventName)
+	}
+	return fmt.Sprintf("Cloud Foundry Firehose %s of the '%s' %s by %s.", r.Operation, r.Metric, eventName, strings.Join(r.By, ", "))
+}
+
+// replaces returns whether the raw series of a metric are replaced by an aggregation rule.
+func (r *AggregationRules) replaces(eventType events.Envelope_EventType, origin string, deployment string, metric string) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, rule := range r.rules {
+		if rule.Replace && rule.matches(eventType, origin, deployment, metric) {
+			return true
+		}
+	}
+	return false
+}
+
+func containerMetricLabelValues(containerMetric metrics.ContainerMetric) map[string]string {
+	return map[string]string{
+		"origin":          containerMetric.Origin,
+		"bosh_deployment": containerMetric.Deployment,
+		"bosh_job":        containerMetric.Job,
+		"bosh_index":      containerMetric.Index,
+		"bosh_ip":         containerMetric.IP,
+		"application_id":  containerMetric.ApplicationId,
+		"instance_id":     strconv.Itoa(int(containerMetric.InstanceIndex)),
+	}
+}
+
+func containerMetricValue(containerMetric metrics.ContainerMetric, metric string) float64 {
+	switch metric {
+	case "cpu_percentage":
+		return containerMetric.CpuPercentage
+	case "memory_bytes":
+		return float64(containerMetric.MemoryBytes)
+	case "disk_bytes":
+		return float64(containerMetric.DiskBytes)
+	case "memory_bytes_quota":
+		return float64(containerMetric.MemoryBytesQuota)
+	case "disk_bytes_quota":
+		return float64(containerMetric.DiskBytesQuota)
+	}
+	return 0
+}
+
+func valueMetricLabelValues(valueMetric metrics.ValueMetric) map[string]string {
+	return map[string]string{
+		"origin":          valueMetric.Origin,
+		"bosh_deployment": valueMetric.Deployment,
+		"bosh_job":        valueMetric.Job,
+		"bosh_index":      valueMetric.Index,
+		"bosh_ip":         valueMetric.IP,
+		"unit":            valueMetric.Unit,
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package collectors
+
+import (
+	"math"
+	"strings"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+type AggregationRulesCollector struct {
+	aggregationRules *AggregationRules
+	metricsStore     *metrics.Store
+	ruleDescs        []*prometheus.Desc
+}
+
+type aggregationGroup struct {
+	labelValues []string
+	sum         float64
+	min         float64
+	max         float64
+	count       float64
+}
+
+func NewAggregationRulesCollector(
+	namespace string,
+	foundation string,
+	aggregationRules *AggregationRules,
+	metricsStore *metrics.Store,
+) *AggregationRulesCollector {
+	constLabels := foundationLabels(foundation)
+
+	ruleDescs := []*prometheus.Desc{}
+	if aggregationRules != nil {
+		for _, rule := range aggregationRules.rules {
+			ruleDescs = append(ruleDescs, prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, aggregation_rules_subsystem, rule.Name),
+				rule.description(),
+				rule.By,
+				constLabels,
+			))
+		}
+	}
+
+	return &AggregationRulesCollector{
+		aggregationRules: aggregationRules,
+		metricsStore:     metricsStore,
+		ruleDescs:        ruleDescs,
+	}
+}
+
+func (c AggregationRulesCollector) Collect(ch chan<- prometheus.Metric) {
+	if len(c.ruleDescs) == 0 {
+		return
+	}
+
+	containerMetrics := c.metricsStore.GetContainerMetrics()
+	valueMetrics := c.metricsStore.GetValueMetrics()
+
+	for i, rule := range c.aggregationRules.rules {
+		groups := map[string]*aggregationGroup{}
+		switch rule.EventType {
+		case events.Envelope_ContainerMetric.String():
+			for _, containerMetric := range containerMetrics {
+				if rule.matches(events.Envelope_ContainerMetric, containerMetric.Origin, containerMetric.Deployment, rule.Metric) {
+					addToGroup(groups, rule.By, containerMetricLabelValues(containerMetric), containerMetricValue(containerMetric, rule.Metric))
+				}
+			}
+		case events.Envelope_ValueMetric.String():
+			for _, valueMetric := range valueMetrics {
+				if rule.matches(events.Envelope_ValueMetric, valueMetric.Origin, valueMetric.Deployment, valueMetric.Name) {
+					addToGroup(groups, rule.By, valueMetricLabelValues(valueMetric), valueMetric.Value)
+				}
+			}
+		}
+
+		for _, group := range groups {
+			ch <- prometheus.MustNewConstMetric(
+				c.ruleDescs[i],
+				prometheus.GaugeValue,
+				group.value(rule.Operation),
+				group.labelValues...,
+			)
+		}
+	}
+}
+
+func (c AggregationRulesCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, ruleDesc := range c.ruleDescs {
+		ch <- ruleDesc
+	}
+}
+
+func addToGroup(groups map[string]*aggregationGroup, by []string, labels map[string]string, value float64) {
+	labelValues := make([]string, len(by))
+	for i, label := range by {
+		labelValues[i] = labels[label]
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	group, ok := groups[key]
+	if !ok {
+		group = &aggregationGroup{labelValues: labelValues, min: math.Inf(1), max: math.Inf(-1)}
+		groups[key] = group
+	}
+
+	group.sum += value
+	group.min = math.Min(group.min, value)
+	group.max = math.Max(group.max, value)
+	group.count++
+}
+
+func (g *aggregationGroup) value(operation string) float64 {
+	switch operation {
+	case "min":
+		return g.min
+	case "max":
+		return g.max
+	case "avg":
+		return g.sum / g.count
+	case "count":
+		return g.count
+	}
+	return g.sum
+}
@@ -0,0 +1,196 @@
+package collectors_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
+)
+
+var _ = Describe("AggregationRulesCollector", func() {
+	var (
+		namespace                 string
+		foundation                string
+		metricsStore              *metrics.Store
+		aggregationRules          *AggregationRules
+		aggregationRulesCollector *AggregationRulesCollector
+
+		applicationMemoryBytesDesc *prometheus.Desc
+		gorouterLatencyMaxDesc     *prometheus.Desc
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		foundation = ""
+		deploymentFilter := filters.NewDeploymentFilter([]string{})
+		eventFilter, _ := filters.NewEventFilter([]string{})
+		routeTemplates, _ := utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(0, 0, deploymentFilter, eventFilter, routeTemplates)
+
+		var err error
+		aggregationRules, err = ParseAggregationRules([]byte(`
+rules:
+- name: application_memory_bytes
+  event_type: ContainerMetric
+  metric: memory_bytes
+  operation: sum
+  by: [application_id]
+- name: gorouter_latency_max
+  event_type: ValueMetric
+  origin: gorouter
+  metric: latency
+  operation: max
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		applicationMemoryBytesDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "aggregation_rule", "application_memory_bytes"),
+			"Cloud Foundry Firehose sum of the 'memory_bytes' container metric by application_id.",
+			[]string{"application_id"},
+			nil,
+		)
+
+		gorouterLatencyMaxDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "aggregation_rule", "gorouter_latency_max"),
+			"Cloud Foundry Firehose max of the 'latency' value metric.",
+			nil,
+			nil,
+		)
+	})
+
+	JustBeforeEach(func() {
+		aggregationRulesCollector = NewAggregationRulesCollector(namespace, foundation, aggregationRules, metricsStore)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go aggregationRulesCollector.Describe(descriptions)
+		})
+
+		It("returns an aggregation_rule_application_memory_bytes metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(applicationMemoryBytesDesc)))
+		})
+
+		It("returns an aggregation_rule_gorouter_latency_max metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(gorouterLatencyMaxDesc)))
+		})
+	})
+
+	Describe("Register", func() {
+		BeforeEach(func() {
+			var err error
+			aggregationRules, err = ParseAggregationRules([]byte(`
+rules:
+- name: total_envelopes_received
+  event_type: ValueMetric
+  metric: envelopes
+  operation: sum
+`))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not clash with the exporter metrics", func() {
+			registry := prometheus.NewRegistry()
+			Expect(registry.Register(NewInternalMetricsCollector(namespace, foundation, metricsStore))).To(Succeed())
+			Expect(registry.Register(aggregationRulesCollector)).To(Succeed())
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			metricsChan chan prometheus.Metric
+
+			addContainerMetric = func(applicationId string, instanceIndex int32, memoryBytes uint64) {
+				metricsStore.AddMetric(&events.Envelope{
+					Origin:     proto.String("rep"),
+					EventType:  events.Envelope_ContainerMetric.Enum(),
+					Timestamp:  proto.Int64(time.Now().UnixNano()),
+					Deployment: proto.String("cf"),
+					Job:        proto.String("diego-cell"),
+					Index:      proto.String("0"),
+					Ip:         proto.String("1.2.3.4"),
+					ContainerMetric: &events.ContainerMetric{
+						ApplicationId: proto.String(applicationId),
+						InstanceIndex: proto.Int32(instanceIndex),
+						CpuPercentage: proto.Float64(1),
+						MemoryBytes:   proto.Uint64(memoryBytes),
+						DiskBytes:     proto.Uint64(1),
+					},
+				})
+			}
+
+			addValueMetric = func(origin string, ip string, value float64) {
+				metricsStore.AddMetric(&events.Envelope{
+					Origin:     proto.String(origin),
+					EventType:  events.Envelope_ValueMetric.Enum(),
+					Timestamp:  proto.Int64(time.Now().UnixNano()),
+					Deployment: proto.String("cf"),
+					Job:        proto.String("router"),
+					Index:      proto.String("0"),
+					Ip:         proto.String(ip),
+					ValueMetric: &events.ValueMetric{
+						Name:  proto.String("latency"),
+						Value: proto.Float64(value),
+						Unit:  proto.String("ms"),
+					},
+				})
+			}
+		)
+
+		BeforeEach(func() {
+			addContainerMetric("app-1", 0, 100)
+			addContainerMetric("app-1", 1, 200)
+			addContainerMetric("app-2", 0, 50)
+
+			addValueMetric("gorouter", "1.1.1.1", 10)
+			addValueMetric("gorouter", "2.2.2.2", 30)
+			addValueMetric("uaa", "3.3.3.3", 100)
+
+			metricsChan = make(chan prometheus.Metric)
+		})
+
+		JustBeforeEach(func() {
+			go aggregationRulesCollector.Collect(metricsChan)
+		})
+
+		It("sums the container metrics of each application", func() {
+			Eventually(metricsChan).Should(Receive(Equal(prometheus.MustNewConstMetric(applicationMemoryBytesDesc, prometheus.GaugeValue, 300, "app-1"))))
+		})
+
+		It("returns an aggregate for each group", func() {
+			Eventually(metricsChan).Should(Receive(Equal(prometheus.MustNewConstMetric(applicationMemoryBytesDesc, prometheus.GaugeValue, 50, "app-2"))))
+		})
+
+		It("only aggregates the matching value metrics", func() {
+			Eventually(metricsChan).Should(Receive(Equal(prometheus.MustNewConstMetric(gorouterLatencyMaxDesc, prometheus.GaugeValue, 30))))
+		})
+
+		Context("when there are no metrics to aggregate", func() {
+			BeforeEach(func() {
+				metricsStore.FlushContainerMetrics()
+				metricsStore.FlushValueMetrics()
+			})
+
+			It("does not return any metric", func() {
+				Consistently(metricsChan).ShouldNot(Receive())
+			})
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package collectors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
+)
+
+var _ = Describe("AggregationRules", func() {
+	Describe("ParseAggregationRules", func() {
+		It("parses the rules", func() {
+			_, err := ParseAggregationRules([]byte(`
+rules:
+- name: application_memory_bytes
+  event_type: ContainerMetric
+  metric: memory_bytes
+  operation: sum
+  by: [application_id]
+  replace: true
+- name: gorouter_latency_max
+  event_type: ValueMetric
+  origin: gorouter
+  metric: latency*
+  operation: max
+  by: [bosh_deployment, bosh_job]
+`))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("parses regular expression patterns", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: latency, event_type: ValueMetric, origin: "/gorouter|uaa/", metric: "/latency\\..*/", operation: max}]`))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error when there are no rules", func() {
+			_, err := ParseAggregationRules([]byte(`rules: []`))
+			Expect(err).To(MatchError("No aggregation rules found"))
+		})
+
+		It("returns an error when a name is not a valid metric name", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: "memory-bytes", event_type: ContainerMetric, metric: memory_bytes, operation: sum}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: name `memory-bytes` is not a valid metric name"))
+		})
+
+		It("returns an error when an event type is not supported", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: errors, event_type: Error, metric: errors, operation: sum}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: event type `Error` is not supported, must be ContainerMetric or ValueMetric"))
+		})
+
+		It("returns an error when a container metric does not exist", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: memory, event_type: ContainerMetric, metric: memory, operation: sum}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: metric `memory` is not a container metric"))
+		})
+
+		It("returns an error when a value metric is not set", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: latency, event_type: ValueMetric, operation: sum}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: metric must be set"))
+		})
+
+		It("returns an error when an operation is not supported", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: memory, event_type: ContainerMetric, metric: memory_bytes, operation: median}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: operation `median` is not supported, must be sum, min, max, avg or count"))
+		})
+
+		It("returns an error when a label does not exist", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: latency, event_type: ValueMetric, metric: latency, operation: max, by: [application_id]}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: cannot aggregate ValueMetric by `application_id`"))
+		})
+
+		It("returns an error when a name is used by another rule", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: memory, event_type: ContainerMetric, metric: memory_bytes, operation: sum}, {name: memory, event_type: ContainerMetric, metric: memory_bytes, operation: max}]`))
+			Expect(err).To(MatchError("Aggregation rule #2: name `memory` is used by another rule"))
+		})
+
+		It("returns an error when a label is repeated", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: latency, event_type: ValueMetric, metric: latency, operation: max, by: [origin, origin]}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: label `origin` is repeated"))
+		})
+
+		It("returns an error when a pattern is invalid", func() {
+			_, err := ParseAggregationRules([]byte(`rules: [{name: latency, event_type: ValueMetric, origin: "[", metric: latency, operation: max}]`))
+			Expect(err).To(MatchError("Aggregation rule #1: pattern `[` is invalid"))
+		})
+	})
+})
@@ -1,5 +1,9 @@
 package collectors
 
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
 // Metric name parts.
 const (
 	// Container Metrics Subsystem.
@@ -10,4 +14,30 @@ const (
 
 	// Value Metrics Subsystem.
 	value_metrics_subsystem = "value_metric"
+
+	// Aggregated Value Metrics Subsystem.
+	aggregated_value_metrics_subsystem = "aggregated_value_metric"
+
+	// HttpStartStop Events Subsystem.
+	http_start_stop_subsystem = "http_start_stop"
+
+	// HttpStartStop Routes Subsystem.
+	http_routes_subsystem = "http_route"
+
+	// Log Messages Subsystem.
+	log_messages_subsystem = "log_message"
+
+	// Error Events Subsystem.
+	errors_subsystem = "error_event"
+
+	// Aggregation Rules Subsystem.
+	aggregation_rules_subsystem = "aggregation_rule"
 )
+
+// foundationLabels labels the metrics with the Cloud Foundry foundation they come from, if any.
+func foundationLabels(foundation string) prometheus.Labels {
+	if foundation == "" {
+		return nil
+	}
+	return prometheus.Labels{"foundation": foundation}
+}
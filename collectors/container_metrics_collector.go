@@ -3,6 +3,7 @@ package collectors
 import (
 	"strconv"
 
+	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
@@ -10,6 +11,8 @@ import (
 
 type ContainerMetricsCollector struct {
 	namespace                  string
+	sampleTimestamps           SampleTimestamps
+	aggregationRules           *AggregationRules
 	metricsStore               *metrics.Store
 	cpuPercentageMetricDesc    *prometheus.Desc
 	memoryBytesMetricDesc      *prometheus.Desc
@@ -20,45 +23,52 @@ type ContainerMetricsCollector struct {
 
 func NewContainerMetricsCollector(
 	namespace string,
+	foundation string,
+	sampleTimestamps SampleTimestamps,
+	aggregationRules *AggregationRules,
 	metricsStore *metrics.Store,
 ) *ContainerMetricsCollector {
+	constLabels := foundationLabels(foundation)
+
 	cpuPercentageMetricDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, container_metrics_subsystem, "cpu_percentage"),
 		"Cloud Foundry Firehose container metric: CPU used, on a scale of 0 to 100.",
 		[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "application_id", "instance_id"},
-		nil,
+		constLabels,
 	)
 
 	memoryBytesMetricDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, container_metrics_subsystem, "memory_bytes"),
 		"Cloud Foundry Firehose container metric: bytes of memory used.",
 		[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "application_id", "instance_id"},
-		nil,
+		constLabels,
 	)
 
 	diskBytesMetricDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, container_metrics_subsystem, "disk_bytes"),
 		"Cloud Foundry Firehose container metric: bytes of disk used.",
 		[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "application_id", "instance_id"},
-		nil,
+		constLabels,
 	)
 
 	memoryBytesQuotaMetricDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, container_metrics_subsystem, "memory_bytes_quota"),
 		"Cloud Foundry Firehose container metric: maximum bytes of memory allocated to container.",
 		[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "application_id", "instance_id"},
-		nil,
+		constLabels,
 	)
 
 	diskBytesQuotaMetricDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, container_metrics_subsystem, "disk_bytes_quota"),
 		"Cloud Foundry Firehose container metric: maximum bytes of disk allocated to container.",
 		[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "application_id", "instance_id"},
-		nil,
+		constLabels,
 	)
 
 	return &ContainerMetricsCollector{
 		namespace:                  namespace,
+		sampleTimestamps:           sampleTimestamps,
+		aggregationRules:           aggregationRules,
 		metricsStore:               metricsStore,
 		cpuPercentageMetricDesc:    cpuPercentageMetricDesc,
 		memoryBytesMetricDesc:      memoryBytesMetricDesc,
@@ -70,66 +80,40 @@ func NewContainerMetricsCollector(
 
 func (c ContainerMetricsCollector) Collect(ch chan<- prometheus.Metric) {
 	for _, containerMetric := range c.metricsStore.GetContainerMetrics() {
-		ch <- prometheus.MustNewConstMetric(
-			c.cpuPercentageMetricDesc,
-			prometheus.GaugeValue,
-			containerMetric.CpuPercentage,
-			containerMetric.Origin,
-			containerMetric.Deployment,
-			containerMetric.Job,
-			containerMetric.Index,
-			containerMetric.IP,
-			containerMetric.ApplicationId,
-			strconv.Itoa(int(containerMetric.InstanceIndex)),
-		)
-		ch <- prometheus.MustNewConstMetric(
-			c.memoryBytesMetricDesc,
-			prometheus.GaugeValue,
-			float64(containerMetric.MemoryBytes),
-			containerMetric.Origin,
-			containerMetric.Deployment,
-			containerMetric.Job,
-			containerMetric.Index,
-			containerMetric.IP,
-			containerMetric.ApplicationId,
-			strconv.Itoa(int(containerMetric.InstanceIndex)),
-		)
-		ch <- prometheus.MustNewConstMetric(
-			c.diskBytesMetricDesc,
-			prometheus.GaugeValue,
-			float64(containerMetric.DiskBytes),
-			containerMetric.Origin,
-			containerMetric.Deployment,
-			containerMetric.Job,
-			containerMetric.Index,
-			containerMetric.IP,
-			containerMetric.ApplicationId,
-			strconv.Itoa(int(containerMetric.InstanceIndex)),
-		)
-		ch <- prometheus.MustNewConstMetric(
-			c.memoryBytesQuotaMetricDesc,
-			prometheus.GaugeValue,
-			float64(containerMetric.MemoryBytesQuota),
-			containerMetric.Origin,
-			containerMetric.Deployment,
-			containerMetric.Job,
-			containerMetric.Index,
-			containerMetric.IP,
-			containerMetric.ApplicationId,
-			strconv.Itoa(int(containerMetric.InstanceIndex)),
-		)
-		ch <- prometheus.MustNewConstMetric(
-			c.diskBytesQuotaMetricDesc,
-			prometheus.GaugeValue,
-			float64(containerMetric.DiskBytesQuota),
-			containerMetric.Origin,
-			containerMetric.Deployment,
-			containerMetric.Job,
-			containerMetric.Index,
-			containerMetric.IP,
-			containerMetric.ApplicationId,
-			strconv.Itoa(int(containerMetric.InstanceIndex)),
-		)
+		timestamp, ok := c.sampleTimestamps.sampleTimestamp(containerMetric.Timestamp)
+		if !ok {
+			continue
+		}
+
+		values := []struct {
+			name  string
+			desc  *prometheus.Desc
+			value float64
+		}{
+			{"cpu_percentage", c.cpuPercentageMetricDesc, containerMetric.CpuPercentage},
+			{"memory_bytes", c.memoryBytesMetricDesc, float64(containerMetric.MemoryBytes)},
+			{"disk_bytes", c.diskBytesMetricDesc, float64(containerMetric.DiskBytes)},
+			{"memory_bytes_quota", c.memoryBytesQuotaMetricDesc, float64(containerMetric.MemoryBytesQuota)},
+			{"disk_bytes_quota", c.diskBytesQuotaMetricDesc, float64(containerMetric.DiskBytesQuota)},
+		}
+		for _, v := range values {
+			if c.aggregationRules.replaces(events.Envelope_ContainerMetric, containerMetric.Origin, containerMetric.Deployment, v.name) {
+				continue
+			}
+
+			ch <- withTimestamp(prometheus.MustNewConstMetric(
+				v.desc,
+				prometheus.GaugeValue,
+				v.value,
+				containerMetric.Origin,
+				containerMetric.Deployment,
+				containerMetric.Job,
+				containerMetric.Index,
+				containerMetric.IP,
+				containerMetric.ApplicationId,
+				strconv.Itoa(int(containerMetric.InstanceIndex)),
+			), timestamp)
+		}
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
@@ -19,11 +20,15 @@ import (
 var _ = Describe("ContainerMetricsCollector", func() {
 	var (
 		namespace                 string
+		foundation                string
+		sampleTimestamps          SampleTimestamps
+		aggregationRules          *AggregationRules
 		metricsStore              *metrics.Store
 		metricsExpiration         time.Duration
 		metricsCleanupInterval    time.Duration
 		deploymentFilter          *filters.DeploymentFilter
 		eventFilter               *filters.EventFilter
+		routeTemplates            *utils.RouteTemplates
 		containerMetricsCollector *ContainerMetricsCollector
 
 		cpuPercentageMetricDesc    *prometheus.Desc
@@ -35,9 +40,13 @@ var _ = Describe("ContainerMetricsCollector", func() {
 
 	BeforeEach(func() {
 		namespace = "test_exporter"
+		foundation = ""
+		sampleTimestamps = SampleTimestamps{}
+		aggregationRules = nil
 		deploymentFilter = filters.NewDeploymentFilter([]string{})
 		eventFilter, _ = filters.NewEventFilter([]string{})
-		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter)
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
 
 		cpuPercentageMetricDesc = prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "container_metric", "cpu_percentage"),
@@ -76,7 +85,7 @@ var _ = Describe("ContainerMetricsCollector", func() {
 	})
 
 	JustBeforeEach(func() {
-		containerMetricsCollector = NewContainerMetricsCollector(namespace, metricsStore)
+		containerMetricsCollector = NewContainerMetricsCollector(namespace, foundation, sampleTimestamps, aggregationRules, metricsStore)
 	})
 
 	Describe("Describe", func() {
@@ -370,6 +379,30 @@ var _ = Describe("ContainerMetricsCollector", func() {
 			Eventually(containerMetricsChan).Should(Receive(Equal(diskBytesQuotaMetric2)))
 		})
 
+		Context("when an aggregation rule replaces a container metric", func() {
+			BeforeEach(func() {
+				var err error
+				aggregationRules, err = ParseAggregationRules([]byte(`
+rules:
+- name: application_memory_bytes
+  event_type: ContainerMetric
+  metric: memory_bytes
+  operation: sum
+  by: [application_id]
+  replace: true
+`))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the other container metrics", func() {
+				Eventually(containerMetricsChan).Should(Receive(Equal(cpuPercentageMetric1)))
+			})
+
+			It("does not return the replaced container metric", func() {
+				Consistently(containerMetricsChan).ShouldNot(Receive(Equal(memoryBytesMetric1)))
+			})
+		})
+
 		Context("when there is no container metrics", func() {
 			BeforeEach(func() {
 				metricsStore.FlushContainerMetrics()
@@ -11,23 +11,31 @@ import (
 
 type CounterEventsCollector struct {
 	namespace                  string
+	constLabels                prometheus.Labels
+	sampleTimestamps           SampleTimestamps
 	metricsStore               *metrics.Store
 	counterEventsCollectorDesc *prometheus.Desc
 }
 
 func NewCounterEventsCollector(
 	namespace string,
+	foundation string,
+	sampleTimestamps SampleTimestamps,
 	metricsStore *metrics.Store,
 ) *CounterEventsCollector {
+	constLabels := foundationLabels(foundation)
+
 	counterEventsCollectorDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, counter_events_subsystem, "collector"),
 		"Cloud Foundry Firehose counter metrics collector.",
 		nil,
-		nil,
+		constLabels,
 	)
 
 	return &CounterEventsCollector{
 		namespace:                  namespace,
+		constLabels:                constLabels,
+		sampleTimestamps:           sampleTimestamps,
 		metricsStore:               metricsStore,
 		counterEventsCollectorDesc: counterEventsCollectorDesc,
 	}
@@ -35,30 +43,35 @@ func NewCounterEventsCollector(
 
 func (c CounterEventsCollector) Collect(ch chan<- prometheus.Metric) {
 	for _, counterEvent := range c.metricsStore.GetCounterEvents() {
+		timestamp, ok := c.sampleTimestamps.sampleTimestamp(counterEvent.Timestamp)
+		if !ok {
+			continue
+		}
+
 		metricName := utils.NormalizeName(counterEvent.Origin) + "_" + utils.NormalizeName(counterEvent.Name) + "_total"
-		ch <- prometheus.MustNewConstMetric(
+		ch <- withTimestamp(prometheus.MustNewConstMetric(
 			prometheus.NewDesc(
 				prometheus.BuildFQName(c.namespace, counter_events_subsystem, metricName),
 				fmt.Sprintf("Cloud Foundry Firehose '%s' total counter event from '%s'.", counterEvent.Name, counterEvent.Origin),
 				[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip"},
-				nil,
+				c.constLabels,
 			),
 			prometheus.CounterValue,
-			float64(counterEvent.Total),
+			float64(counterEvent.Counter),
 			counterEvent.Origin,
 			counterEvent.Deployment,
 			counterEvent.Job,
 			counterEvent.Index,
 			counterEvent.IP,
-		)
+		), timestamp)
 
 		metricName = utils.NormalizeName(counterEvent.Origin) + "_" + utils.NormalizeName(counterEvent.Name) + "_delta"
-		ch <- prometheus.MustNewConstMetric(
+		ch <- withTimestamp(prometheus.MustNewConstMetric(
 			prometheus.NewDesc(
 				prometheus.BuildFQName(c.namespace, counter_events_subsystem, metricName),
 				fmt.Sprintf("Cloud Foundry Firehose '%s' delta counter event from '%s'.", counterEvent.Name, counterEvent.Origin),
 				[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip"},
-				nil,
+				c.constLabels,
 			),
 			prometheus.GaugeValue,
 			float64(counterEvent.Delta),
@@ -67,7 +80,7 @@ func (c CounterEventsCollector) Collect(ch chan<- prometheus.Metric) {
 			counterEvent.Job,
 			counterEvent.Index,
 			counterEvent.IP,
-		)
+		), timestamp)
 	}
 }
 
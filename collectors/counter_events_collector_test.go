@@ -9,6 +9,7 @@ import (
 
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
@@ -19,11 +20,14 @@ import (
 var _ = Describe("CounterEventsCollector", func() {
 	var (
 		namespace              string
+		foundation             string
+		sampleTimestamps       SampleTimestamps
 		metricsStore           *metrics.Store
 		metricsExpiration      time.Duration
 		metricsCleanupInterval time.Duration
 		deploymentFilter       *filters.DeploymentFilter
 		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
 		counterEventsCollector *CounterEventsCollector
 
 		counterEventsCollectorDesc *prometheus.Desc
@@ -31,9 +35,12 @@ var _ = Describe("CounterEventsCollector", func() {
 
 	BeforeEach(func() {
 		namespace = "test_exporter"
+		foundation = ""
+		sampleTimestamps = SampleTimestamps{}
 		deploymentFilter = filters.NewDeploymentFilter([]string{})
 		eventFilter, _ = filters.NewEventFilter([]string{})
-		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter)
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
 
 		counterEventsCollectorDesc = prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "counter_event", "collector"),
@@ -44,7 +51,7 @@ var _ = Describe("CounterEventsCollector", func() {
 	})
 
 	JustBeforeEach(func() {
-		counterEventsCollector = NewCounterEventsCollector(namespace, metricsStore)
+		counterEventsCollector = NewCounterEventsCollector(namespace, foundation, sampleTimestamps, metricsStore)
 	})
 
 	Describe("Describe", func() {
@@ -63,6 +70,23 @@ var _ = Describe("CounterEventsCollector", func() {
 		It("returns a counter_event_collector metric description", func() {
 			Eventually(descriptions).Should(Receive(Equal(counterEventsCollectorDesc)))
 		})
+
+		Context("when a foundation is set", func() {
+			BeforeEach(func() {
+				foundation = "fake-foundation"
+
+				counterEventsCollectorDesc = prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "counter_event", "collector"),
+					"Cloud Foundry Firehose counter metrics collector.",
+					nil,
+					prometheus.Labels{"foundation": foundation},
+				)
+			})
+
+			It("returns a counter_event_collector metric description labeled with the foundation", func() {
+				Eventually(descriptions).Should(Receive(Equal(counterEventsCollectorDesc)))
+			})
+		})
 	})
 
 	Describe("Collect", func() {
@@ -213,6 +237,32 @@ var _ = Describe("CounterEventsCollector", func() {
 			Eventually(counterEventsChan).Should(Receive(Equal(deltaCounterEvent2)))
 		})
 
+		Context("when a foundation is set", func() {
+			BeforeEach(func() {
+				foundation = "fake-foundation"
+
+				totalCounterEvent1 = prometheus.MustNewConstMetric(
+					prometheus.NewDesc(
+						prometheus.BuildFQName(namespace, "counter_event", originNormalized+"_"+counterEvent1NameNormalized+"_total"),
+						fmt.Sprintf("Cloud Foundry Firehose '%s' total counter event from '%s'.", counterEvent1Name, origin),
+						[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip"},
+						prometheus.Labels{"foundation": foundation},
+					),
+					prometheus.CounterValue,
+					float64(counterEvent1Total),
+					origin,
+					boshDeployment,
+					boshJob,
+					boshIndex,
+					boshIP,
+				)
+			})
+
+			It("returns a counter_event_fake_origin_fake_counter_event_1_total metric labeled with the foundation", func() {
+				Eventually(counterEventsChan).Should(Receive(Equal(totalCounterEvent1)))
+			})
+		})
+
 		Context("when there is no counter metrics", func() {
 			BeforeEach(func() {
 				metricsStore.FlushCounterEvents()
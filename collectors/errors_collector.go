@@ -0,0 +1,72 @@
+package collectors
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+type ErrorsCollector struct {
+	namespace         string
+	metricsStore      *metrics.Store
+	totalDesc         *prometheus.Desc
+	lastTimestampDesc *prometheus.Desc
+}
+
+func NewErrorsCollector(
+	namespace string,
+	foundation string,
+	metricsStore *metrics.Store,
+) *ErrorsCollector {
+	constLabels := foundationLabels(foundation)
+
+	totalDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, errors_subsystem, "total"),
+		"Cloud Foundry Firehose total error events.",
+		[]string{"origin", "source", "code"},
+		constLabels,
+	)
+
+	lastTimestampDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, errors_subsystem, "last_timestamp"),
+		"Number of seconds since 1970 since last error event received from Cloud Foundry Firehose.",
+		[]string{"origin", "source", "code"},
+		constLabels,
+	)
+
+	return &ErrorsCollector{
+		namespace:         namespace,
+		metricsStore:      metricsStore,
+		totalDesc:         totalDesc,
+		lastTimestampDesc: lastTimestampDesc,
+	}
+}
+
+func (c ErrorsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, errorEvent := range c.metricsStore.GetErrors() {
+		ch <- prometheus.MustNewConstMetric(
+			c.totalDesc,
+			prometheus.CounterValue,
+			float64(errorEvent.Count),
+			errorEvent.Origin,
+			errorEvent.Source,
+			strconv.Itoa(int(errorEvent.Code)),
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.lastTimestampDesc,
+			prometheus.GaugeValue,
+			float64(errorEvent.Timestamp)/1e9,
+			errorEvent.Origin,
+			errorEvent.Source,
+			strconv.Itoa(int(errorEvent.Code)),
+		)
+	}
+}
+
+func (c ErrorsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalDesc
+	ch <- c.lastTimestampDesc
+}
@@ -0,0 +1,159 @@
+package collectors_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
+)
+
+var _ = Describe("ErrorsCollector", func() {
+	var (
+		namespace              string
+		foundation             string
+		metricsStore           *metrics.Store
+		metricsExpiration      time.Duration
+		metricsCleanupInterval time.Duration
+		deploymentFilter       *filters.DeploymentFilter
+		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
+		errorsCollector        *ErrorsCollector
+
+		totalDesc         *prometheus.Desc
+		lastTimestampDesc *prometheus.Desc
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		foundation = ""
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+
+		totalDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "error_event", "total"),
+			"Cloud Foundry Firehose total error events.",
+			[]string{"origin", "source", "code"},
+			nil,
+		)
+
+		lastTimestampDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "error_event", "last_timestamp"),
+			"Number of seconds since 1970 since last error event received from Cloud Foundry Firehose.",
+			[]string{"origin", "source", "code"},
+			nil,
+		)
+	})
+
+	JustBeforeEach(func() {
+		errorsCollector = NewErrorsCollector(namespace, foundation, metricsStore)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go errorsCollector.Describe(descriptions)
+		})
+
+		It("returns a error_event_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalDesc)))
+		})
+
+		It("returns a error_event_last_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastTimestampDesc)))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			origin         = "fake-origin"
+			errorSource    = "fake-source"
+			errorCode      = int32(127)
+			errorTimestamp = int64(1500000000000000000)
+
+			errorsChan          chan prometheus.Metric
+			totalMetric         prometheus.Metric
+			lastTimestampMetric prometheus.Metric
+		)
+
+		BeforeEach(func() {
+			for i := 0; i < 3; i++ {
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_Error.Enum(),
+						Timestamp:  proto.Int64(errorTimestamp),
+						Deployment: proto.String("fake-deployment-name"),
+						Job:        proto.String("fake-job-name"),
+						Index:      proto.String("0"),
+						Ip:         proto.String("1.2.3.4"),
+						Error: &events.Error{
+							Source:  proto.String(errorSource),
+							Code:    proto.Int32(errorCode),
+							Message: proto.String("fake-message"),
+						},
+					},
+				)
+			}
+
+			errorsChan = make(chan prometheus.Metric)
+
+			totalMetric = prometheus.MustNewConstMetric(
+				totalDesc,
+				prometheus.CounterValue,
+				float64(3),
+				origin,
+				errorSource,
+				"127",
+			)
+
+			lastTimestampMetric = prometheus.MustNewConstMetric(
+				lastTimestampDesc,
+				prometheus.GaugeValue,
+				float64(1500000000),
+				origin,
+				errorSource,
+				"127",
+			)
+		})
+
+		JustBeforeEach(func() {
+			go errorsCollector.Collect(errorsChan)
+		})
+
+		It("returns a error_event_total metric", func() {
+			Eventually(errorsChan).Should(Receive(Equal(totalMetric)))
+		})
+
+		It("returns a error_event_last_timestamp metric", func() {
+			Eventually(errorsChan).Should(Receive(Equal(lastTimestampMetric)))
+		})
+
+		Context("when there is no error events", func() {
+			BeforeEach(func() {
+				metricsStore.FlushErrors()
+			})
+
+			It("does not return any metric", func() {
+				Consistently(errorsChan).ShouldNot(Receive())
+			})
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+type HttpRoutesCollector struct {
+	namespace                  string
+	metricsStore               *metrics.Store
+	requestsDesc               *prometheus.Desc
+	responseBytesDesc          *prometheus.Desc
+	requestDurationSecondsDesc *prometheus.Desc
+}
+
+func NewHttpRoutesCollector(
+	namespace string,
+	foundation string,
+	metricsStore *metrics.Store,
+) *HttpRoutesCollector {
+	constLabels := foundationLabels(foundation)
+
+	requestsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_routes_subsystem, "requests_total"),
+		"Cloud Foundry Firehose gorouter total requests per route.",
+		[]string{"host", "path", "method", "status_class"},
+		constLabels,
+	)
+
+	responseBytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_routes_subsystem, "response_bytes_total"),
+		"Cloud Foundry Firehose gorouter total response bytes per route.",
+		[]string{"host", "path", "method"},
+		constLabels,
+	)
+
+	requestDurationSecondsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_routes_subsystem, "request_duration_seconds"),
+		"Cloud Foundry Firehose gorouter request duration in seconds per route.",
+		[]string{"host", "path", "method"},
+		constLabels,
+	)
+
+	return &HttpRoutesCollector{
+		namespace:                  namespace,
+		metricsStore:               metricsStore,
+		requestsDesc:               requestsDesc,
+		responseBytesDesc:          responseBytesDesc,
+		requestDurationSecondsDesc: requestDurationSecondsDesc,
+	}
+}
+
+func (c HttpRoutesCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, httpRoute := range c.metricsStore.GetHttpRoutes() {
+		for statusClass, requests := range httpRoute.Requests {
+			ch <- prometheus.MustNewConstMetric(
+				c.requestsDesc,
+				prometheus.CounterValue,
+				float64(requests),
+				httpRoute.Host,
+				httpRoute.Path,
+				httpRoute.Method,
+				statusClass,
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.responseBytesDesc,
+			prometheus.CounterValue,
+			float64(httpRoute.ResponseBytes),
+			httpRoute.Host,
+			httpRoute.Path,
+			httpRoute.Method,
+		)
+
+		ch <- prometheus.MustNewConstHistogram(
+			c.requestDurationSecondsDesc,
+			httpRoute.DurationCount,
+			httpRoute.DurationSum,
+			httpRoute.DurationBuckets,
+			httpRoute.Host,
+			httpRoute.Path,
+			httpRoute.Method,
+		)
+	}
+}
+
+func (c HttpRoutesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requestsDesc
+	ch <- c.responseBytesDesc
+	ch <- c.requestDurationSecondsDesc
+}
@@ -0,0 +1,191 @@
+package collectors_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
+)
+
+var _ = Describe("HttpRoutesCollector", func() {
+	var (
+		namespace              string
+		foundation             string
+		metricsStore           *metrics.Store
+		metricsExpiration      time.Duration
+		metricsCleanupInterval time.Duration
+		deploymentFilter       *filters.DeploymentFilter
+		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
+		httpRoutesCollector    *HttpRoutesCollector
+
+		requestsDesc               *prometheus.Desc
+		responseBytesDesc          *prometheus.Desc
+		requestDurationSecondsDesc *prometheus.Desc
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		foundation = ""
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{"/v2/apps/{guid}/**"})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+
+		requestsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "http_route", "requests_total"),
+			"Cloud Foundry Firehose gorouter total requests per route.",
+			[]string{"host", "path", "method", "status_class"},
+			nil,
+		)
+
+		responseBytesDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "http_route", "response_bytes_total"),
+			"Cloud Foundry Firehose gorouter total response bytes per route.",
+			[]string{"host", "path", "method"},
+			nil,
+		)
+
+		requestDurationSecondsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "http_route", "request_duration_seconds"),
+			"Cloud Foundry Firehose gorouter request duration in seconds per route.",
+			[]string{"host", "path", "method"},
+			nil,
+		)
+	})
+
+	JustBeforeEach(func() {
+		httpRoutesCollector = NewHttpRoutesCollector(namespace, foundation, metricsStore)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go httpRoutesCollector.Describe(descriptions)
+		})
+
+		It("returns a http_route_requests_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(requestsDesc)))
+		})
+
+		It("returns a http_route_response_bytes_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(responseBytesDesc)))
+		})
+
+		It("returns a http_route_request_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(requestDurationSecondsDesc)))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			host           = "api.fake-domain.com"
+			path           = "/v2/apps/{guid}/**"
+			startTimestamp = time.Now().UnixNano()
+			duration       = 200 * time.Millisecond
+
+			httpRoutesChan               chan prometheus.Metric
+			requestsMetric               prometheus.Metric
+			responseBytesMetric          prometheus.Metric
+			requestDurationSecondsMetric prometheus.Metric
+		)
+
+		BeforeEach(func() {
+			metricsStore.AddMetric(
+				&events.Envelope{
+					Origin:     proto.String("gorouter"),
+					EventType:  events.Envelope_HttpStartStop.Enum(),
+					Timestamp:  proto.Int64(time.Now().Unix() * 1000),
+					Deployment: proto.String("fake-deployment-name"),
+					Job:        proto.String("router"),
+					Index:      proto.String("0"),
+					Ip:         proto.String("1.2.3.4"),
+					HttpStartStop: &events.HttpStartStop{
+						StartTimestamp: proto.Int64(startTimestamp),
+						StopTimestamp:  proto.Int64(startTimestamp + duration.Nanoseconds()),
+						PeerType:       events.PeerType_Client.Enum(),
+						Method:         events.Method_GET.Enum(),
+						Uri:            proto.String("https://" + host + "/v2/apps/fake-guid/stats?fake=query"),
+						StatusCode:     proto.Int32(404),
+						ContentLength:  proto.Int64(512),
+					},
+				},
+			)
+
+			httpRoutesChan = make(chan prometheus.Metric)
+
+			requestsMetric = prometheus.MustNewConstMetric(
+				requestsDesc,
+				prometheus.CounterValue,
+				float64(1),
+				host,
+				path,
+				"GET",
+				"4xx",
+			)
+
+			responseBytesMetric = prometheus.MustNewConstMetric(
+				responseBytesDesc,
+				prometheus.CounterValue,
+				float64(512),
+				host,
+				path,
+				"GET",
+			)
+
+			requestDurationSecondsMetric = prometheus.MustNewConstHistogram(
+				requestDurationSecondsDesc,
+				uint64(1),
+				duration.Seconds(),
+				map[float64]uint64{
+					.005: 0, .01: 0, .025: 0, .05: 0, .1: 0, .25: 1, .5: 1, 1: 1, 2.5: 1, 5: 1, 10: 1,
+				},
+				host,
+				path,
+				"GET",
+			)
+		})
+
+		JustBeforeEach(func() {
+			go httpRoutesCollector.Collect(httpRoutesChan)
+		})
+
+		It("returns a http_route_requests_total metric", func() {
+			Eventually(httpRoutesChan).Should(Receive(Equal(requestsMetric)))
+		})
+
+		It("returns a http_route_response_bytes_total metric", func() {
+			Eventually(httpRoutesChan).Should(Receive(Equal(responseBytesMetric)))
+		})
+
+		It("returns a http_route_request_duration_seconds metric", func() {
+			Eventually(httpRoutesChan).Should(Receive(Equal(requestDurationSecondsMetric)))
+		})
+
+		Context("when there is no http route metrics", func() {
+			BeforeEach(func() {
+				metricsStore.FlushHttpRoutes()
+			})
+
+			It("does not return any metric", func() {
+				Consistently(httpRoutesChan).ShouldNot(Receive())
+			})
+		})
+	})
+})
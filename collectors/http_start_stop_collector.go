@@ -0,0 +1,72 @@
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+type HttpStartStopCollector struct {
+	namespace                  string
+	metricsStore               *metrics.Store
+	requestsDesc               *prometheus.Desc
+	requestDurationSecondsDesc *prometheus.Desc
+}
+
+func NewHttpStartStopCollector(
+	namespace string,
+	foundation string,
+	metricsStore *metrics.Store,
+) *HttpStartStopCollector {
+	constLabels := foundationLabels(foundation)
+
+	requestsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "requests_total"),
+		"Cloud Foundry Firehose http start stop total requests per application.",
+		[]string{"application_id", "method", "status_class"},
+		constLabels,
+	)
+
+	requestDurationSecondsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "request_duration_seconds"),
+		"Cloud Foundry Firehose http start stop request duration in seconds per application.",
+		[]string{"application_id", "method"},
+		constLabels,
+	)
+
+	return &HttpStartStopCollector{
+		namespace:                  namespace,
+		metricsStore:               metricsStore,
+		requestsDesc:               requestsDesc,
+		requestDurationSecondsDesc: requestDurationSecondsDesc,
+	}
+}
+
+func (c HttpStartStopCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, httpStartStop := range c.metricsStore.GetHttpStartStops() {
+		for statusClass, requests := range httpStartStop.Requests {
+			ch <- prometheus.MustNewConstMetric(
+				c.requestsDesc,
+				prometheus.CounterValue,
+				float64(requests),
+				httpStartStop.ApplicationId,
+				httpStartStop.Method,
+				statusClass,
+			)
+		}
+
+		ch <- prometheus.MustNewConstHistogram(
+			c.requestDurationSecondsDesc,
+			httpStartStop.DurationCount,
+			httpStartStop.DurationSum,
+			httpStartStop.DurationBuckets,
+			httpStartStop.ApplicationId,
+			httpStartStop.Method,
+		)
+	}
+}
+
+func (c HttpStartStopCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requestsDesc
+	ch <- c.requestDurationSecondsDesc
+}
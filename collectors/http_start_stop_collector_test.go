@@ -0,0 +1,166 @@
+package collectors_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
+)
+
+var _ = Describe("HttpStartStopCollector", func() {
+	var (
+		namespace              string
+		foundation             string
+		metricsStore           *metrics.Store
+		metricsExpiration      time.Duration
+		metricsCleanupInterval time.Duration
+		deploymentFilter       *filters.DeploymentFilter
+		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
+		httpStartStopCollector *HttpStartStopCollector
+
+		requestsDesc               *prometheus.Desc
+		requestDurationSecondsDesc *prometheus.Desc
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		foundation = ""
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+
+		requestsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "http_start_stop", "requests_total"),
+			"Cloud Foundry Firehose http start stop total requests per application.",
+			[]string{"application_id", "method", "status_class"},
+			nil,
+		)
+
+		requestDurationSecondsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "http_start_stop", "request_duration_seconds"),
+			"Cloud Foundry Firehose http start stop request duration in seconds per application.",
+			[]string{"application_id", "method"},
+			nil,
+		)
+	})
+
+	JustBeforeEach(func() {
+		httpStartStopCollector = NewHttpStartStopCollector(namespace, foundation, metricsStore)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go httpStartStopCollector.Describe(descriptions)
+		})
+
+		It("returns a http_start_stop_requests_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(requestsDesc)))
+		})
+
+		It("returns a http_start_stop_request_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(requestDurationSecondsDesc)))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			applicationId   = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+			applicationUUID = &events.UUID{
+				Low:  proto.Uint64(0x7243cc580bc17af4),
+				High: proto.Uint64(0x79d4c3b2020e67a5),
+			}
+			startTimestamp = time.Now().UnixNano()
+			duration       = 200 * time.Millisecond
+
+			httpStartStopChan            chan prometheus.Metric
+			requestsMetric               prometheus.Metric
+			requestDurationSecondsMetric prometheus.Metric
+		)
+
+		BeforeEach(func() {
+			metricsStore.AddMetric(
+				&events.Envelope{
+					Origin:     proto.String("gorouter"),
+					EventType:  events.Envelope_HttpStartStop.Enum(),
+					Timestamp:  proto.Int64(time.Now().Unix() * 1000),
+					Deployment: proto.String("fake-deployment-name"),
+					Job:        proto.String("router"),
+					Index:      proto.String("0"),
+					Ip:         proto.String("1.2.3.4"),
+					HttpStartStop: &events.HttpStartStop{
+						StartTimestamp: proto.Int64(startTimestamp),
+						StopTimestamp:  proto.Int64(startTimestamp + duration.Nanoseconds()),
+						PeerType:       events.PeerType_Client.Enum(),
+						Method:         events.Method_GET.Enum(),
+						Uri:            proto.String("http://fake-host/fake-path"),
+						StatusCode:     proto.Int32(200),
+						ApplicationId:  applicationUUID,
+					},
+				},
+			)
+
+			httpStartStopChan = make(chan prometheus.Metric)
+
+			requestsMetric = prometheus.MustNewConstMetric(
+				requestsDesc,
+				prometheus.CounterValue,
+				float64(1),
+				applicationId,
+				"GET",
+				"2xx",
+			)
+
+			requestDurationSecondsMetric = prometheus.MustNewConstHistogram(
+				requestDurationSecondsDesc,
+				uint64(1),
+				duration.Seconds(),
+				map[float64]uint64{
+					.005: 0, .01: 0, .025: 0, .05: 0, .1: 0, .25: 1, .5: 1, 1: 1, 2.5: 1, 5: 1, 10: 1,
+				},
+				applicationId,
+				"GET",
+			)
+		})
+
+		JustBeforeEach(func() {
+			go httpStartStopCollector.Collect(httpStartStopChan)
+		})
+
+		It("returns a http_start_stop_requests_total metric", func() {
+			Eventually(httpStartStopChan).Should(Receive(Equal(requestsMetric)))
+		})
+
+		It("returns a http_start_stop_request_duration_seconds metric", func() {
+			Eventually(httpStartStopChan).Should(Receive(Equal(requestDurationSecondsMetric)))
+		})
+
+		Context("when there is no http start stop metrics", func() {
+			BeforeEach(func() {
+				metricsStore.FlushHttpStartStops()
+			})
+
+			It("does not return any metric", func() {
+				Consistently(httpStartStopChan).ShouldNot(Receive())
+			})
+		})
+	})
+})
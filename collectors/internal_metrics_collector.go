@@ -1,6 +1,9 @@
 package collectors
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
@@ -22,117 +25,336 @@ type InternalMetricsCollector struct {
 	totalValueMetricsReceivedDesc            *prometheus.Desc
 	totalValueMetricsProcessedDesc           *prometheus.Desc
 	lastValueMetricReceivedTimestampDesc     *prometheus.Desc
+	totalHttpStartStopsReceivedDesc          *prometheus.Desc
+	totalHttpStartStopsProcessedDesc         *prometheus.Desc
+	lastHttpStartStopReceivedTimestampDesc   *prometheus.Desc
+	totalLogMessagesReceivedDesc             *prometheus.Desc
+	totalLogMessagesProcessedDesc            *prometheus.Desc
+	lastLogMessageReceivedTimestampDesc      *prometheus.Desc
+	totalErrorsReceivedDesc                  *prometheus.Desc
+	totalErrorsProcessedDesc                 *prometheus.Desc
+	lastErrorReceivedTimestampDesc           *prometheus.Desc
+	firehoseConnectedDesc                    *prometheus.Desc
+	totalFirehoseReconnectsDesc              *prometheus.Desc
+	lastFirehoseReconnectTimestampDesc       *prometheus.Desc
+	envelopeQueueDepthDesc                   *prometheus.Desc
+	totalEnvelopesDequeuedDesc               *prometheus.Desc
+	totalEnvelopeQueueLatencyNanosecondsDesc *prometheus.Desc
+	totalEnvelopesDroppedDesc                *prometheus.Desc
+	totalFirehoseConnectionsDesc             *prometheus.Desc
+	lastFirehoseConnectTimestampDesc         *prometheus.Desc
+	totalEnvelopeBytesDecodedDesc            *prometheus.Desc
+	totalFirehoseDisconnectsDesc             *prometheus.Desc
+	totalSeriesEvictedDesc                   *prometheus.Desc
+	totalSeriesRejectedDesc                  *prometheus.Desc
+	totalEnvelopesFilteredDesc               *prometheus.Desc
+	secondsSinceLastFirehoseConnectDesc      *prometheus.Desc
+	totalDopplerEndpointFailoversDesc        *prometheus.Desc
+	activeDopplerEndpointInfoDesc            *prometheus.Desc
+	totalCounterEventResetsDesc              *prometheus.Desc
 	slowConsumerAlertDesc                    *prometheus.Desc
 	lastSlowConsumerAlertTimestampDesc       *prometheus.Desc
 }
 
 func NewInternalMetricsCollector(
 	namespace string,
+	foundation string,
 	metricsStore *metrics.Store,
 ) *InternalMetricsCollector {
+	constLabels := foundationLabels(foundation)
+
 	totalEnvelopesReceivedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_envelopes_received"),
 		"Total number of envelopes received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	lastEnvelopeReceivedTimestampDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "last_envelope_received_timestamp"),
 		"Number of seconds since 1970 since last envelope received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	totalMetricsReceivedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_metrics_received"),
 		"Total number of metrics received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	lastMetricReceivedTimestampDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "last_metric_received_timestamp"),
 		"Number of seconds since 1970 since last metric received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	totalContainerMetricsReceivedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_container_metrics_received"),
 		"Total number of container metrics received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	totalContainerMetricsProcessedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_container_metrics_processed"),
 		"Total number of container metrics processed from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	lastContainerMetricReceivedTimestampDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "last_container_metric_received_timestamp"),
 		"Number of seconds since 1970 since last container metric received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	totalCounterEventsReceivedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_counter_events_received"),
 		"Total number of counter events received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	totalCounterEventsProcessedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_counter_events_processed"),
 		"Total number of counter events processed from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	lastCounterEventReceivedTimestampDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "last_counter_event_received_timestamp"),
 		"Number of seconds since 1970 since last counter event received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	totalValueMetricsReceivedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_value_metrics_received"),
 		"Total number of value metrics received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	totalValueMetricsProcessedDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "total_value_metrics_processed"),
 		"Total number of value metrics processed from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	lastValueMetricReceivedTimestampDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "last_value_metric_received_timestamp"),
 		"Number of seconds since 1970 since last value metric received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
+	)
+
+	totalHttpStartStopsReceivedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_http_start_stops_received"),
+		"Total number of http start stop events received from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalHttpStartStopsProcessedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_http_start_stops_processed"),
+		"Total number of http start stop events processed from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	lastHttpStartStopReceivedTimestampDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "last_http_start_stop_received_timestamp"),
+		"Number of seconds since 1970 since last http start stop event received from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalLogMessagesReceivedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_log_messages_received"),
+		"Total number of log messages received from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalLogMessagesProcessedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_log_messages_processed"),
+		"Total number of log messages processed from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	lastLogMessageReceivedTimestampDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "last_log_message_received_timestamp"),
+		"Number of seconds since 1970 since last log message received from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalErrorsReceivedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_errors_received"),
+		"Total number of errors received from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalErrorsProcessedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_errors_processed"),
+		"Total number of errors processed from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	lastErrorReceivedTimestampDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "last_error_received_timestamp"),
+		"Number of seconds since 1970 since last error received from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	firehoseConnectedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "firehose_connected"),
+		"Nozzle is connected to Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalFirehoseReconnectsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_firehose_reconnects"),
+		"Total number of reconnection attempts to Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	lastFirehoseReconnectTimestampDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "last_firehose_reconnect_timestamp"),
+		"Number of seconds since 1970 since last reconnection attempt to Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	envelopeQueueDepthDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "envelope_queue_depth"),
+		"Number of envelopes waiting in the processing queue.",
+		[]string{},
+		constLabels,
+	)
+
+	totalEnvelopesDequeuedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_envelopes_dequeued"),
+		"Total number of envelopes taken from the processing queue.",
+		[]string{},
+		constLabels,
+	)
+
+	totalEnvelopeQueueLatencyNanosecondsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_envelope_queue_latency_seconds"),
+		"Total number of seconds envelopes spent waiting in the processing queue.",
+		[]string{},
+		constLabels,
+	)
+
+	totalEnvelopesDroppedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_envelopes_dropped"),
+		"Total number of envelopes dropped because the processing queue was full.",
+		[]string{},
+		constLabels,
+	)
+
+	totalFirehoseConnectionsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_firehose_connections"),
+		"Total number of connections established to Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	lastFirehoseConnectTimestampDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "last_firehose_connect_timestamp"),
+		"Number of seconds since 1970 since last connection established to Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalEnvelopeBytesDecodedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_envelope_bytes_decoded"),
+		"Total number of bytes of the protobuf encoded envelopes decoded from Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalFirehoseDisconnectsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_firehose_disconnects"),
+		"Total number of connections to Cloud Foundry Firehose dropped by websocket close code.",
+		[]string{"close_code"},
+		constLabels,
+	)
+
+	totalSeriesEvictedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_series_evicted"),
+		"Total number of Counter Event and Value Metric series evicted to respect the series limits by reason.",
+		[]string{"reason"},
+		constLabels,
+	)
+
+	totalSeriesRejectedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_series_rejected"),
+		"Total number of new Counter Event and Value Metric series rejected to respect the series limits by reason.",
+		[]string{"reason"},
+		constLabels,
+	)
+
+	totalEnvelopesFilteredDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_envelopes_filtered"),
+		"Total number of envelopes from Cloud Foundry Firehose dropped by a filter, by filter and origin.",
+		[]string{"filter", "origin"},
+		constLabels,
+	)
+
+	secondsSinceLastFirehoseConnectDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "seconds_since_last_firehose_connect"),
+		"Number of seconds since last connection established to Cloud Foundry Firehose.",
+		[]string{},
+		constLabels,
+	)
+
+	totalDopplerEndpointFailoversDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_doppler_endpoint_failovers"),
+		"Total number of fail overs to the next Cloud Foundry Doppler endpoint.",
+		[]string{},
+		constLabels,
+	)
+
+	activeDopplerEndpointInfoDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "active_doppler_endpoint_info"),
+		"Cloud Foundry Doppler endpoint the Nozzle is consuming from.",
+		[]string{"endpoint"},
+		constLabels,
+	)
+
+	totalCounterEventResetsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_counter_event_resets"),
+		"Total number of Counter Event totals reset by their emitter, such as after a restart.",
+		[]string{},
+		constLabels,
 	)
 
 	slowConsumerAlertDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "slow_consumer_alert"),
 		"Nozzle could not keep up with Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	lastSlowConsumerAlertTimestampDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "last_slow_consumer_alert_timestamp"),
 		"Number of seconds since 1970 since last slow consumer alert received from Cloud Foundry Firehose.",
 		[]string{},
-		nil,
+		constLabels,
 	)
 
 	collector := &InternalMetricsCollector{
@@ -151,6 +373,33 @@ func NewInternalMetricsCollector(
 		totalValueMetricsReceivedDesc:            totalValueMetricsReceivedDesc,
 		totalValueMetricsProcessedDesc:           totalValueMetricsProcessedDesc,
 		lastValueMetricReceivedTimestampDesc:     lastValueMetricReceivedTimestampDesc,
+		totalHttpStartStopsReceivedDesc:          totalHttpStartStopsReceivedDesc,
+		totalHttpStartStopsProcessedDesc:         totalHttpStartStopsProcessedDesc,
+		lastHttpStartStopReceivedTimestampDesc:   lastHttpStartStopReceivedTimestampDesc,
+		totalLogMessagesReceivedDesc:             totalLogMessagesReceivedDesc,
+		totalLogMessagesProcessedDesc:            totalLogMessagesProcessedDesc,
+		lastLogMessageReceivedTimestampDesc:      lastLogMessageReceivedTimestampDesc,
+		totalErrorsReceivedDesc:                  totalErrorsReceivedDesc,
+		totalErrorsProcessedDesc:                 totalErrorsProcessedDesc,
+		lastErrorReceivedTimestampDesc:           lastErrorReceivedTimestampDesc,
+		firehoseConnectedDesc:                    firehoseConnectedDesc,
+		totalFirehoseReconnectsDesc:              totalFirehoseReconnectsDesc,
+		lastFirehoseReconnectTimestampDesc:       lastFirehoseReconnectTimestampDesc,
+		envelopeQueueDepthDesc:                   envelopeQueueDepthDesc,
+		totalEnvelopesDequeuedDesc:               totalEnvelopesDequeuedDesc,
+		totalEnvelopeQueueLatencyNanosecondsDesc: totalEnvelopeQueueLatencyNanosecondsDesc,
+		totalEnvelopesDroppedDesc:                totalEnvelopesDroppedDesc,
+		totalFirehoseConnectionsDesc:             totalFirehoseConnectionsDesc,
+		lastFirehoseConnectTimestampDesc:         lastFirehoseConnectTimestampDesc,
+		totalEnvelopeBytesDecodedDesc:            totalEnvelopeBytesDecodedDesc,
+		totalFirehoseDisconnectsDesc:             totalFirehoseDisconnectsDesc,
+		totalSeriesEvictedDesc:                   totalSeriesEvictedDesc,
+		totalSeriesRejectedDesc:                  totalSeriesRejectedDesc,
+		totalEnvelopesFilteredDesc:               totalEnvelopesFilteredDesc,
+		secondsSinceLastFirehoseConnectDesc:      secondsSinceLastFirehoseConnectDesc,
+		totalDopplerEndpointFailoversDesc:        totalDopplerEndpointFailoversDesc,
+		activeDopplerEndpointInfoDesc:            activeDopplerEndpointInfoDesc,
+		totalCounterEventResetsDesc:              totalCounterEventResetsDesc,
 		slowConsumerAlertDesc:                    slowConsumerAlertDesc,
 		lastSlowConsumerAlertTimestampDesc:       lastSlowConsumerAlertTimestampDesc,
 	}
@@ -238,6 +487,194 @@ func (c InternalMetricsCollector) Collect(ch chan<- prometheus.Metric) {
 		float64(internalMetrics.LastValueMetricReceivedTimestamp),
 	)
 
+	ch <- prometheus.MustNewConstMetric(
+		c.totalHttpStartStopsReceivedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalHttpStartStopsReceived),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalHttpStartStopsProcessedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalHttpStartStopsProcessed),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.lastHttpStartStopReceivedTimestampDesc,
+		prometheus.GaugeValue,
+		float64(internalMetrics.LastHttpStartStopReceivedTimestamp),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalLogMessagesReceivedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalLogMessagesReceived),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalLogMessagesProcessedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalLogMessagesProcessed),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.lastLogMessageReceivedTimestampDesc,
+		prometheus.GaugeValue,
+		float64(internalMetrics.LastLogMessageReceivedTimestamp),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalErrorsReceivedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalErrorsReceived),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalErrorsProcessedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalErrorsProcessed),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.lastErrorReceivedTimestampDesc,
+		prometheus.GaugeValue,
+		float64(internalMetrics.LastErrorReceivedTimestamp),
+	)
+
+	if internalMetrics.FirehoseConnected {
+		ch <- prometheus.MustNewConstMetric(
+			c.firehoseConnectedDesc,
+			prometheus.UntypedValue,
+			1,
+		)
+	} else {
+		ch <- prometheus.MustNewConstMetric(
+			c.firehoseConnectedDesc,
+			prometheus.UntypedValue,
+			0,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalFirehoseReconnectsDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalFirehoseReconnects),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.lastFirehoseReconnectTimestampDesc,
+		prometheus.GaugeValue,
+		float64(internalMetrics.LastFirehoseReconnectTimestamp),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.envelopeQueueDepthDesc,
+		prometheus.GaugeValue,
+		float64(internalMetrics.EnvelopeQueueDepth),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalEnvelopesDequeuedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalEnvelopesDequeued),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalEnvelopeQueueLatencyNanosecondsDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalEnvelopeQueueLatencyNanoseconds)/1e9,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalEnvelopesDroppedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalEnvelopesDropped),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalFirehoseConnectionsDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalFirehoseConnections),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.lastFirehoseConnectTimestampDesc,
+		prometheus.GaugeValue,
+		float64(internalMetrics.LastFirehoseConnectTimestamp),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalEnvelopeBytesDecodedDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalEnvelopeBytesDecoded),
+	)
+
+	for closeCode, disconnects := range internalMetrics.TotalFirehoseDisconnects {
+		ch <- prometheus.MustNewConstMetric(
+			c.totalFirehoseDisconnectsDesc,
+			prometheus.CounterValue,
+			float64(disconnects),
+			strconv.Itoa(closeCode),
+		)
+	}
+
+	for reason, evicted := range internalMetrics.TotalSeriesEvicted {
+		ch <- prometheus.MustNewConstMetric(
+			c.totalSeriesEvictedDesc,
+			prometheus.CounterValue,
+			float64(evicted),
+			reason,
+		)
+	}
+
+	for reason, rejected := range internalMetrics.TotalSeriesRejected {
+		ch <- prometheus.MustNewConstMetric(
+			c.totalSeriesRejectedDesc,
+			prometheus.CounterValue,
+			float64(rejected),
+			reason,
+		)
+	}
+
+	for filteredEnvelopes, filtered := range internalMetrics.TotalEnvelopesFiltered {
+		ch <- prometheus.MustNewConstMetric(
+			c.totalEnvelopesFilteredDesc,
+			prometheus.CounterValue,
+			float64(filtered),
+			filteredEnvelopes.Filter,
+			filteredEnvelopes.Origin,
+		)
+	}
+
+	if internalMetrics.LastFirehoseConnectTimestamp > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.secondsSinceLastFirehoseConnectDesc,
+			prometheus.GaugeValue,
+			time.Since(time.Unix(internalMetrics.LastFirehoseConnectTimestamp, 0)).Seconds(),
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalDopplerEndpointFailoversDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalDopplerEndpointFailovers),
+	)
+
+	if internalMetrics.ActiveDopplerEndpoint != "" {
+		ch <- prometheus.MustNewConstMetric(
+			c.activeDopplerEndpointInfoDesc,
+			prometheus.GaugeValue,
+			1,
+			internalMetrics.ActiveDopplerEndpoint,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.totalCounterEventResetsDesc,
+		prometheus.CounterValue,
+		float64(internalMetrics.TotalCounterEventResets),
+	)
+
 	if internalMetrics.SlowConsumerAlert {
 		ch <- prometheus.MustNewConstMetric(
 			c.slowConsumerAlertDesc,
@@ -273,6 +710,33 @@ func (c InternalMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.totalValueMetricsReceivedDesc
 	ch <- c.totalValueMetricsProcessedDesc
 	ch <- c.lastValueMetricReceivedTimestampDesc
+	ch <- c.totalHttpStartStopsReceivedDesc
+	ch <- c.totalHttpStartStopsProcessedDesc
+	ch <- c.lastHttpStartStopReceivedTimestampDesc
+	ch <- c.totalLogMessagesReceivedDesc
+	ch <- c.totalLogMessagesProcessedDesc
+	ch <- c.lastLogMessageReceivedTimestampDesc
+	ch <- c.totalErrorsReceivedDesc
+	ch <- c.totalErrorsProcessedDesc
+	ch <- c.lastErrorReceivedTimestampDesc
+	ch <- c.firehoseConnectedDesc
+	ch <- c.totalFirehoseReconnectsDesc
+	ch <- c.lastFirehoseReconnectTimestampDesc
+	ch <- c.envelopeQueueDepthDesc
+	ch <- c.totalEnvelopesDequeuedDesc
+	ch <- c.totalEnvelopeQueueLatencyNanosecondsDesc
+	ch <- c.totalEnvelopesDroppedDesc
+	ch <- c.totalFirehoseConnectionsDesc
+	ch <- c.lastFirehoseConnectTimestampDesc
+	ch <- c.totalEnvelopeBytesDecodedDesc
+	ch <- c.totalFirehoseDisconnectsDesc
+	ch <- c.totalSeriesEvictedDesc
+	ch <- c.totalSeriesRejectedDesc
+	ch <- c.totalEnvelopesFilteredDesc
+	ch <- c.secondsSinceLastFirehoseConnectDesc
+	ch <- c.totalDopplerEndpointFailoversDesc
+	ch <- c.activeDopplerEndpointInfoDesc
+	ch <- c.totalCounterEventResetsDesc
 	ch <- c.slowConsumerAlertDesc
 	ch <- c.lastSlowConsumerAlertTimestampDesc
 }
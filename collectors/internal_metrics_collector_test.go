@@ -8,7 +8,9 @@ import (
 
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
 )
@@ -16,11 +18,13 @@ import (
 var _ = Describe("InternalMetricsCollector", func() {
 	var (
 		namespace                string
+		foundation               string
 		metricsStore             *metrics.Store
 		metricsExpiration        time.Duration
 		metricsCleanupInterval   time.Duration
 		deploymentFilter         *filters.DeploymentFilter
 		eventFilter              *filters.EventFilter
+		routeTemplates           *utils.RouteTemplates
 		internalMetricsCollector *InternalMetricsCollector
 
 		totalEnvelopesReceivedDesc               *prometheus.Desc
@@ -36,15 +40,44 @@ var _ = Describe("InternalMetricsCollector", func() {
 		totalValueMetricsReceivedDesc            *prometheus.Desc
 		totalValueMetricsProcessedDesc           *prometheus.Desc
 		lastValueMetricReceivedTimestampDesc     *prometheus.Desc
+		totalHttpStartStopsReceivedDesc          *prometheus.Desc
+		totalHttpStartStopsProcessedDesc         *prometheus.Desc
+		lastHttpStartStopReceivedTimestampDesc   *prometheus.Desc
+		totalLogMessagesReceivedDesc             *prometheus.Desc
+		totalLogMessagesProcessedDesc            *prometheus.Desc
+		lastLogMessageReceivedTimestampDesc      *prometheus.Desc
+		totalErrorsReceivedDesc                  *prometheus.Desc
+		totalErrorsProcessedDesc                 *prometheus.Desc
+		lastErrorReceivedTimestampDesc           *prometheus.Desc
+		firehoseConnectedDesc                    *prometheus.Desc
+		totalFirehoseReconnectsDesc              *prometheus.Desc
+		lastFirehoseReconnectTimestampDesc       *prometheus.Desc
+		envelopeQueueDepthDesc                   *prometheus.Desc
+		totalEnvelopesDequeuedDesc               *prometheus.Desc
+		totalEnvelopeQueueLatencyNanosecondsDesc *prometheus.Desc
+		totalEnvelopesDroppedDesc                *prometheus.Desc
+		totalFirehoseConnectionsDesc             *prometheus.Desc
+		lastFirehoseConnectTimestampDesc         *prometheus.Desc
+		totalEnvelopeBytesDecodedDesc            *prometheus.Desc
+		totalFirehoseDisconnectsDesc             *prometheus.Desc
+		totalSeriesEvictedDesc                   *prometheus.Desc
+		totalSeriesRejectedDesc                  *prometheus.Desc
+		totalEnvelopesFilteredDesc               *prometheus.Desc
+		secondsSinceLastFirehoseConnectDesc      *prometheus.Desc
+		activeDopplerEndpointInfoDesc            *prometheus.Desc
+		totalDopplerEndpointFailoversDesc        *prometheus.Desc
+		totalCounterEventResetsDesc              *prometheus.Desc
 		slowConsumerAlertDesc                    *prometheus.Desc
 		lastSlowConsumerAlertTimestampDesc       *prometheus.Desc
 	)
 
 	BeforeEach(func() {
 		namespace = "test_exporter"
+		foundation = ""
 		deploymentFilter = filters.NewDeploymentFilter([]string{})
 		eventFilter, _ = filters.NewEventFilter([]string{})
-		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter)
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
 
 		totalEnvelopesReceivedDesc = prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "total_envelopes_received"),
@@ -137,6 +170,195 @@ var _ = Describe("InternalMetricsCollector", func() {
 			nil,
 		)
 
+		totalHttpStartStopsReceivedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_http_start_stops_received"),
+			"Total number of http start stop events received from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalHttpStartStopsProcessedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_http_start_stops_processed"),
+			"Total number of http start stop events processed from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		lastHttpStartStopReceivedTimestampDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_http_start_stop_received_timestamp"),
+			"Number of seconds since 1970 since last http start stop event received from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalLogMessagesReceivedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_log_messages_received"),
+			"Total number of log messages received from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalLogMessagesProcessedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_log_messages_processed"),
+			"Total number of log messages processed from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		lastLogMessageReceivedTimestampDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_log_message_received_timestamp"),
+			"Number of seconds since 1970 since last log message received from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalErrorsReceivedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_errors_received"),
+			"Total number of errors received from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalErrorsProcessedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_errors_processed"),
+			"Total number of errors processed from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		lastErrorReceivedTimestampDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_error_received_timestamp"),
+			"Number of seconds since 1970 since last error received from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		firehoseConnectedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "firehose_connected"),
+			"Nozzle is connected to Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalFirehoseReconnectsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_firehose_reconnects"),
+			"Total number of reconnection attempts to Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		lastFirehoseReconnectTimestampDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_firehose_reconnect_timestamp"),
+			"Number of seconds since 1970 since last reconnection attempt to Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		envelopeQueueDepthDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "envelope_queue_depth"),
+			"Number of envelopes waiting in the processing queue.",
+			[]string{},
+			nil,
+		)
+
+		totalEnvelopesDequeuedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_envelopes_dequeued"),
+			"Total number of envelopes taken from the processing queue.",
+			[]string{},
+			nil,
+		)
+
+		totalEnvelopeQueueLatencyNanosecondsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_envelope_queue_latency_seconds"),
+			"Total number of seconds envelopes spent waiting in the processing queue.",
+			[]string{},
+			nil,
+		)
+
+		totalEnvelopesDroppedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_envelopes_dropped"),
+			"Total number of envelopes dropped because the processing queue was full.",
+			[]string{},
+			nil,
+		)
+
+		totalFirehoseConnectionsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_firehose_connections"),
+			"Total number of connections established to Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		lastFirehoseConnectTimestampDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_firehose_connect_timestamp"),
+			"Number of seconds since 1970 since last connection established to Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalEnvelopeBytesDecodedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_envelope_bytes_decoded"),
+			"Total number of bytes of the protobuf encoded envelopes decoded from Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalFirehoseDisconnectsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_firehose_disconnects"),
+			"Total number of connections to Cloud Foundry Firehose dropped by websocket close code.",
+			[]string{"close_code"},
+			nil,
+		)
+
+		totalSeriesEvictedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_series_evicted"),
+			"Total number of Counter Event and Value Metric series evicted to respect the series limits by reason.",
+			[]string{"reason"},
+			nil,
+		)
+
+		totalSeriesRejectedDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_series_rejected"),
+			"Total number of new Counter Event and Value Metric series rejected to respect the series limits by reason.",
+			[]string{"reason"},
+			nil,
+		)
+
+		totalEnvelopesFilteredDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_envelopes_filtered"),
+			"Total number of envelopes from Cloud Foundry Firehose dropped by a filter, by filter and origin.",
+			[]string{"filter", "origin"},
+			nil,
+		)
+
+		secondsSinceLastFirehoseConnectDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "seconds_since_last_firehose_connect"),
+			"Number of seconds since last connection established to Cloud Foundry Firehose.",
+			[]string{},
+			nil,
+		)
+
+		totalDopplerEndpointFailoversDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_doppler_endpoint_failovers"),
+			"Total number of fail overs to the next Cloud Foundry Doppler endpoint.",
+			[]string{},
+			nil,
+		)
+
+		activeDopplerEndpointInfoDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active_doppler_endpoint_info"),
+			"Cloud Foundry Doppler endpoint the Nozzle is consuming from.",
+			[]string{"endpoint"},
+			nil,
+		)
+
+		totalCounterEventResetsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "total_counter_event_resets"),
+			"Total number of Counter Event totals reset by their emitter, such as after a restart.",
+			[]string{},
+			nil,
+		)
+
 		slowConsumerAlertDesc = prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "slow_consumer_alert"),
 			"Nozzle could not keep up with Cloud Foundry Firehose.",
@@ -153,7 +375,7 @@ var _ = Describe("InternalMetricsCollector", func() {
 	})
 
 	JustBeforeEach(func() {
-		internalMetricsCollector = NewInternalMetricsCollector(namespace, metricsStore)
+		internalMetricsCollector = NewInternalMetricsCollector(namespace, foundation, metricsStore)
 	})
 
 	Describe("Describe", func() {
@@ -221,6 +443,114 @@ var _ = Describe("InternalMetricsCollector", func() {
 			Eventually(descriptions).Should(Receive(Equal(lastValueMetricReceivedTimestampDesc)))
 		})
 
+		It("returns a total_http_start_stops_received metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalHttpStartStopsReceivedDesc)))
+		})
+
+		It("returns a total_http_start_stops_processed metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalHttpStartStopsProcessedDesc)))
+		})
+
+		It("returns a last_http_start_stop_received_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastHttpStartStopReceivedTimestampDesc)))
+		})
+
+		It("returns a total_log_messages_received metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalLogMessagesReceivedDesc)))
+		})
+
+		It("returns a total_log_messages_processed metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalLogMessagesProcessedDesc)))
+		})
+
+		It("returns a last_log_message_received_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastLogMessageReceivedTimestampDesc)))
+		})
+
+		It("returns a total_errors_received metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalErrorsReceivedDesc)))
+		})
+
+		It("returns a total_errors_processed metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalErrorsProcessedDesc)))
+		})
+
+		It("returns a last_error_received_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastErrorReceivedTimestampDesc)))
+		})
+
+		It("returns a firehose_connected metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(firehoseConnectedDesc)))
+		})
+
+		It("returns a total_firehose_reconnects metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalFirehoseReconnectsDesc)))
+		})
+
+		It("returns a last_firehose_reconnect_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastFirehoseReconnectTimestampDesc)))
+		})
+
+		It("returns a envelope_queue_depth metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(envelopeQueueDepthDesc)))
+		})
+
+		It("returns a total_envelopes_dequeued metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalEnvelopesDequeuedDesc)))
+		})
+
+		It("returns a total_envelope_queue_latency_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalEnvelopeQueueLatencyNanosecondsDesc)))
+		})
+
+		It("returns a total_envelopes_dropped metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalEnvelopesDroppedDesc)))
+		})
+
+		It("returns a total_firehose_connections metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalFirehoseConnectionsDesc)))
+		})
+
+		It("returns a last_firehose_connect_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastFirehoseConnectTimestampDesc)))
+		})
+
+		It("returns a total_envelope_bytes_decoded metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalEnvelopeBytesDecodedDesc)))
+		})
+
+		It("returns a total_firehose_disconnects metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalFirehoseDisconnectsDesc)))
+		})
+
+		It("returns a total_series_evicted metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalSeriesEvictedDesc)))
+		})
+
+		It("returns a total_series_rejected metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalSeriesRejectedDesc)))
+		})
+
+		It("returns a total_envelopes_filtered metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalEnvelopesFilteredDesc)))
+		})
+
+		It("returns a seconds_since_last_firehose_connect metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(secondsSinceLastFirehoseConnectDesc)))
+		})
+
+		It("returns a total_doppler_endpoint_failovers metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalDopplerEndpointFailoversDesc)))
+		})
+
+		It("returns a active_doppler_endpoint_info metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(activeDopplerEndpointInfoDesc)))
+		})
+
+		It("returns a total_counter_event_resets metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(totalCounterEventResetsDesc)))
+		})
+
 		It("returns a slow_consumer_alert metric description", func() {
 			Eventually(descriptions).Should(Receive(Equal(slowConsumerAlertDesc)))
 		})
@@ -246,6 +576,32 @@ var _ = Describe("InternalMetricsCollector", func() {
 			totalValueMetricsReceived            = int64(300)
 			totalValueMetricsProcessed           = int64(150)
 			lastValueMetricReceivedTimestamp     = time.Now().Unix()
+			totalHttpStartStopsReceived          = int64(400)
+			totalHttpStartStopsProcessed         = int64(200)
+			lastHttpStartStopReceivedTimestamp   = time.Now().Unix()
+			totalLogMessagesReceived             = int64(600)
+			totalLogMessagesProcessed            = int64(300)
+			lastLogMessageReceivedTimestamp      = time.Now().Unix()
+			totalErrorsReceived                  = int64(600)
+			totalErrorsProcessed                 = int64(300)
+			lastErrorReceivedTimestamp           = time.Now().Unix()
+			firehoseConnected                    = true
+			totalFirehoseReconnects              = int64(5)
+			lastFirehoseReconnectTimestamp       = time.Now().Unix()
+			envelopeQueueDepth                   = int64(50)
+			totalEnvelopesDequeued               = int64(1000)
+			totalEnvelopeQueueLatencyNanoseconds = int64(2500000000)
+			totalEnvelopesDropped                = int64(10)
+			totalFirehoseConnections             = int64(3)
+			lastFirehoseConnectTimestamp         = time.Now().Unix()
+			totalEnvelopeBytesDecoded            = int64(4096)
+			totalFirehoseDisconnects             = map[int]int64{1000: 2, 1006: 1}
+			totalSeriesEvicted                   = map[string]int64{"max_series_per_origin": 5}
+			totalSeriesRejected                  = map[string]int64{"max_series": 3}
+			totalEnvelopesFiltered               = map[metrics.FilteredEnvelopes]int64{{Filter: "origin_pattern", Origin: "gorouter"}: 7}
+			activeDopplerEndpoint                = "wss://doppler.example.com:443"
+			totalDopplerEndpointFailovers        = int64(2)
+			totalCounterEventResets              = int64(2)
 			slowConsumerAlert                    = false
 			lastSlowConsumerAlertTimestamp       = time.Now().Unix()
 
@@ -263,6 +619,32 @@ var _ = Describe("InternalMetricsCollector", func() {
 			totalValueMetricsReceivedMetric            prometheus.Metric
 			totalValueMetricsProcessedMetric           prometheus.Metric
 			lastValueMetricReceivedTimestampMetric     prometheus.Metric
+			totalHttpStartStopsReceivedMetric          prometheus.Metric
+			totalHttpStartStopsProcessedMetric         prometheus.Metric
+			lastHttpStartStopReceivedTimestampMetric   prometheus.Metric
+			totalLogMessagesReceivedMetric             prometheus.Metric
+			totalLogMessagesProcessedMetric            prometheus.Metric
+			lastLogMessageReceivedTimestampMetric      prometheus.Metric
+			totalErrorsReceivedMetric                  prometheus.Metric
+			totalErrorsProcessedMetric                 prometheus.Metric
+			lastErrorReceivedTimestampMetric           prometheus.Metric
+			firehoseConnectedMetric                    prometheus.Metric
+			totalFirehoseReconnectsMetric              prometheus.Metric
+			lastFirehoseReconnectTimestampMetric       prometheus.Metric
+			envelopeQueueDepthMetric                   prometheus.Metric
+			totalEnvelopesDequeuedMetric               prometheus.Metric
+			totalEnvelopeQueueLatencyNanosecondsMetric prometheus.Metric
+			totalEnvelopesDroppedMetric                prometheus.Metric
+			totalFirehoseConnectionsMetric             prometheus.Metric
+			lastFirehoseConnectTimestampMetric         prometheus.Metric
+			totalEnvelopeBytesDecodedMetric            prometheus.Metric
+			totalFirehoseDisconnectsMetric             prometheus.Metric
+			totalSeriesEvictedMetric                   prometheus.Metric
+			totalSeriesRejectedMetric                  prometheus.Metric
+			totalEnvelopesFilteredMetric               prometheus.Metric
+			activeDopplerEndpointInfoMetric            prometheus.Metric
+			totalDopplerEndpointFailoversMetric        prometheus.Metric
+			totalCounterEventResetsMetric              prometheus.Metric
 			slowConsumerAlertMetric                    prometheus.Metric
 			lastSlowConsumerAlertTimestampMetric       prometheus.Metric
 		)
@@ -282,6 +664,32 @@ var _ = Describe("InternalMetricsCollector", func() {
 				TotalValueMetricsReceived:            totalValueMetricsReceived,
 				TotalValueMetricsProcessed:           totalValueMetricsProcessed,
 				LastValueMetricReceivedTimestamp:     lastValueMetricReceivedTimestamp,
+				TotalHttpStartStopsReceived:          totalHttpStartStopsReceived,
+				TotalHttpStartStopsProcessed:         totalHttpStartStopsProcessed,
+				LastHttpStartStopReceivedTimestamp:   lastHttpStartStopReceivedTimestamp,
+				TotalLogMessagesReceived:             totalLogMessagesReceived,
+				TotalLogMessagesProcessed:            totalLogMessagesProcessed,
+				LastLogMessageReceivedTimestamp:      lastLogMessageReceivedTimestamp,
+				TotalErrorsReceived:                  totalErrorsReceived,
+				TotalErrorsProcessed:                 totalErrorsProcessed,
+				LastErrorReceivedTimestamp:           lastErrorReceivedTimestamp,
+				FirehoseConnected:                    firehoseConnected,
+				TotalFirehoseReconnects:              totalFirehoseReconnects,
+				LastFirehoseReconnectTimestamp:       lastFirehoseReconnectTimestamp,
+				EnvelopeQueueDepth:                   envelopeQueueDepth,
+				TotalEnvelopesDequeued:               totalEnvelopesDequeued,
+				TotalEnvelopeQueueLatencyNanoseconds: totalEnvelopeQueueLatencyNanoseconds,
+				TotalEnvelopesDropped:                totalEnvelopesDropped,
+				TotalFirehoseConnections:             totalFirehoseConnections,
+				LastFirehoseConnectTimestamp:         lastFirehoseConnectTimestamp,
+				TotalEnvelopeBytesDecoded:            totalEnvelopeBytesDecoded,
+				TotalFirehoseDisconnects:             totalFirehoseDisconnects,
+				TotalSeriesEvicted:                   totalSeriesEvicted,
+				TotalSeriesRejected:                  totalSeriesRejected,
+				TotalEnvelopesFiltered:               totalEnvelopesFiltered,
+				ActiveDopplerEndpoint:                activeDopplerEndpoint,
+				TotalDopplerEndpointFailovers:        totalDopplerEndpointFailovers,
+				TotalCounterEventResets:              totalCounterEventResets,
 				SlowConsumerAlert:                    slowConsumerAlert,
 				LastSlowConsumerAlertTimestamp:       lastSlowConsumerAlertTimestamp,
 			}
@@ -366,6 +774,168 @@ var _ = Describe("InternalMetricsCollector", func() {
 				float64(lastValueMetricReceivedTimestamp),
 			)
 
+			totalHttpStartStopsReceivedMetric = prometheus.MustNewConstMetric(
+				totalHttpStartStopsReceivedDesc,
+				prometheus.CounterValue,
+				float64(totalHttpStartStopsReceived),
+			)
+
+			totalHttpStartStopsProcessedMetric = prometheus.MustNewConstMetric(
+				totalHttpStartStopsProcessedDesc,
+				prometheus.CounterValue,
+				float64(totalHttpStartStopsProcessed),
+			)
+
+			lastHttpStartStopReceivedTimestampMetric = prometheus.MustNewConstMetric(
+				lastHttpStartStopReceivedTimestampDesc,
+				prometheus.GaugeValue,
+				float64(lastHttpStartStopReceivedTimestamp),
+			)
+
+			totalLogMessagesReceivedMetric = prometheus.MustNewConstMetric(
+				totalLogMessagesReceivedDesc,
+				prometheus.CounterValue,
+				float64(totalLogMessagesReceived),
+			)
+
+			totalLogMessagesProcessedMetric = prometheus.MustNewConstMetric(
+				totalLogMessagesProcessedDesc,
+				prometheus.CounterValue,
+				float64(totalLogMessagesProcessed),
+			)
+
+			lastLogMessageReceivedTimestampMetric = prometheus.MustNewConstMetric(
+				lastLogMessageReceivedTimestampDesc,
+				prometheus.GaugeValue,
+				float64(lastLogMessageReceivedTimestamp),
+			)
+
+			totalErrorsReceivedMetric = prometheus.MustNewConstMetric(
+				totalErrorsReceivedDesc,
+				prometheus.CounterValue,
+				float64(totalErrorsReceived),
+			)
+
+			totalErrorsProcessedMetric = prometheus.MustNewConstMetric(
+				totalErrorsProcessedDesc,
+				prometheus.CounterValue,
+				float64(totalErrorsProcessed),
+			)
+
+			lastErrorReceivedTimestampMetric = prometheus.MustNewConstMetric(
+				lastErrorReceivedTimestampDesc,
+				prometheus.GaugeValue,
+				float64(lastErrorReceivedTimestamp),
+			)
+
+			firehoseConnectedMetric = prometheus.MustNewConstMetric(
+				firehoseConnectedDesc,
+				prometheus.UntypedValue,
+				1,
+			)
+
+			totalFirehoseReconnectsMetric = prometheus.MustNewConstMetric(
+				totalFirehoseReconnectsDesc,
+				prometheus.CounterValue,
+				float64(totalFirehoseReconnects),
+			)
+
+			lastFirehoseReconnectTimestampMetric = prometheus.MustNewConstMetric(
+				lastFirehoseReconnectTimestampDesc,
+				prometheus.GaugeValue,
+				float64(lastFirehoseReconnectTimestamp),
+			)
+
+			envelopeQueueDepthMetric = prometheus.MustNewConstMetric(
+				envelopeQueueDepthDesc,
+				prometheus.GaugeValue,
+				float64(envelopeQueueDepth),
+			)
+
+			totalEnvelopesDequeuedMetric = prometheus.MustNewConstMetric(
+				totalEnvelopesDequeuedDesc,
+				prometheus.CounterValue,
+				float64(totalEnvelopesDequeued),
+			)
+
+			totalEnvelopeQueueLatencyNanosecondsMetric = prometheus.MustNewConstMetric(
+				totalEnvelopeQueueLatencyNanosecondsDesc,
+				prometheus.CounterValue,
+				float64(totalEnvelopeQueueLatencyNanoseconds)/1e9,
+			)
+
+			totalEnvelopesDroppedMetric = prometheus.MustNewConstMetric(
+				totalEnvelopesDroppedDesc,
+				prometheus.CounterValue,
+				float64(totalEnvelopesDropped),
+			)
+
+			totalFirehoseConnectionsMetric = prometheus.MustNewConstMetric(
+				totalFirehoseConnectionsDesc,
+				prometheus.CounterValue,
+				float64(totalFirehoseConnections),
+			)
+
+			lastFirehoseConnectTimestampMetric = prometheus.MustNewConstMetric(
+				lastFirehoseConnectTimestampDesc,
+				prometheus.GaugeValue,
+				float64(lastFirehoseConnectTimestamp),
+			)
+
+			totalEnvelopeBytesDecodedMetric = prometheus.MustNewConstMetric(
+				totalEnvelopeBytesDecodedDesc,
+				prometheus.CounterValue,
+				float64(totalEnvelopeBytesDecoded),
+			)
+
+			totalFirehoseDisconnectsMetric = prometheus.MustNewConstMetric(
+				totalFirehoseDisconnectsDesc,
+				prometheus.CounterValue,
+				float64(1),
+				"1006",
+			)
+
+			totalSeriesEvictedMetric = prometheus.MustNewConstMetric(
+				totalSeriesEvictedDesc,
+				prometheus.CounterValue,
+				float64(5),
+				"max_series_per_origin",
+			)
+
+			totalSeriesRejectedMetric = prometheus.MustNewConstMetric(
+				totalSeriesRejectedDesc,
+				prometheus.CounterValue,
+				float64(3),
+				"max_series",
+			)
+
+			totalEnvelopesFilteredMetric = prometheus.MustNewConstMetric(
+				totalEnvelopesFilteredDesc,
+				prometheus.CounterValue,
+				float64(7),
+				"origin_pattern",
+				"gorouter",
+			)
+
+			totalDopplerEndpointFailoversMetric = prometheus.MustNewConstMetric(
+				totalDopplerEndpointFailoversDesc,
+				prometheus.CounterValue,
+				float64(totalDopplerEndpointFailovers),
+			)
+
+			activeDopplerEndpointInfoMetric = prometheus.MustNewConstMetric(
+				activeDopplerEndpointInfoDesc,
+				prometheus.GaugeValue,
+				1,
+				activeDopplerEndpoint,
+			)
+
+			totalCounterEventResetsMetric = prometheus.MustNewConstMetric(
+				totalCounterEventResetsDesc,
+				prometheus.CounterValue,
+				float64(totalCounterEventResets),
+			)
+
 			slowConsumerAlertMetric = prometheus.MustNewConstMetric(
 				slowConsumerAlertDesc,
 				prometheus.UntypedValue,
@@ -436,6 +1006,136 @@ var _ = Describe("InternalMetricsCollector", func() {
 			Eventually(internalMetricsChan).Should(Receive(Equal(lastValueMetricReceivedTimestampMetric)))
 		})
 
+		It("returns a total_http_start_stops_received metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalHttpStartStopsReceivedMetric)))
+		})
+
+		It("returns a total_http_start_stops_processed metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalHttpStartStopsProcessedMetric)))
+		})
+
+		It("returns a last_http_start_stop_received_timestamp metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(lastHttpStartStopReceivedTimestampMetric)))
+		})
+
+		It("returns a total_log_messages_received metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalLogMessagesReceivedMetric)))
+		})
+
+		It("returns a total_log_messages_processed metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalLogMessagesProcessedMetric)))
+		})
+
+		It("returns a last_log_message_received_timestamp metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(lastLogMessageReceivedTimestampMetric)))
+		})
+
+		It("returns a total_errors_received metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalErrorsReceivedMetric)))
+		})
+
+		It("returns a total_errors_processed metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalErrorsProcessedMetric)))
+		})
+
+		It("returns a last_error_received_timestamp metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(lastErrorReceivedTimestampMetric)))
+		})
+
+		It("returns a firehose_connected metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(firehoseConnectedMetric)))
+		})
+
+		It("returns a total_firehose_reconnects metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalFirehoseReconnectsMetric)))
+		})
+
+		It("returns a last_firehose_reconnect_timestamp metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(lastFirehoseReconnectTimestampMetric)))
+		})
+
+		It("returns a envelope_queue_depth metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(envelopeQueueDepthMetric)))
+		})
+
+		It("returns a total_envelopes_dequeued metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalEnvelopesDequeuedMetric)))
+		})
+
+		It("returns a total_envelope_queue_latency_seconds metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalEnvelopeQueueLatencyNanosecondsMetric)))
+		})
+
+		It("returns a total_envelopes_dropped metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalEnvelopesDroppedMetric)))
+		})
+
+		It("returns a total_firehose_connections metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalFirehoseConnectionsMetric)))
+		})
+
+		It("returns a last_firehose_connect_timestamp metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(lastFirehoseConnectTimestampMetric)))
+		})
+
+		It("returns a total_envelope_bytes_decoded metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalEnvelopeBytesDecodedMetric)))
+		})
+
+		It("returns a total_firehose_disconnects metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalFirehoseDisconnectsMetric)))
+		})
+
+		It("returns a total_series_evicted metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalSeriesEvictedMetric)))
+		})
+
+		It("returns a total_series_rejected metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalSeriesRejectedMetric)))
+		})
+
+		It("returns a total_envelopes_filtered metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalEnvelopesFilteredMetric)))
+		})
+
+		Context("when the nozzle connected a minute ago", func() {
+			BeforeEach(func() {
+				internalMetrics.LastFirehoseConnectTimestamp = time.Now().Add(-1 * time.Minute).Unix()
+			})
+
+			It("returns a seconds_since_last_firehose_connect metric", func() {
+				sample := &dto.Metric{}
+				Eventually(func() *prometheus.Desc {
+					metric := <-internalMetricsChan
+					metric.Write(sample)
+					return metric.Desc()
+				}).Should(Equal(secondsSinceLastFirehoseConnectDesc))
+				Expect(sample.GetGauge().GetValue()).To(BeNumerically("~", 60, 2))
+			})
+		})
+
+		Context("when the nozzle never connected", func() {
+			BeforeEach(func() {
+				internalMetrics.LastFirehoseConnectTimestamp = 0
+			})
+
+			It("does not return a seconds_since_last_firehose_connect metric", func() {
+				Consistently(internalMetricsChan).ShouldNot(Receive(WithTransform(prometheus.Metric.Desc, Equal(secondsSinceLastFirehoseConnectDesc))))
+			})
+		})
+
+		It("returns a total_doppler_endpoint_failovers metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalDopplerEndpointFailoversMetric)))
+		})
+
+		It("returns a active_doppler_endpoint_info metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(activeDopplerEndpointInfoMetric)))
+		})
+
+		It("returns a total_counter_event_resets metric", func() {
+			Eventually(internalMetricsChan).Should(Receive(Equal(totalCounterEventResetsMetric)))
+		})
+
 		It("returns a slow_consumer_alert metric", func() {
 			Eventually(internalMetricsChan).Should(Receive(Equal(slowConsumerAlertMetric)))
 		})
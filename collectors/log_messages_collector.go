@@ -0,0 +1,70 @@
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+type LogMessagesCollector struct {
+	namespace    string
+	metricsStore *metrics.Store
+	messagesDesc *prometheus.Desc
+	bytesDesc    *prometheus.Desc
+}
+
+func NewLogMessagesCollector(
+	namespace string,
+	foundation string,
+	metricsStore *metrics.Store,
+) *LogMessagesCollector {
+	constLabels := foundationLabels(foundation)
+
+	messagesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, log_messages_subsystem, "messages_total"),
+		"Cloud Foundry Firehose total log messages per application.",
+		[]string{"application_id", "source_type", "message_type"},
+		constLabels,
+	)
+
+	bytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, log_messages_subsystem, "bytes_total"),
+		"Cloud Foundry Firehose total log message bytes per application.",
+		[]string{"application_id", "source_type", "message_type"},
+		constLabels,
+	)
+
+	return &LogMessagesCollector{
+		namespace:    namespace,
+		metricsStore: metricsStore,
+		messagesDesc: messagesDesc,
+		bytesDesc:    bytesDesc,
+	}
+}
+
+func (c LogMessagesCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, logMessage := range c.metricsStore.GetLogMessages() {
+		ch <- prometheus.MustNewConstMetric(
+			c.messagesDesc,
+			prometheus.CounterValue,
+			float64(logMessage.Messages),
+			logMessage.ApplicationId,
+			logMessage.SourceType,
+			logMessage.MessageType,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.bytesDesc,
+			prometheus.CounterValue,
+			float64(logMessage.Bytes),
+			logMessage.ApplicationId,
+			logMessage.SourceType,
+			logMessage.MessageType,
+		)
+	}
+}
+
+func (c LogMessagesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.messagesDesc
+	ch <- c.bytesDesc
+}
@@ -0,0 +1,160 @@
+package collectors_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
+)
+
+var _ = Describe("LogMessagesCollector", func() {
+	var (
+		namespace              string
+		foundation             string
+		metricsStore           *metrics.Store
+		metricsExpiration      time.Duration
+		metricsCleanupInterval time.Duration
+		deploymentFilter       *filters.DeploymentFilter
+		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
+		logMessagesCollector   *LogMessagesCollector
+
+		messagesDesc *prometheus.Desc
+		bytesDesc    *prometheus.Desc
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		foundation = ""
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+
+		messagesDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "log_message", "messages_total"),
+			"Cloud Foundry Firehose total log messages per application.",
+			[]string{"application_id", "source_type", "message_type"},
+			nil,
+		)
+
+		bytesDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "log_message", "bytes_total"),
+			"Cloud Foundry Firehose total log message bytes per application.",
+			[]string{"application_id", "source_type", "message_type"},
+			nil,
+		)
+	})
+
+	JustBeforeEach(func() {
+		logMessagesCollector = NewLogMessagesCollector(namespace, foundation, metricsStore)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go logMessagesCollector.Describe(descriptions)
+		})
+
+		It("returns a log_message_messages_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(messagesDesc)))
+		})
+
+		It("returns a log_message_bytes_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(bytesDesc)))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			applicationId = "fake-application-id"
+			sourceType    = "APP"
+			message       = "fake-log-message"
+
+			logMessagesChan chan prometheus.Metric
+			messagesMetric  prometheus.Metric
+			bytesMetric     prometheus.Metric
+		)
+
+		BeforeEach(func() {
+			for i := 0; i < 2; i++ {
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String("fake-origin"),
+						EventType:  events.Envelope_LogMessage.Enum(),
+						Timestamp:  proto.Int64(time.Now().Unix() * 1000),
+						Deployment: proto.String("fake-deployment-name"),
+						Job:        proto.String("fake-job-name"),
+						Index:      proto.String("0"),
+						Ip:         proto.String("1.2.3.4"),
+						LogMessage: &events.LogMessage{
+							Message:     []byte(message),
+							MessageType: events.LogMessage_ERR.Enum(),
+							Timestamp:   proto.Int64(time.Now().UnixNano()),
+							AppId:       proto.String(applicationId),
+							SourceType:  proto.String(sourceType),
+						},
+					},
+				)
+			}
+
+			logMessagesChan = make(chan prometheus.Metric)
+
+			messagesMetric = prometheus.MustNewConstMetric(
+				messagesDesc,
+				prometheus.CounterValue,
+				float64(2),
+				applicationId,
+				sourceType,
+				"ERR",
+			)
+
+			bytesMetric = prometheus.MustNewConstMetric(
+				bytesDesc,
+				prometheus.CounterValue,
+				float64(2*len(message)),
+				applicationId,
+				sourceType,
+				"ERR",
+			)
+		})
+
+		JustBeforeEach(func() {
+			go logMessagesCollector.Collect(logMessagesChan)
+		})
+
+		It("returns a log_message_messages_total metric", func() {
+			Eventually(logMessagesChan).Should(Receive(Equal(messagesMetric)))
+		})
+
+		It("returns a log_message_bytes_total metric", func() {
+			Eventually(logMessagesChan).Should(Receive(Equal(bytesMetric)))
+		})
+
+		Context("when there is no log messages", func() {
+			BeforeEach(func() {
+				metricsStore.FlushLogMessages()
+			})
+
+			It("does not return any metric", func() {
+				Consistently(logMessagesChan).ShouldNot(Receive())
+			})
+		})
+	})
+})
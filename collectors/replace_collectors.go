@@ -0,0 +1,35 @@
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ReplaceCollectors registers the next collectors in place of the previous ones. The next collectors are
+// checked against a scratch registry before unregistering anything, and the previous collectors are
+// registered again when any of the next ones cannot be registered.
+func ReplaceCollectors(registerer prometheus.Registerer, previous []prometheus.Collector, next []prometheus.Collector) error {
+	scratch := prometheus.NewRegistry()
+	for _, collector := range next {
+		if err := scratch.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	for _, collector := range previous {
+		registerer.Unregister(collector)
+	}
+
+	for i, collector := range next {
+		if err := registerer.Register(collector); err != nil {
+			for _, registered := range next[:i] {
+				registerer.Unregister(registered)
+			}
+			for _, collector := range previous {
+				registerer.Register(collector)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
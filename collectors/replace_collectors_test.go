@@ -0,0 +1,52 @@
+package collectors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
+)
+
+var _ = Describe("ReplaceCollectors", func() {
+	var (
+		registry *prometheus.Registry
+		previous []prometheus.Collector
+
+		newGauge = func(name string, labels ...string) prometheus.Collector {
+			return prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: name}, labels)
+		}
+	)
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+		previous = []prometheus.Collector{newGauge("previous_a"), newGauge("previous_b")}
+		registry.MustRegister(previous...)
+	})
+
+	It("registers the next collectors in place of the previous ones", func() {
+		next := []prometheus.Collector{newGauge("previous_a"), newGauge("next_b")}
+		Expect(ReplaceCollectors(registry, previous, next)).To(Succeed())
+		Expect(registry.Unregister(next[0])).To(BeTrue())
+		Expect(registry.Unregister(next[1])).To(BeTrue())
+		Expect(registry.Unregister(previous[1])).To(BeFalse())
+	})
+
+	It("keeps the previous collectors when the next ones are inconsistent", func() {
+		next := []prometheus.Collector{newGauge("next_a"), newGauge("next_a", "origin")}
+		Expect(ReplaceCollectors(registry, previous, next)).ToNot(Succeed())
+		Expect(registry.Unregister(previous[0])).To(BeTrue())
+		Expect(registry.Unregister(previous[1])).To(BeTrue())
+	})
+
+	It("registers the previous collectors again when a next one cannot be registered", func() {
+		registry.MustRegister(newGauge("other"))
+
+		next := []prometheus.Collector{newGauge("next_a"), newGauge("other", "origin")}
+		Expect(ReplaceCollectors(registry, previous, next)).ToNot(Succeed())
+		Expect(registry.Unregister(previous[0])).To(BeTrue())
+		Expect(registry.Unregister(previous[1])).To(BeTrue())
+		Expect(registry.Unregister(next[0])).To(BeFalse())
+	})
+})
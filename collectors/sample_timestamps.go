@@ -0,0 +1,60 @@
+package collectors
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// SampleTimestamps decides whether the samples of Container Metrics, Counter Events and Value Metrics
+// carry the timestamp of the envelope they come from instead of the scrape time.
+type SampleTimestamps struct {
+	Enabled bool
+	// MaxAge drops the samples whose envelope is older, as Prometheus rejects samples older than the
+	// ones it already ingested. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// sampleTimestamp returns the timestamp of a sample built from an envelope timestamped in nanoseconds,
+// the zero time meaning the scrape time, and false when the sample must be dropped. Envelopes
+// timestamped in the future, due to clock skew between the VMs and the exporter, use the scrape time.
+func (t SampleTimestamps) sampleTimestamp(envelopeTimestamp int64) (time.Time, bool) {
+	if !t.Enabled || envelopeTimestamp <= 0 {
+		return time.Time{}, true
+	}
+
+	now := time.Now()
+	timestamp := time.Unix(0, envelopeTimestamp)
+	if timestamp.After(now) {
+		return time.Time{}, true
+	}
+	if t.MaxAge > 0 && now.Sub(timestamp) > t.MaxAge {
+		return time.Time{}, false
+	}
+
+	return timestamp, true
+}
+
+// timestampedMetric exposes a metric with an explicit sample timestamp.
+type timestampedMetric struct {
+	prometheus.Metric
+	timestamp time.Time
+}
+
+func (m timestampedMetric) Write(metric *dto.Metric) error {
+	if err := m.Metric.Write(metric); err != nil {
+		return err
+	}
+
+	timestampMs := m.timestamp.UnixNano() / int64(time.Millisecond)
+	metric.TimestampMs = &timestampMs
+	return nil
+}
+
+func withTimestamp(metric prometheus.Metric, timestamp time.Time) prometheus.Metric {
+	if timestamp.IsZero() {
+		return metric
+	}
+	return timestampedMetric{Metric: metric, timestamp: timestamp}
+}
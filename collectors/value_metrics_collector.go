@@ -3,6 +3,7 @@ package collectors
 import (
 	"fmt"
 
+	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
@@ -11,23 +12,34 @@ import (
 
 type ValueMetricsCollector struct {
 	namespace                 string
+	constLabels               prometheus.Labels
+	sampleTimestamps          SampleTimestamps
+	aggregationRules          *AggregationRules
 	metricsStore              *metrics.Store
 	valueMetricsCollectorDesc *prometheus.Desc
 }
 
 func NewValueMetricsCollector(
 	namespace string,
+	foundation string,
+	sampleTimestamps SampleTimestamps,
+	aggregationRules *AggregationRules,
 	metricsStore *metrics.Store,
 ) *ValueMetricsCollector {
+	constLabels := foundationLabels(foundation)
+
 	valueMetricsCollectorDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, value_metrics_subsystem, "collector"),
 		"Cloud Foundry Firehose value metrics collector.",
 		nil,
-		nil,
+		constLabels,
 	)
 
 	return &ValueMetricsCollector{
 		namespace:                 namespace,
+		constLabels:               constLabels,
+		sampleTimestamps:          sampleTimestamps,
+		aggregationRules:          aggregationRules,
 		metricsStore:              metricsStore,
 		valueMetricsCollectorDesc: valueMetricsCollectorDesc,
 	}
@@ -35,13 +47,22 @@ func NewValueMetricsCollector(
 
 func (c ValueMetricsCollector) Collect(ch chan<- prometheus.Metric) {
 	for _, valueMetric := range c.metricsStore.GetValueMetrics() {
+		if c.aggregationRules.replaces(events.Envelope_ValueMetric, valueMetric.Origin, valueMetric.Deployment, valueMetric.Name) {
+			continue
+		}
+
+		timestamp, ok := c.sampleTimestamps.sampleTimestamp(valueMetric.Timestamp)
+		if !ok {
+			continue
+		}
+
 		metricName := utils.NormalizeName(valueMetric.Origin) + "_" + utils.NormalizeName(valueMetric.Name)
-		ch <- prometheus.MustNewConstMetric(
+		ch <- withTimestamp(prometheus.MustNewConstMetric(
 			prometheus.NewDesc(
 				prometheus.BuildFQName(c.namespace, value_metrics_subsystem, metricName),
 				fmt.Sprintf("Cloud Foundry Firehose '%s' value metric from '%s'.", valueMetric.Name, valueMetric.Origin),
 				[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "unit"},
-				nil,
+				c.constLabels,
 			),
 			prometheus.GaugeValue,
 			float64(valueMetric.Value),
@@ -51,7 +72,40 @@ func (c ValueMetricsCollector) Collect(ch chan<- prometheus.Metric) {
 			valueMetric.Index,
 			valueMetric.IP,
 			valueMetric.Unit,
+		), timestamp)
+
+		if valueMetric.Aggregate == nil {
+			continue
+		}
+		aggregatedValueMetricDesc := prometheus.NewDesc(
+			prometheus.BuildFQName(c.namespace, aggregated_value_metrics_subsystem, metricName),
+			fmt.Sprintf("Cloud Foundry Firehose '%s' value metric from '%s' aggregated over the aggregation window.", valueMetric.Name, valueMetric.Origin),
+			[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "unit", "aggregate"},
+			c.constLabels,
 		)
+		aggregates := []struct {
+			name  string
+			value float64
+		}{
+			{"min", valueMetric.Aggregate.Min},
+			{"max", valueMetric.Aggregate.Max},
+			{"sum", valueMetric.Aggregate.Sum},
+			{"count", float64(valueMetric.Aggregate.Count)},
+		}
+		for _, aggregate := range aggregates {
+			ch <- prometheus.MustNewConstMetric(
+				aggregatedValueMetricDesc,
+				prometheus.GaugeValue,
+				aggregate.value,
+				valueMetric.Origin,
+				valueMetric.Deployment,
+				valueMetric.Job,
+				valueMetric.Index,
+				valueMetric.IP,
+				valueMetric.Unit,
+				aggregate.name,
+			)
+		}
 	}
 }
 
@@ -9,9 +9,11 @@ import (
 
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	. "github.com/cloudfoundry-community/firehose_exporter/collectors"
 )
@@ -19,11 +21,15 @@ import (
 var _ = Describe("ValueMetricsCollector", func() {
 	var (
 		namespace              string
+		foundation             string
+		sampleTimestamps       SampleTimestamps
+		aggregationRules       *AggregationRules
 		metricsStore           *metrics.Store
 		metricsExpiration      time.Duration
 		metricsCleanupInterval time.Duration
 		deploymentFilter       *filters.DeploymentFilter
 		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
 		valueMetricsCollector  *ValueMetricsCollector
 
 		valueMetricsCollectorDesc *prometheus.Desc
@@ -31,9 +37,13 @@ var _ = Describe("ValueMetricsCollector", func() {
 
 	BeforeEach(func() {
 		namespace = "test_exporter"
+		foundation = ""
+		sampleTimestamps = SampleTimestamps{}
+		aggregationRules = nil
 		deploymentFilter = filters.NewDeploymentFilter([]string{})
 		eventFilter, _ = filters.NewEventFilter([]string{})
-		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter)
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
 
 		valueMetricsCollectorDesc = prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "value_metric", "collector"),
@@ -44,7 +54,7 @@ var _ = Describe("ValueMetricsCollector", func() {
 	})
 
 	JustBeforeEach(func() {
-		valueMetricsCollector = NewValueMetricsCollector(namespace, metricsStore)
+		valueMetricsCollector = NewValueMetricsCollector(namespace, foundation, sampleTimestamps, aggregationRules, metricsStore)
 	})
 
 	Describe("Describe", func() {
@@ -182,5 +192,169 @@ var _ = Describe("ValueMetricsCollector", func() {
 				Consistently(valueMetricsChan).ShouldNot(Receive())
 			})
 		})
+
+		Context("when the value metric is aggregated", func() {
+			var (
+				valueMetric1Max prometheus.Metric
+			)
+
+			BeforeEach(func() {
+				valueAggregations, err := metrics.NewValueMetricAggregations([]string{origin + "/" + valueMetric1Name}, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				metricsStore.SetValueMetricAggregations(valueAggregations)
+
+				for _, value := range []float64{valueMetric1Value * 2, valueMetric1Value} {
+					metricsStore.AddMetric(
+						&events.Envelope{
+							Origin:     proto.String(origin),
+							EventType:  events.Envelope_ValueMetric.Enum(),
+							Timestamp:  proto.Int64(time.Now().Unix() * 1000),
+							Deployment: proto.String(boshDeployment),
+							Job:        proto.String(boshJob),
+							Index:      proto.String(boshIndex),
+							Ip:         proto.String(boshIP),
+							ValueMetric: &events.ValueMetric{
+								Name:  proto.String(valueMetric1Name),
+								Value: proto.Float64(value),
+								Unit:  proto.String(valueMetric1Unit),
+							},
+						},
+					)
+				}
+
+				valueMetric1Max = prometheus.MustNewConstMetric(
+					prometheus.NewDesc(
+						prometheus.BuildFQName(namespace, "aggregated_value_metric", originNormalized+"_"+valueMetric1NameNormalized),
+						fmt.Sprintf("Cloud Foundry Firehose '%s' value metric from '%s' aggregated over the aggregation window.", valueMetric1Name, origin),
+						[]string{"origin", "bosh_deployment", "bosh_job", "bosh_index", "bosh_ip", "unit", "aggregate"},
+						nil,
+					),
+					prometheus.GaugeValue,
+					valueMetric1Value*2,
+					origin,
+					boshDeployment,
+					boshJob,
+					boshIndex,
+					boshIP,
+					valueMetric1Unit,
+					"max",
+				)
+			})
+
+			It("returns the last value_metric_fake_origin_fake_value_metric_1 metric", func() {
+				Eventually(valueMetricsChan).Should(Receive(Equal(valueMetric1)))
+			})
+
+			It("returns an aggregated_value_metric_fake_origin_fake_value_metric_1 metric with the max aggregate", func() {
+				Eventually(valueMetricsChan).Should(Receive(Equal(valueMetric1Max)))
+			})
+
+			Context("and a value metric is named like one of its aggregates", func() {
+				BeforeEach(func() {
+					for _, suffix := range []string{"Min", "Max", "Sum", "Count"} {
+						metricsStore.AddMetric(
+							&events.Envelope{
+								Origin:     proto.String(origin),
+								EventType:  events.Envelope_ValueMetric.Enum(),
+								Timestamp:  proto.Int64(time.Now().Unix() * 1000),
+								Deployment: proto.String(boshDeployment),
+								Job:        proto.String(boshJob),
+								Index:      proto.String(boshIndex),
+								Ip:         proto.String(boshIP),
+								ValueMetric: &events.ValueMetric{
+									Name:  proto.String(valueMetric1Name + suffix),
+									Value: proto.Float64(valueMetric1Value),
+									Unit:  proto.String(valueMetric1Unit),
+								},
+							},
+						)
+					}
+				})
+
+				It("gathers the metrics without collision", func() {
+					registry := prometheus.NewRegistry()
+					Expect(registry.Register(valueMetricsCollector)).To(Succeed())
+
+					_, err := registry.Gather()
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when envelope timestamps are enabled", func() {
+			var (
+				now = time.Now()
+
+				collectTimestamps = func() map[string]*int64 {
+					ch := make(chan prometheus.Metric, 10)
+					valueMetricsCollector.Collect(ch)
+					close(ch)
+
+					timestamps := map[string]*int64{}
+					for metric := range ch {
+						m := &dto.Metric{}
+						Expect(metric.Write(m)).To(Succeed())
+						for _, label := range m.Label {
+							if label.GetName() == "bosh_ip" {
+								timestamps[label.GetValue()] = m.TimestampMs
+							}
+						}
+					}
+					return timestamps
+				}
+			)
+
+			BeforeEach(func() {
+				sampleTimestamps = SampleTimestamps{Enabled: true, MaxAge: 5 * time.Minute}
+
+				metricsStore.FlushValueMetrics()
+				for ip, timestamp := range map[string]time.Time{
+					"1.1.1.1": now.Add(-1 * time.Minute),
+					"2.2.2.2": now.Add(-10 * time.Minute),
+					"3.3.3.3": now.Add(1 * time.Hour),
+				} {
+					metricsStore.AddMetric(
+						&events.Envelope{
+							Origin:     proto.String(origin),
+							EventType:  events.Envelope_ValueMetric.Enum(),
+							Timestamp:  proto.Int64(timestamp.UnixNano()),
+							Deployment: proto.String(boshDeployment),
+							Job:        proto.String(boshJob),
+							Index:      proto.String(boshIndex),
+							Ip:         proto.String(ip),
+							ValueMetric: &events.ValueMetric{
+								Name:  proto.String(valueMetric1Name),
+								Value: proto.Float64(valueMetric1Value),
+								Unit:  proto.String(valueMetric1Unit),
+							},
+						},
+					)
+				}
+			})
+
+			It("exposes the envelope timestamp", func() {
+				Expect(collectTimestamps()).To(HaveKeyWithValue("1.1.1.1", proto.Int64(now.Add(-1*time.Minute).UnixNano()/int64(time.Millisecond))))
+			})
+
+			It("drops the samples older than the maximum age", func() {
+				Expect(collectTimestamps()).ToNot(HaveKey("2.2.2.2"))
+			})
+
+			It("exposes the samples timestamped in the future at the scrape time", func() {
+				timestamps := collectTimestamps()
+				Expect(timestamps).To(HaveKey("3.3.3.3"))
+				Expect(timestamps["3.3.3.3"]).To(BeNil())
+			})
+
+			Context("and there is no maximum age", func() {
+				BeforeEach(func() {
+					sampleTimestamps.MaxAge = 0
+				})
+
+				It("exposes the old samples", func() {
+					Expect(collectTimestamps()).To(HaveKeyWithValue("2.2.2.2", proto.Int64(now.Add(-10*time.Minute).UnixNano()/int64(time.Millisecond))))
+				})
+			})
+		})
 	})
 })
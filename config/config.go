@@ -0,0 +1,166 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Settings are the flag values set by a configuration file, by flag name.
+//
+// The file mirrors the flags: the dotted prefix of a flag name is a nested mapping, dashes can be
+// written as underscores, and comma separated flags take lists.
+//
+//	doppler:
+//	  url: wss://doppler.example.com:443
+//	  metric_expiration: 10m
+//	filter:
+//	  exclude_origins: [gorouter, "/uaa.*/"]
+type Settings map[string]string
+
+// Load reads the settings from a YAML configuration file.
+func Load(path string, flagSet *flag.FlagSet, ignored ...string) (Settings, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data, flagSet, ignored...)
+}
+
+// Parse decodes YAML settings, checking that every setting is a flag of flagSet, other than the ignored
+// ones, and that its value has the type of the flag.
+func Parse(data []byte, flagSet *flag.FlagSet, ignored ...string) (Settings, error) {
+	file := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if err := flatten("", file, values); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	settings := Settings{}
+	for _, name := range names {
+		f := flagSet.Lookup(name)
+		if f == nil || containsString(ignored, name) {
+			return nil, fmt.Errorf("Config setting `%s` is unknown", name)
+		}
+
+		value, err := settingValue(f, values[name])
+		if err != nil {
+			return nil, fmt.Errorf("Config setting `%s`: %v", name, err)
+		}
+		settings[name] = value
+	}
+
+	return settings, nil
+}
+
+func flatten(prefix string, mapping map[interface{}]interface{}, values map[string]interface{}) error {
+	for key, value := range mapping {
+		name, ok := key.(string)
+		if !ok {
+			return fmt.Errorf("Config setting `%s%v` is not a string", prefix, key)
+		}
+		name = prefix + strings.Replace(name, "_", "-", -1)
+
+		if nested, ok := value.(map[interface{}]interface{}); ok {
+			if err := flatten(name+".", nested, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, ok := values[name]; ok {
+			return fmt.Errorf("Config setting `%s` is set more than once", name)
+		}
+		values[name] = value
+	}
+	return nil
+}
+
+func settingValue(f *flag.Flag, value interface{}) (string, error) {
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return "", fmt.Errorf("flag type is not supported")
+	}
+
+	switch getter.Get().(type) {
+	case string:
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				scalar, err := scalarValue(item)
+				if err != nil {
+					return "", err
+				}
+				items[i] = scalar
+			}
+			return strings.Join(items, ","), nil
+		}
+		return scalarValue(value)
+	case bool:
+		if b, ok := value.(bool); ok {
+			return strconv.FormatBool(b), nil
+		}
+		return "", fmt.Errorf("must be a boolean")
+	case uint:
+		if i, ok := value.(int); ok && i >= 0 {
+			return strconv.Itoa(i), nil
+		}
+		return "", fmt.Errorf("must be a positive integer")
+	case float64:
+		switch n := value.(type) {
+		case int:
+			return strconv.Itoa(n), nil
+		case float64:
+			return strconv.FormatFloat(n, 'g', -1, 64), nil
+		}
+		return "", fmt.Errorf("must be a number")
+	case time.Duration:
+		if s, ok := value.(string); ok {
+			if _, err := time.ParseDuration(s); err == nil {
+				return s, nil
+			}
+		}
+		if i, ok := value.(int); ok && i == 0 {
+			return "0s", nil
+		}
+		return "", fmt.Errorf("must be a duration, e.g. 5m")
+	}
+
+	return "", fmt.Errorf("flag type is not supported")
+}
+
+func scalarValue(value interface{}) (string, error) {
+	switch value.(type) {
+	case string, bool, int, float64:
+		return fmt.Sprint(value), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("must be a string or a list of strings")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
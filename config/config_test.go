@@ -0,0 +1,127 @@
+package config_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/config"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		flagSet *flag.FlagSet
+	)
+
+	BeforeEach(func() {
+		flagSet = flag.NewFlagSet("test", flag.ContinueOnError)
+		flagSet.String("doppler.url", "", "")
+		flagSet.Duration("doppler.metric-expiration", 5*time.Minute, "")
+		flagSet.Uint("pipeline.workers", 4, "")
+		flagSet.Float64("replay.speed", 1, "")
+		flagSet.Bool("skip-ssl-verify", false, "")
+		flagSet.String("filter.exclude-origins", "", "")
+		flagSet.String("config.file", "", "")
+	})
+
+	Describe("Parse", func() {
+		It("parses the settings by flag name", func() {
+			settings, err := Parse([]byte(`
+doppler:
+  url: wss://doppler.example.com:443
+  metric_expiration: 10m
+pipeline.workers: 8
+replay:
+  speed: 0.5
+skip-ssl-verify: true
+filter:
+  exclude_origins: [gorouter, "/uaa.*/"]
+`), flagSet, "config.file")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings).To(Equal(Settings{
+				"doppler.url":               "wss://doppler.example.com:443",
+				"doppler.metric-expiration": "10m",
+				"pipeline.workers":          "8",
+				"replay.speed":              "0.5",
+				"skip-ssl-verify":           "true",
+				"filter.exclude-origins":    "gorouter,/uaa.*/",
+			}))
+		})
+
+		It("parses an empty file", func() {
+			settings, err := Parse([]byte(``), flagSet)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings).To(BeEmpty())
+		})
+
+		It("returns an error when a setting is not a flag", func() {
+			_, err := Parse([]byte(`doppler: {urls: "wss://doppler.example.com:443"}`), flagSet)
+			Expect(err).To(MatchError("Config setting `doppler.urls` is unknown"))
+		})
+
+		It("returns an error when a setting is ignored", func() {
+			_, err := Parse([]byte(`config: {file: other.yml}`), flagSet, "config.file")
+			Expect(err).To(MatchError("Config setting `config.file` is unknown"))
+		})
+
+		It("returns an error when a setting is set more than once", func() {
+			_, err := Parse([]byte("doppler:\n  url: a\ndoppler.url: b\n"), flagSet)
+			Expect(err).To(MatchError("Config setting `doppler.url` is set more than once"))
+		})
+
+		It("returns an error when a duration is invalid", func() {
+			_, err := Parse([]byte(`doppler: {metric_expiration: 10}`), flagSet)
+			Expect(err).To(MatchError("Config setting `doppler.metric-expiration`: must be a duration, e.g. 5m"))
+		})
+
+		It("returns an error when an integer is negative", func() {
+			_, err := Parse([]byte(`pipeline: {workers: -1}`), flagSet)
+			Expect(err).To(MatchError("Config setting `pipeline.workers`: must be a positive integer"))
+		})
+
+		It("returns an error when a boolean is invalid", func() {
+			_, err := Parse([]byte(`skip-ssl-verify: maybe`), flagSet)
+			Expect(err).To(MatchError("Config setting `skip-ssl-verify`: must be a boolean"))
+		})
+
+		It("returns an error when a string is a mapping in a list", func() {
+			_, err := Parse([]byte(`filter: {exclude_origins: [{name: gorouter}]}`), flagSet)
+			Expect(err).To(MatchError("Config setting `filter.exclude-origins`: must be a string or a list of strings"))
+		})
+	})
+
+	Describe("Load", func() {
+		var (
+			dir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "config")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("reads the settings from a file", func() {
+			path := filepath.Join(dir, "config.yml")
+			Expect(ioutil.WriteFile(path, []byte(`doppler: {url: "wss://doppler.example.com:443"}`), 0644)).To(Succeed())
+
+			settings, err := Load(path, flagSet)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings).To(Equal(Settings{"doppler.url": "wss://doppler.example.com:443"}))
+		})
+
+		It("returns an error when the file does not exist", func() {
+			_, err := Load(filepath.Join(dir, "missing.yml"), flagSet)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
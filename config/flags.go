@@ -0,0 +1,166 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Flags applies the settings of a configuration file to a flag set. The flags set on the command line
+// or by their environment variable take precedence over the file, and the flags the file does not set
+// keep their default value.
+type Flags struct {
+	flagSet     *flag.FlagSet
+	ignored     []string
+	reloadable  []string
+	commandLine map[string]bool
+}
+
+// NewFlags must be called once the flag set is parsed. The environment variable of a flag is its name
+// in upper case prefixed by envPrefix, dots and dashes being replaced by underscores.
+func NewFlags(flagSet *flag.FlagSet, envPrefix string, ignored []string, reloadable []string) *Flags {
+	commandLine := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) {
+		commandLine[f.Name] = true
+	})
+	flagSet.VisitAll(func(f *flag.Flag) {
+		envVar := envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(f.Name))
+		if os.Getenv(envVar) != "" {
+			commandLine[f.Name] = true
+		}
+	})
+
+	return &Flags{
+		flagSet:     flagSet,
+		ignored:     ignored,
+		reloadable:  reloadable,
+		commandLine: commandLine,
+	}
+}
+
+// Load reads the settings of a configuration file.
+func (f *Flags) Load(path string) (Settings, error) {
+	return Load(path, f.flagSet, f.ignored...)
+}
+
+// Apply sets the flags from the settings, and resets the flags the settings do not set to their default
+// value.
+func (f *Flags) Apply(settings Settings) error {
+	var err error
+	f.visit(func(fl *flag.Flag) {
+		if err != nil || f.commandLine[fl.Name] {
+			return
+		}
+
+		value, ok := settings[fl.Name]
+		if !ok {
+			value = fl.DefValue
+		}
+		if setErr := fl.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("Invalid `%s` setting: %v", fl.Name, setErr)
+		}
+	})
+	return err
+}
+
+// Reload only sets the reloadable flags whose setting changed, and returns the other flags whose setting
+// changed, which are left untouched. The reloaded flags are restored when apply, which puts them into
+// effect, returns an error.
+func (f *Flags) Reload(settings Settings, apply func() error) ([]string, error) {
+	current := f.values()
+	changed := map[string]string{}
+	var err error
+	f.visit(func(fl *flag.Flag) {
+		if err != nil || f.commandLine[fl.Name] {
+			return
+		}
+
+		value, ok := settings[fl.Name]
+		if !ok {
+			value = fl.DefValue
+		}
+		normalized, valueErr := normalizeValue(fl, value)
+		if valueErr != nil {
+			err = fmt.Errorf("Invalid `%s` setting: %v", fl.Name, valueErr)
+			return
+		}
+		if normalized != current[fl.Name] {
+			changed[fl.Name] = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unchanged := []string{}
+	previous := map[string]string{}
+	for name, value := range changed {
+		if !containsString(f.reloadable, name) {
+			unchanged = append(unchanged, name)
+			continue
+		}
+
+		previous[name] = current[name]
+		if err := f.flagSet.Lookup(name).Value.Set(value); err != nil {
+			f.restore(previous)
+			return nil, fmt.Errorf("Invalid `%s` setting: %v", name, err)
+		}
+	}
+	sort.Strings(unchanged)
+
+	if err := apply(); err != nil {
+		f.restore(previous)
+		return nil, err
+	}
+	return unchanged, nil
+}
+
+func (f *Flags) visit(fn func(*flag.Flag)) {
+	f.flagSet.VisitAll(func(fl *flag.Flag) {
+		if !containsString(f.ignored, fl.Name) {
+			fn(fl)
+		}
+	})
+}
+
+func (f *Flags) values() map[string]string {
+	values := map[string]string{}
+	f.visit(func(fl *flag.Flag) {
+		values[fl.Name] = fl.Value.String()
+	})
+	return values
+}
+
+// normalizeValue returns a setting as the flag would print it once set, without setting the flag.
+func normalizeValue(fl *flag.Flag, value string) (string, error) {
+	getter, ok := fl.Value.(flag.Getter)
+	if !ok {
+		return value, nil
+	}
+
+	switch getter.Get().(type) {
+	case bool:
+		b, err := strconv.ParseBool(value)
+		return strconv.FormatBool(b), err
+	case uint:
+		u, err := strconv.ParseUint(value, 0, strconv.IntSize)
+		return strconv.FormatUint(u, 10), err
+	case float64:
+		n, err := strconv.ParseFloat(value, 64)
+		return strconv.FormatFloat(n, 'g', -1, 64), err
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		return d.String(), err
+	}
+	return value, nil
+}
+
+func (f *Flags) restore(values map[string]string) {
+	for name, value := range values {
+		f.flagSet.Lookup(name).Value.Set(value)
+	}
+}
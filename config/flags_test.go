@@ -0,0 +1,161 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/config"
+)
+
+type countingValue struct {
+	value string
+	sets  int
+}
+
+func (v *countingValue) String() string   { return v.value }
+func (v *countingValue) Get() interface{} { return v.value }
+func (v *countingValue) Set(value string) error {
+	v.value = value
+	v.sets++
+	return nil
+}
+
+var _ = Describe("Flags", func() {
+	var (
+		flagSet    *flag.FlagSet
+		dopplerURL *string
+		expiration *time.Duration
+		origins    *string
+		workers    *uint
+		namespace  *countingValue
+		flags      *Flags
+	)
+
+	BeforeEach(func() {
+		flagSet = flag.NewFlagSet("test", flag.ContinueOnError)
+		dopplerURL = flagSet.String("doppler.url", "", "")
+		expiration = flagSet.Duration("doppler.metric-expiration", 5*time.Minute, "")
+		origins = flagSet.String("filter.exclude-origins", "", "")
+		workers = flagSet.Uint("pipeline.workers", 4, "")
+		namespace = &countingValue{value: "firehose_exporter"}
+		flagSet.Var(namespace, "metrics.namespace", "")
+		flagSet.String("config.file", "", "")
+	})
+
+	JustBeforeEach(func() {
+		flags = NewFlags(flagSet, "CONFIG_TEST_", []string{"config.file"}, []string{"filter.exclude-origins"})
+	})
+
+	Describe("Apply", func() {
+		It("sets the flags from the settings", func() {
+			Expect(flags.Apply(Settings{"doppler.url": "wss://doppler.example.com:443", "pipeline.workers": "8"})).To(Succeed())
+			Expect(*dopplerURL).To(Equal("wss://doppler.example.com:443"))
+			Expect(*workers).To(Equal(uint(8)))
+		})
+
+		It("resets the flags the settings do not set to their default value", func() {
+			Expect(flags.Apply(Settings{"doppler.metric-expiration": "10m"})).To(Succeed())
+			Expect(flags.Apply(Settings{})).To(Succeed())
+			Expect(*expiration).To(Equal(5 * time.Minute))
+		})
+
+		It("returns an error when a setting is invalid", func() {
+			Expect(flags.Apply(Settings{"pipeline.workers": "many"})).To(MatchError(HavePrefix("Invalid `pipeline.workers` setting")))
+		})
+
+		Context("when a flag is set on the command line", func() {
+			BeforeEach(func() {
+				Expect(flagSet.Parse([]string{"-doppler.url", "wss://command-line.example.com:443"})).To(Succeed())
+			})
+
+			It("keeps the command line value", func() {
+				Expect(flags.Apply(Settings{"doppler.url": "wss://doppler.example.com:443"})).To(Succeed())
+				Expect(*dopplerURL).To(Equal("wss://command-line.example.com:443"))
+			})
+		})
+
+		Context("when a flag is set by its environment variable", func() {
+			BeforeEach(func() {
+				os.Setenv("CONFIG_TEST_PIPELINE_WORKERS", "2")
+				*workers = 2
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("CONFIG_TEST_PIPELINE_WORKERS")
+			})
+
+			It("keeps the environment variable value", func() {
+				Expect(flags.Apply(Settings{"pipeline.workers": "8"})).To(Succeed())
+				Expect(*workers).To(Equal(uint(2)))
+			})
+		})
+	})
+
+	Describe("Reload", func() {
+		var (
+			applied int
+			apply   = func() error {
+				applied++
+				return nil
+			}
+		)
+
+		JustBeforeEach(func() {
+			applied = 0
+			Expect(flags.Apply(Settings{"doppler.url": "wss://doppler.example.com:443", "filter.exclude-origins": "gorouter"})).To(Succeed())
+		})
+
+		It("applies the reloadable flags", func() {
+			unchanged, err := flags.Reload(Settings{"doppler.url": "wss://doppler.example.com:443", "filter.exclude-origins": "uaa"}, apply)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unchanged).To(BeEmpty())
+			Expect(*origins).To(Equal("uaa"))
+			Expect(applied).To(Equal(1))
+		})
+
+		It("keeps the previous value of the other flags and returns them", func() {
+			unchanged, err := flags.Reload(Settings{"doppler.url": "wss://other.example.com:443", "pipeline.workers": "8", "filter.exclude-origins": "uaa"}, apply)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unchanged).To(Equal([]string{"doppler.url", "pipeline.workers"}))
+			Expect(*dopplerURL).To(Equal("wss://doppler.example.com:443"))
+			Expect(*workers).To(Equal(uint(4)))
+			Expect(*origins).To(Equal("uaa"))
+		})
+
+		It("never sets the other flags", func() {
+			sets := namespace.sets
+			unchanged, err := flags.Reload(Settings{"doppler.url": "wss://doppler.example.com:443", "metrics.namespace": "cf"}, apply)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unchanged).To(Equal([]string{"metrics.namespace"}))
+			Expect(namespace.sets).To(Equal(sets))
+			Expect(namespace.value).To(Equal("firehose_exporter"))
+		})
+
+		It("does not return the flags set to an equivalent value", func() {
+			unchanged, err := flags.Reload(Settings{"doppler.url": "wss://doppler.example.com:443", "doppler.metric-expiration": "300s", "pipeline.workers": "4"}, apply)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unchanged).To(BeEmpty())
+		})
+
+		It("restores the flags when applying them fails", func() {
+			_, err := flags.Reload(Settings{"filter.exclude-origins": "uaa"}, func() error {
+				return errors.New("invalid filter")
+			})
+			Expect(err).To(MatchError("invalid filter"))
+			Expect(*origins).To(Equal("gorouter"))
+			Expect(*dopplerURL).To(Equal("wss://doppler.example.com:443"))
+		})
+
+		It("restores the flags when a setting is invalid", func() {
+			_, err := flags.Reload(Settings{"filter.exclude-origins": "uaa", "pipeline.workers": "many"}, apply)
+			Expect(err).To(HaveOccurred())
+			Expect(*origins).To(Equal("gorouter"))
+			Expect(applied).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package filters
+
+import (
+	"github.com/cloudfoundry/sonde-go/events"
+)
+
+// EnvelopeFilter enables the envelopes whose deployment, job and origin are enabled by the pattern
+// filters, a nil pattern filter enabling any value.
+type EnvelopeFilter struct {
+	deploymentFilter *PatternFilter
+	jobFilter        *PatternFilter
+	originFilter     *PatternFilter
+}
+
+func NewEnvelopeFilter(deploymentFilter *PatternFilter, jobFilter *PatternFilter, originFilter *PatternFilter) *EnvelopeFilter {
+	return &EnvelopeFilter{
+		deploymentFilter: deploymentFilter,
+		jobFilter:        jobFilter,
+		originFilter:     originFilter,
+	}
+}
+
+func (f *EnvelopeFilter) Enabled(envelope *events.Envelope) bool {
+	return f.DisabledBy(envelope) == ""
+}
+
+// DisabledBy returns the field, `deployment`, `job` or `origin`, whose pattern filter does not enable an
+// envelope, or an empty string when the envelope is enabled.
+func (f *EnvelopeFilter) DisabledBy(envelope *events.Envelope) string {
+	if f == nil {
+		return ""
+	}
+
+	switch {
+	case !f.deploymentFilter.Enabled(envelope.GetDeployment()):
+		return "deployment"
+	case !f.jobFilter.Enabled(envelope.GetJob()):
+		return "job"
+	case !f.originFilter.Enabled(envelope.GetOrigin()):
+		return "origin"
+	}
+	return ""
+}
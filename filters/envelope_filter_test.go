@@ -0,0 +1,77 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/filters"
+)
+
+var _ = Describe("EnvelopeFilter", func() {
+	var (
+		envelopeFilter *EnvelopeFilter
+
+		envelope = func(deployment string, job string, origin string) *events.Envelope {
+			return &events.Envelope{
+				Origin:     proto.String(origin),
+				EventType:  events.Envelope_ValueMetric.Enum(),
+				Deployment: proto.String(deployment),
+				Job:        proto.String(job),
+			}
+		}
+	)
+
+	BeforeEach(func() {
+		deploymentFilter, err := NewPatternFilter([]string{"cf"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		jobFilter, err := NewPatternFilter(nil, []string{"/smoke.*/"})
+		Expect(err).ToNot(HaveOccurred())
+		originFilter, err := NewPatternFilter(nil, []string{"syslog_*"})
+		Expect(err).ToNot(HaveOccurred())
+
+		envelopeFilter = NewEnvelopeFilter(deploymentFilter, jobFilter, originFilter)
+	})
+
+	Describe("Enabled", func() {
+		It("enables the envelopes enabled by every pattern filter", func() {
+			Expect(envelopeFilter.Enabled(envelope("cf", "router", "gorouter"))).To(BeTrue())
+		})
+
+		It("does not enable the envelopes of a deployment not enabled", func() {
+			Expect(envelopeFilter.Enabled(envelope("concourse", "router", "gorouter"))).To(BeFalse())
+		})
+
+		It("does not enable the envelopes of a job not enabled", func() {
+			Expect(envelopeFilter.Enabled(envelope("cf", "smoke-tests", "gorouter"))).To(BeFalse())
+		})
+
+		It("does not enable the envelopes of an origin not enabled", func() {
+			Expect(envelopeFilter.Enabled(envelope("cf", "router", "syslog_drain_binder"))).To(BeFalse())
+		})
+
+		Context("when there are no pattern filters", func() {
+			BeforeEach(func() {
+				envelopeFilter = NewEnvelopeFilter(nil, nil, nil)
+			})
+
+			It("enables any envelope", func() {
+				Expect(envelopeFilter.Enabled(envelope("concourse", "smoke-tests", "syslog_drain_binder"))).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("DisabledBy", func() {
+		It("returns an empty string for the enabled envelopes", func() {
+			Expect(envelopeFilter.DisabledBy(envelope("cf", "router", "gorouter"))).To(BeEmpty())
+		})
+
+		It("returns the field whose pattern filter does not enable an envelope", func() {
+			Expect(envelopeFilter.DisabledBy(envelope("concourse", "router", "gorouter"))).To(Equal("deployment"))
+			Expect(envelopeFilter.DisabledBy(envelope("cf", "smoke-tests", "gorouter"))).To(Equal("job"))
+			Expect(envelopeFilter.DisabledBy(envelope("cf", "router", "syslog_drain_binder"))).To(Equal("origin"))
+		})
+	})
+})
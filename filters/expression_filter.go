@@ -0,0 +1,375 @@
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cloudfoundry/sonde-go/events"
+
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+)
+
+// ExpressionFilter enables the envelopes for which a filter expression is true. Expressions compare
+// envelope fields to quoted strings, and are combined with `and`, `or`, `not` and parentheses:
+//
+//	event_type == "ValueMetric" and origin == "gorouter" and deployment != "cf"
+//	event_type != "ContainerMetric" or tags.team in ["payments", "checkout"]
+//	name =~ "latency.*"
+//
+// The fields are origin, deployment, job, index, ip, event_type, name, unit, app_id and tags.<key>.
+// Fields an envelope does not have are empty. The operators are `==`, `!=`, `in`, and `=~` and `!~`
+// matching regular expressions against the whole field.
+type ExpressionFilter struct {
+	expression expressionNode
+}
+
+func NewExpressionFilter(expression string) (*ExpressionFilter, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &expressionParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != endToken {
+		return nil, token.errorf("expected `and`, `or` or the end of the expression, found %s", token)
+	}
+
+	return &ExpressionFilter{expression: node}, nil
+}
+
+func (f *ExpressionFilter) Enabled(envelope *events.Envelope) bool {
+	if f == nil {
+		return true
+	}
+
+	return f.expression.eval(envelope)
+}
+
+type expressionNode interface {
+	eval(envelope *events.Envelope) bool
+}
+
+type andNode struct{ left, right expressionNode }
+
+func (n andNode) eval(envelope *events.Envelope) bool {
+	return n.left.eval(envelope) && n.right.eval(envelope)
+}
+
+type orNode struct{ left, right expressionNode }
+
+func (n orNode) eval(envelope *events.Envelope) bool {
+	return n.left.eval(envelope) || n.right.eval(envelope)
+}
+
+type notNode struct{ node expressionNode }
+
+func (n notNode) eval(envelope *events.Envelope) bool {
+	return !n.node.eval(envelope)
+}
+
+type comparisonNode struct {
+	field   func(envelope *events.Envelope) string
+	negate  bool
+	values  []string
+	pattern *regexp.Regexp
+}
+
+func (n comparisonNode) eval(envelope *events.Envelope) bool {
+	value := n.field(envelope)
+
+	matched := false
+	if n.pattern != nil {
+		matched = n.pattern.MatchString(value)
+	} else {
+		for _, v := range n.values {
+			if v == value {
+				matched = true
+				break
+			}
+		}
+	}
+
+	return matched != n.negate
+}
+
+var expressionFields = map[string]func(envelope *events.Envelope) string{
+	"origin":     func(envelope *events.Envelope) string { return envelope.GetOrigin() },
+	"deployment": func(envelope *events.Envelope) string { return envelope.GetDeployment() },
+	"job":        func(envelope *events.Envelope) string { return envelope.GetJob() },
+	"index":      func(envelope *events.Envelope) string { return envelope.GetIndex() },
+	"ip":         func(envelope *events.Envelope) string { return envelope.GetIp() },
+	"event_type": func(envelope *events.Envelope) string { return envelope.GetEventType().String() },
+	"name": func(envelope *events.Envelope) string {
+		switch envelope.GetEventType() {
+		case events.Envelope_CounterEvent:
+			return envelope.GetCounterEvent().GetName()
+		case events.Envelope_ValueMetric:
+			return envelope.GetValueMetric().GetName()
+		}
+		return ""
+	},
+	"unit": func(envelope *events.Envelope) string { return envelope.GetValueMetric().GetUnit() },
+	"app_id": func(envelope *events.Envelope) string {
+		switch envelope.GetEventType() {
+		case events.Envelope_ContainerMetric:
+			return envelope.GetContainerMetric().GetApplicationId()
+		case events.Envelope_LogMessage:
+			return envelope.GetLogMessage().GetAppId()
+		case events.Envelope_HttpStartStop:
+			if applicationId := envelope.GetHttpStartStop().GetApplicationId(); applicationId != nil {
+				return utils.FormatUUID(applicationId)
+			}
+		}
+		return ""
+	},
+}
+
+func expressionField(name string) (func(envelope *events.Envelope) string, bool) {
+	if strings.HasPrefix(name, "tags.") && len(name) > len("tags.") {
+		tag := strings.TrimPrefix(name, "tags.")
+		return func(envelope *events.Envelope) string { return envelope.GetTags()[tag] }, true
+	}
+
+	field, ok := expressionFields[name]
+	return field, ok
+}
+
+type expressionTokenKind int
+
+const (
+	endToken expressionTokenKind = iota
+	identToken
+	stringToken
+	operatorToken
+	punctuationToken
+)
+
+type expressionToken struct {
+	kind     expressionTokenKind
+	value    string
+	position int
+}
+
+func (t expressionToken) String() string {
+	switch t.kind {
+	case endToken:
+		return "the end of the expression"
+	case stringToken:
+		return strconv.Quote(t.value)
+	}
+	return "`" + t.value + "`"
+}
+
+func (t expressionToken) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Filter expression error at position %d: %s", t.position+1, fmt.Sprintf(format, args...))
+}
+
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	tokens := []expressionToken{}
+
+	for i := 0; i < len(expression); {
+		c := rune(expression[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']' || c == ',':
+			tokens = append(tokens, expressionToken{kind: punctuationToken, value: string(c), position: i})
+			i++
+		case c == '=' || c == '!':
+			if i+1 < len(expression) && (expression[i+1] == '=' || expression[i+1] == '~') {
+				tokens = append(tokens, expressionToken{kind: operatorToken, value: expression[i : i+2], position: i})
+				i += 2
+				continue
+			}
+			return nil, expressionToken{position: i}.errorf("unexpected `%c`, operators are `==`, `!=`, `=~`, `!~` and `in`", c)
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, expressionToken{position: i}.errorf("unterminated string")
+			}
+			value, err := strconv.Unquote(expression[i : end+1])
+			if err != nil {
+				return nil, expressionToken{position: i}.errorf("invalid string %s", expression[i:end+1])
+			}
+			tokens = append(tokens, expressionToken{kind: stringToken, value: value, position: i})
+			i = end + 1
+		case isIdentRune(c):
+			end := i
+			for end < len(expression) && isIdentRune(rune(expression[end])) {
+				end++
+			}
+			tokens = append(tokens, expressionToken{kind: identToken, value: expression[i:end], position: i})
+			i = end
+		default:
+			return nil, expressionToken{position: i}.errorf("unexpected `%c`", c)
+		}
+	}
+
+	return append(tokens, expressionToken{kind: endToken, position: len(expression)}), nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || c == '.' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+type expressionParser struct {
+	tokens []expressionToken
+	next   int
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.next]
+}
+
+func (p *expressionParser) pop() expressionToken {
+	token := p.tokens[p.next]
+	if token.kind != endToken {
+		p.next++
+	}
+	return token
+}
+
+func (p *expressionParser) isKeyword(keyword string) bool {
+	token := p.peek()
+	return token.kind == identToken && token.value == keyword
+}
+
+func (p *expressionParser) isPunctuation(punctuation string) bool {
+	token := p.peek()
+	return token.kind == punctuationToken && token.value == punctuation
+}
+
+func (p *expressionParser) parseOr() (expressionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.pop()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (expressionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.pop()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseNot() (expressionNode, error) {
+	if p.isKeyword("not") {
+		p.pop()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: node}, nil
+	}
+
+	if p.isPunctuation("(") {
+		p.pop()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if token := p.pop(); token.kind != punctuationToken || token.value != ")" {
+			return nil, token.errorf("expected `)`, found %s", token)
+		}
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (expressionNode, error) {
+	fieldToken := p.pop()
+	if fieldToken.kind != identToken {
+		return nil, fieldToken.errorf("expected a field, found %s", fieldToken)
+	}
+	field, ok := expressionField(fieldToken.value)
+	if !ok {
+		return nil, fieldToken.errorf("unknown field %s", fieldToken)
+	}
+
+	opToken := p.pop()
+	switch {
+	case opToken.kind == identToken && opToken.value == "in":
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonNode{field: field, values: values}, nil
+	case opToken.kind != operatorToken:
+		return nil, opToken.errorf("expected an operator, found %s", opToken)
+	}
+
+	valueToken := p.pop()
+	if valueToken.kind != stringToken {
+		return nil, valueToken.errorf("expected a quoted string, found %s", valueToken)
+	}
+
+	node := comparisonNode{field: field, negate: opToken.value[0] == '!'}
+	if opToken.value[1] == '~' {
+		pattern, err := regexp.Compile("^(?:" + valueToken.value + ")$")
+		if err != nil {
+			return nil, valueToken.errorf("invalid regular expression: %v", err)
+		}
+		node.pattern = pattern
+	} else {
+		node.values = []string{valueToken.value}
+	}
+	return node, nil
+}
+
+func (p *expressionParser) parseList() ([]string, error) {
+	if token := p.pop(); token.kind != punctuationToken || token.value != "[" {
+		return nil, token.errorf("expected `[`, found %s", token)
+	}
+
+	values := []string{}
+	for {
+		valueToken := p.pop()
+		if valueToken.kind != stringToken {
+			return nil, valueToken.errorf("expected a quoted string, found %s", valueToken)
+		}
+		values = append(values, valueToken.value)
+
+		token := p.pop()
+		if token.kind == punctuationToken && token.value == "]" {
+			return values, nil
+		}
+		if token.kind != punctuationToken || token.value != "," {
+			return nil, token.errorf("expected `,` or `]`, found %s", token)
+		}
+	}
+}
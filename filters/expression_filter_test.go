@@ -0,0 +1,142 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/filters"
+)
+
+var _ = Describe("ExpressionFilter", func() {
+	var (
+		valueMetric = &events.Envelope{
+			Origin:     proto.String("gorouter"),
+			EventType:  events.Envelope_ValueMetric.Enum(),
+			Deployment: proto.String("cf"),
+			Job:        proto.String("router"),
+			Index:      proto.String("0"),
+			Ip:         proto.String("1.2.3.4"),
+			Tags:       map[string]string{"team": "routing"},
+			ValueMetric: &events.ValueMetric{
+				Name: proto.String("latency.uaa"),
+				Unit: proto.String("ms"),
+			},
+		}
+
+		containerMetric = &events.Envelope{
+			Origin:     proto.String("rep"),
+			EventType:  events.Envelope_ContainerMetric.Enum(),
+			Deployment: proto.String("cf"),
+			Job:        proto.String("diego-cell"),
+			Index:      proto.String("1"),
+			Ip:         proto.String("5.6.7.8"),
+			Tags:       map[string]string{"team": "payments"},
+			ContainerMetric: &events.ContainerMetric{
+				ApplicationId: proto.String("4630f6ba-8ddc-41f1-afea-1905332d6660"),
+			},
+		}
+
+		enabled = func(expression string, envelope *events.Envelope) bool {
+			expressionFilter, err := NewExpressionFilter(expression)
+			Expect(err).ToNot(HaveOccurred())
+			return expressionFilter.Enabled(envelope)
+		}
+	)
+
+	Describe("Enabled", func() {
+		It("compares the envelope fields", func() {
+			Expect(enabled(`origin == "gorouter"`, valueMetric)).To(BeTrue())
+			Expect(enabled(`deployment != "cf"`, valueMetric)).To(BeFalse())
+			Expect(enabled(`job == "router" and index == "0" and ip == "1.2.3.4"`, valueMetric)).To(BeTrue())
+			Expect(enabled(`event_type == "ValueMetric" and name == "latency.uaa" and unit == "ms"`, valueMetric)).To(BeTrue())
+		})
+
+		It("compares the application id", func() {
+			Expect(enabled(`app_id == "4630f6ba-8ddc-41f1-afea-1905332d6660"`, containerMetric)).To(BeTrue())
+			Expect(enabled(`app_id == ""`, valueMetric)).To(BeTrue())
+		})
+
+		It("compares the tags", func() {
+			Expect(enabled(`tags.team == "routing"`, valueMetric)).To(BeTrue())
+			Expect(enabled(`tags.owner == ""`, valueMetric)).To(BeTrue())
+		})
+
+		It("matches values against lists", func() {
+			Expect(enabled(`tags.team in ["payments", "checkout"]`, containerMetric)).To(BeTrue())
+			Expect(enabled(`tags.team in ["payments", "checkout"]`, valueMetric)).To(BeFalse())
+		})
+
+		It("matches regular expressions against the whole field", func() {
+			Expect(enabled(`name =~ "latency.*"`, valueMetric)).To(BeTrue())
+			Expect(enabled(`name =~ "latency"`, valueMetric)).To(BeFalse())
+			Expect(enabled(`job !~ "diego-.*"`, valueMetric)).To(BeTrue())
+		})
+
+		It("gives `not` precedence over `and`, and `and` precedence over `or`", func() {
+			Expect(enabled(`origin == "rep" or origin == "gorouter" and deployment == "cf"`, containerMetric)).To(BeTrue())
+			Expect(enabled(`(origin == "rep" or origin == "gorouter") and deployment == "diego"`, containerMetric)).To(BeFalse())
+			Expect(enabled(`not origin == "rep" and deployment == "cf"`, containerMetric)).To(BeFalse())
+			Expect(enabled(`not (origin == "gorouter" and deployment == "cf")`, containerMetric)).To(BeTrue())
+		})
+
+		It("drops value metrics from an origin unless the deployment is cf", func() {
+			expression := `event_type != "ValueMetric" or origin != "gorouter" or deployment == "cf"`
+			Expect(enabled(expression, valueMetric)).To(BeTrue())
+
+			otherDeployment := proto.Clone(valueMetric).(*events.Envelope)
+			otherDeployment.Deployment = proto.String("cf-routing")
+			Expect(enabled(expression, otherDeployment)).To(BeFalse())
+		})
+
+		It("enables all the envelopes when the filter is nil", func() {
+			var expressionFilter *ExpressionFilter
+			Expect(expressionFilter.Enabled(valueMetric)).To(BeTrue())
+		})
+	})
+
+	Describe("NewExpressionFilter", func() {
+		It("returns an error for an unknown field", func() {
+			_, err := NewExpressionFilter(`origin == "rep" and source == "x"`)
+			Expect(err).To(MatchError("Filter expression error at position 21: unknown field `source`"))
+		})
+
+		It("returns an error for an invalid operator", func() {
+			_, err := NewExpressionFilter(`origin = "rep"`)
+			Expect(err).To(MatchError("Filter expression error at position 8: unexpected `=`, operators are `==`, `!=`, `=~`, `!~` and `in`"))
+		})
+
+		It("returns an error for an unterminated string", func() {
+			_, err := NewExpressionFilter(`origin == "rep`)
+			Expect(err).To(MatchError("Filter expression error at position 11: unterminated string"))
+		})
+
+		It("returns an error for a missing value", func() {
+			_, err := NewExpressionFilter(`origin == rep`)
+			Expect(err).To(MatchError("Filter expression error at position 11: expected a quoted string, found `rep`"))
+		})
+
+		It("returns an error for an unbalanced parenthesis", func() {
+			_, err := NewExpressionFilter(`(origin == "rep"`)
+			Expect(err).To(MatchError("Filter expression error at position 17: expected `)`, found the end of the expression"))
+		})
+
+		It("returns an error for trailing tokens", func() {
+			_, err := NewExpressionFilter(`origin == "rep" deployment == "cf"`)
+			Expect(err).To(MatchError("Filter expression error at position 17: expected `and`, `or` or the end of the expression, found `deployment`"))
+		})
+
+		It("returns an error for an invalid regular expression", func() {
+			_, err := NewExpressionFilter(`name =~ "latency["`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("Filter expression error at position 9: invalid regular expression"))
+		})
+
+		It("returns an error for an invalid list", func() {
+			_, err := NewExpressionFilter(`origin in ["rep" "uaa"]`)
+			Expect(err).To(MatchError("Filter expression error at position 18: expected `,` or `]`, found \"uaa\""))
+		})
+	})
+})
@@ -0,0 +1,41 @@
+package filters
+
+import (
+	"github.com/cloudfoundry/sonde-go/events"
+
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+)
+
+// MetricNameFilter enables the Counter Events and Value Metrics by name, with a pattern filter per event
+// type. The patterns are matched against the raw name, like `latency.uaa`, and against the normalized
+// name prefixed by the normalized origin, like `gorouter_latency_uaa`.
+type MetricNameFilter struct {
+	counterEventFilter *PatternFilter
+	valueMetricFilter  *PatternFilter
+}
+
+func NewMetricNameFilter(counterEventFilter *PatternFilter, valueMetricFilter *PatternFilter) *MetricNameFilter {
+	return &MetricNameFilter{
+		counterEventFilter: counterEventFilter,
+		valueMetricFilter:  valueMetricFilter,
+	}
+}
+
+func (f *MetricNameFilter) Enabled(envelope *events.Envelope) bool {
+	if f == nil {
+		return true
+	}
+
+	switch envelope.GetEventType() {
+	case events.Envelope_CounterEvent:
+		return nameEnabled(f.counterEventFilter, envelope.GetOrigin(), envelope.GetCounterEvent().GetName())
+	case events.Envelope_ValueMetric:
+		return nameEnabled(f.valueMetricFilter, envelope.GetOrigin(), envelope.GetValueMetric().GetName())
+	}
+
+	return true
+}
+
+func nameEnabled(filter *PatternFilter, origin string, name string) bool {
+	return filter.Enabled(name, utils.NormalizeName(origin)+"_"+utils.NormalizeName(name))
+}
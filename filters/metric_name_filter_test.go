@@ -0,0 +1,69 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/filters"
+)
+
+var _ = Describe("MetricNameFilter", func() {
+	var (
+		metricNameFilter *MetricNameFilter
+
+		counterEvent = func(origin string, name string) *events.Envelope {
+			return &events.Envelope{
+				Origin:       proto.String(origin),
+				EventType:    events.Envelope_CounterEvent.Enum(),
+				CounterEvent: &events.CounterEvent{Name: proto.String(name)},
+			}
+		}
+
+		valueMetric = func(origin string, name string) *events.Envelope {
+			return &events.Envelope{
+				Origin:      proto.String(origin),
+				EventType:   events.Envelope_ValueMetric.Enum(),
+				ValueMetric: &events.ValueMetric{Name: proto.String(name)},
+			}
+		}
+	)
+
+	BeforeEach(func() {
+		counterEventFilter, err := NewPatternFilter(nil, []string{"gorouter_*_debug"})
+		Expect(err).ToNot(HaveOccurred())
+		valueMetricFilter, err := NewPatternFilter([]string{"latency*", "memoryStats.*"}, []string{"/.*goroutine.*/"})
+		Expect(err).ToNot(HaveOccurred())
+
+		metricNameFilter = NewMetricNameFilter(counterEventFilter, valueMetricFilter)
+	})
+
+	Describe("Enabled", func() {
+		It("enables the metrics whose raw name is allowed", func() {
+			Expect(metricNameFilter.Enabled(valueMetric("gorouter", "latency.uaa"))).To(BeTrue())
+		})
+
+		It("does not enable the metrics whose name is not allowed", func() {
+			Expect(metricNameFilter.Enabled(valueMetric("gorouter", "total_routes"))).To(BeFalse())
+		})
+
+		It("does not enable the metrics whose name is denied, even when allowed", func() {
+			Expect(metricNameFilter.Enabled(valueMetric("gorouter", "memoryStats.numGoroutines"))).To(BeFalse())
+		})
+
+		It("matches the normalized name prefixed by the origin", func() {
+			Expect(metricNameFilter.Enabled(counterEvent("gorouter", "requests.debug"))).To(BeFalse())
+			Expect(metricNameFilter.Enabled(counterEvent("gorouter", "requests"))).To(BeTrue())
+		})
+
+		It("applies the filter of the event type", func() {
+			Expect(metricNameFilter.Enabled(counterEvent("uaa", "total_routes"))).To(BeTrue())
+		})
+
+		It("enables the other event types", func() {
+			Expect(metricNameFilter.Enabled(&events.Envelope{EventType: events.Envelope_ContainerMetric.Enum()})).To(BeTrue())
+		})
+	})
+})
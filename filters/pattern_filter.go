@@ -0,0 +1,112 @@
+package filters
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PatternFilter enables the values matching one of its include patterns, if any, and none of its
+// exclude patterns. Patterns are globs, or regular expressions when enclosed in slashes like
+// `/service-instance_.*/`, and must match the whole value.
+type PatternFilter struct {
+	include []pattern
+	exclude []pattern
+}
+
+type pattern struct {
+	glob   string
+	regexp *regexp.Regexp
+}
+
+func NewPatternFilter(include []string, exclude []string) (*PatternFilter, error) {
+	includePatterns, err := parsePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+
+	excludePatterns, err := parsePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PatternFilter{include: includePatterns, exclude: excludePatterns}, nil
+}
+
+// Enabled returns whether a value is enabled. When a value has several forms, it is enabled when none
+// of them is excluded and one of them is included.
+func (f *PatternFilter) Enabled(values ...string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, p := range f.exclude {
+		for _, value := range values {
+			if p.matches(value) {
+				return false
+			}
+		}
+	}
+
+	if len(f.include) > 0 {
+		for _, p := range f.include {
+			for _, value := range values {
+				if p.matches(value) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// NewSinglePatternFilter returns a filter enabling the values matching a pattern, every value being
+// enabled when the pattern is empty.
+func NewSinglePatternFilter(value string) (*PatternFilter, error) {
+	include := []string{}
+	if value != "" {
+		include = append(include, value)
+	}
+
+	return NewPatternFilter(include, nil)
+}
+
+func parsePatterns(values []string) ([]pattern, error) {
+	patterns := []pattern{}
+	for _, value := range values {
+		p, err := parsePattern(value)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func parsePattern(value string) (pattern, error) {
+	if len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		re, err := regexp.Compile("^(?:" + value[1:len(value)-1] + ")$")
+		if err != nil {
+			return pattern{}, fmt.Errorf("Filter pattern `%s` is not a valid regular expression: %v", value, err)
+		}
+		return pattern{regexp: re}, nil
+	}
+
+	if _, err := path.Match(value, ""); err != nil {
+		return pattern{}, fmt.Errorf("Filter pattern `%s` is not a valid glob", value)
+	}
+	return pattern{glob: value}, nil
+}
+
+func (p pattern) matches(value string) bool {
+	if p.regexp != nil {
+		return p.regexp.MatchString(value)
+	}
+
+	matched, _ := path.Match(p.glob, value)
+	return matched
+}
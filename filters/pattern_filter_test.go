@@ -0,0 +1,113 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/filters"
+)
+
+var _ = Describe("PatternFilter", func() {
+	var (
+		include       []string
+		exclude       []string
+		patternFilter *PatternFilter
+		err           error
+	)
+
+	BeforeEach(func() {
+		include = []string{"cf", "service-instance_*"}
+		exclude = []string{"/service-instance_[0-9]+/"}
+	})
+
+	JustBeforeEach(func() {
+		patternFilter, err = NewPatternFilter(include, exclude)
+	})
+
+	Describe("Enabled", func() {
+		It("enables the values matching a glob", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patternFilter.Enabled("cf")).To(BeTrue())
+			Expect(patternFilter.Enabled("service-instance_ab12")).To(BeTrue())
+		})
+
+		It("does not enable the values not included", func() {
+			Expect(patternFilter.Enabled("concourse")).To(BeFalse())
+		})
+
+		It("does not enable the excluded values, even when included", func() {
+			Expect(patternFilter.Enabled("service-instance_1234")).To(BeFalse())
+		})
+
+		It("matches the whole value", func() {
+			Expect(patternFilter.Enabled("cf-mysql")).To(BeFalse())
+			Expect(patternFilter.Enabled("service-instance_1234ab")).To(BeTrue())
+		})
+
+		Context("when there are no include patterns", func() {
+			BeforeEach(func() {
+				include = []string{}
+			})
+
+			It("enables the values not excluded", func() {
+				Expect(patternFilter.Enabled("concourse")).To(BeTrue())
+				Expect(patternFilter.Enabled("service-instance_1234")).To(BeFalse())
+			})
+		})
+
+		Context("when the filter is nil", func() {
+			It("returns true", func() {
+				var nilFilter *PatternFilter
+				Expect(nilFilter.Enabled("concourse")).To(BeTrue())
+			})
+		})
+	})
+
+	Context("when a regular expression is invalid", func() {
+		BeforeEach(func() {
+			exclude = []string{"/service-instance_[/"}
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("Filter pattern `/service-instance_[/` is not a valid regular expression"))
+		})
+	})
+
+	Context("when a glob is invalid", func() {
+		BeforeEach(func() {
+			include = []string{"service-instance_["}
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("Filter pattern `service-instance_[` is not a valid glob"))
+		})
+	})
+})
+
+var _ = Describe("NewSinglePatternFilter", func() {
+	It("enables the values matching a glob", func() {
+		patternFilter, err := NewSinglePatternFilter("service-instance_*")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patternFilter.Enabled("service-instance_1234")).To(BeTrue())
+		Expect(patternFilter.Enabled("cf")).To(BeFalse())
+	})
+
+	It("enables the values matching a regular expression", func() {
+		patternFilter, err := NewSinglePatternFilter("/gorouter|uaa/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patternFilter.Enabled("uaa")).To(BeTrue())
+		Expect(patternFilter.Enabled("rep")).To(BeFalse())
+	})
+
+	It("enables every value when the pattern is empty", func() {
+		patternFilter, err := NewSinglePatternFilter("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patternFilter.Enabled("cf")).To(BeTrue())
+	})
+
+	It("returns an error when the pattern is invalid", func() {
+		_, err := NewSinglePatternFilter("[")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,26 +1,42 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/version"
 
+	"github.com/cloudfoundry-community/firehose_exporter/capture"
 	"github.com/cloudfoundry-community/firehose_exporter/collectors"
+	"github.com/cloudfoundry-community/firehose_exporter/config"
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
 	"github.com/cloudfoundry-community/firehose_exporter/firehosenozzle"
+	"github.com/cloudfoundry-community/firehose_exporter/foundations"
+	"github.com/cloudfoundry-community/firehose_exporter/httpclient"
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/rungroup"
 	"github.com/cloudfoundry-community/firehose_exporter/uaatokenrefresher"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 )
 
 var (
+	configFile = flag.String(
+		"config.file", "",
+		"YAML file setting any of these flags, reloaded on SIGHUP or a POST to /-/reload ($FIREHOSE_EXPORTER_CONFIG_FILE).",
+	)
+
 	uaaUrl = flag.String(
 		"uaa.url", "",
 		"Cloud Foundry UAA URL ($FIREHOSE_EXPORTER_UAA_URL).",
@@ -38,7 +54,17 @@ var (
 
 	dopplerUrl = flag.String(
 		"doppler.url", "",
-		"Cloud Foundry Doppler URL ($FIREHOSE_EXPORTER_DOPPLER_URL).",
+		"Comma separated Cloud Foundry Doppler URLs, or RLP Gateway URLs when using the v2 API, in order of preference ($FIREHOSE_EXPORTER_DOPPLER_URL).",
+	)
+
+	dopplerFailbackInterval = flag.Duration(
+		"doppler.failback-interval", 5*time.Minute,
+		"How often to check whether the preferred Cloud Foundry Doppler URL is back when consuming from another one ($FIREHOSE_EXPORTER_DOPPLER_FAILBACK_INTERVAL).",
+	)
+
+	dopplerAPIVersion = flag.String(
+		"doppler.api-version", "v1",
+		"Cloud Foundry Doppler API version, v1 for the Firehose websocket or v2 for the RLP Gateway ($FIREHOSE_EXPORTER_DOPPLER_API_VERSION).",
 	)
 
 	dopplerSubscriptionID = flag.String(
@@ -51,6 +77,21 @@ var (
 		"Cloud Foundry Doppler Idle Timeout in seconds ($FIREHOSE_EXPORTER_DOPPLER_IDLE_TIMEOUT_SECONDS).",
 	)
 
+	dopplerReconnectMinBackoff = flag.Duration(
+		"doppler.reconnect-min-backoff", 1*time.Second,
+		"Cloud Foundry Doppler minimum backoff between reconnection attempts ($FIREHOSE_EXPORTER_DOPPLER_RECONNECT_MIN_BACKOFF).",
+	)
+
+	dopplerReconnectMaxBackoff = flag.Duration(
+		"doppler.reconnect-max-backoff", 1*time.Minute,
+		"Cloud Foundry Doppler maximum backoff between reconnection attempts ($FIREHOSE_EXPORTER_DOPPLER_RECONNECT_MAX_BACKOFF).",
+	)
+
+	dopplerReconnectMaxRetries = flag.Uint(
+		"doppler.reconnect-max-retries", 0,
+		"Cloud Foundry Doppler maximum consecutive reconnection attempts before exiting, 0 means unlimited ($FIREHOSE_EXPORTER_DOPPLER_RECONNECT_MAX_RETRIES).",
+	)
+
 	dopplerMetricExpiration = flag.Duration(
 		"doppler.metric-expiration", 5*time.Minute,
 		"How long a Cloud Foundry Doppler metric is valid ($FIREHOSE_EXPORTER_DOPPLER_METRIC_EXPIRATION).",
@@ -63,7 +104,67 @@ var (
 
 	dopplerEvents = flag.String(
 		"doppler.events", "",
-		"Comma separated events to filter (ContainerMetric,CounterEvent,ValueMetric) ($FIREHOSE_EXPORTER_DOPPLER_EVENTS).",
+		"Comma separated events to filter (ContainerMetric,CounterEvent,ValueMetric,HttpStartStop,LogMessage,Error) ($FIREHOSE_EXPORTER_DOPPLER_EVENTS).",
+	)
+
+	filterIncludeDeployments = flag.String(
+		"filter.include-deployments", "",
+		"Comma separated glob or /regexp/ patterns of the deployments to process, all when empty ($FIREHOSE_EXPORTER_FILTER_INCLUDE_DEPLOYMENTS).",
+	)
+
+	filterExcludeDeployments = flag.String(
+		"filter.exclude-deployments", "",
+		"Comma separated glob or /regexp/ patterns of the deployments to skip, taking precedence over the included ones ($FIREHOSE_EXPORTER_FILTER_EXCLUDE_DEPLOYMENTS).",
+	)
+
+	filterIncludeJobs = flag.String(
+		"filter.include-jobs", "",
+		"Comma separated glob or /regexp/ patterns of the BOSH jobs to process, all when empty ($FIREHOSE_EXPORTER_FILTER_INCLUDE_JOBS).",
+	)
+
+	filterExcludeJobs = flag.String(
+		"filter.exclude-jobs", "",
+		"Comma separated glob or /regexp/ patterns of the BOSH jobs to skip, taking precedence over the included ones ($FIREHOSE_EXPORTER_FILTER_EXCLUDE_JOBS).",
+	)
+
+	filterIncludeOrigins = flag.String(
+		"filter.include-origins", "",
+		"Comma separated glob or /regexp/ patterns of the envelope origins to process, all when empty ($FIREHOSE_EXPORTER_FILTER_INCLUDE_ORIGINS).",
+	)
+
+	filterExcludeOrigins = flag.String(
+		"filter.exclude-origins", "",
+		"Comma separated glob or /regexp/ patterns of the envelope origins to skip, taking precedence over the included ones ($FIREHOSE_EXPORTER_FILTER_EXCLUDE_ORIGINS).",
+	)
+
+	filterAllowCounterEvents = flag.String(
+		"filter.allow-counter-events", "",
+		"Comma separated glob or /regexp/ patterns of the names of the Counter Events to store, all when empty ($FIREHOSE_EXPORTER_FILTER_ALLOW_COUNTER_EVENTS).",
+	)
+
+	filterDenyCounterEvents = flag.String(
+		"filter.deny-counter-events", "",
+		"Comma separated glob or /regexp/ patterns of the names of the Counter Events to drop, taking precedence over the allowed ones ($FIREHOSE_EXPORTER_FILTER_DENY_COUNTER_EVENTS).",
+	)
+
+	filterAllowValueMetrics = flag.String(
+		"filter.allow-value-metrics", "",
+		"Comma separated glob or /regexp/ patterns of the names of the Value Metrics to store, all when empty ($FIREHOSE_EXPORTER_FILTER_ALLOW_VALUE_METRICS).",
+	)
+
+	filterDenyValueMetrics = flag.String(
+		"filter.deny-value-metrics", "",
+		"Comma separated glob or /regexp/ patterns of the names of the Value Metrics to drop, taking precedence over the allowed ones ($FIREHOSE_EXPORTER_FILTER_DENY_VALUE_METRICS).",
+	)
+
+	filterExpression = flag.String(
+		"filter.expression", "",
+		"Expression over the envelope fields selecting the envelopes to process, e.g. event_type != \"ValueMetric\" or deployment == \"cf\" ($FIREHOSE_EXPORTER_FILTER_EXPRESSION).",
+	)
+
+	foundationsFile = flag.String(
+		"foundations.file", "",
+		"YAML file listing the Cloud Foundry foundations to consume, instead of the uaa.* and doppler.* connection flags ($FIREHOSE_EXPORTER_FOUNDATIONS_FILE).",
 	)
 
 	skipSSLValidation = flag.Bool(
@@ -71,16 +172,146 @@ var (
 		"Disable SSL Verify ($FIREHOSE_EXPORTER_SKIP_SSL_VERIFY).",
 	)
 
+	proxyURL = flag.String(
+		"proxy.url", "",
+		"Proxy URL used to connect to Cloud Foundry UAA and Doppler, defaults to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables ($FIREHOSE_EXPORTER_PROXY_URL).",
+	)
+
+	tlsCACertFile = flag.String(
+		"tls.ca-cert-file", "",
+		"PEM file with the CA certificates to trust, on top of the system ones, when connecting to Cloud Foundry UAA and Doppler ($FIREHOSE_EXPORTER_TLS_CA_CERT_FILE).",
+	)
+
+	tlsClientCertFile = flag.String(
+		"tls.client-cert-file", "",
+		"PEM client certificate presented to Cloud Foundry UAA and Doppler ($FIREHOSE_EXPORTER_TLS_CLIENT_CERT_FILE).",
+	)
+
+	tlsClientKeyFile = flag.String(
+		"tls.client-key-file", "",
+		"PEM client key of the client certificate ($FIREHOSE_EXPORTER_TLS_CLIENT_KEY_FILE).",
+	)
+
 	metricsNamespace = flag.String(
 		"metrics.namespace", "firehose_exporter",
 		"Metrics Namespace ($FIREHOSE_EXPORTER_METRICS_NAMESPACE).",
 	)
 
+	metricsHttpRouteTemplates = flag.String(
+		"metrics.http-route-templates", "",
+		"Comma separated URI path templates used to label gorouter route metrics, e.g. /v2/apps/{guid}/**, paths matching no template are labelled `unmatched` ($FIREHOSE_EXPORTER_METRICS_HTTP_ROUTE_TEMPLATES).",
+	)
+
 	metricsCleanupInterval = flag.Duration(
 		"metrics.cleanup-interval", 2*time.Minute,
 		"Metrics clean up interval ($FIREHOSE_EXPORTER_METRICS_CLEANUP_INTERVAL).",
 	)
 
+	metricsExpirationPoliciesFile = flag.String(
+		"metrics.expiration-policies-file", "",
+		"YAML file with the metrics expiration per event type, origin, deployment and metric name ($FIREHOSE_EXPORTER_METRICS_EXPIRATION_POLICIES_FILE).",
+	)
+
+	metricsValueMetricAggregations = flag.String(
+		"metrics.value-metric-aggregations", "",
+		"Comma separated glob patterns matching the origin/name of the Value Metrics to aggregate over a sliding window, e.g. gorouter/latency* ($FIREHOSE_EXPORTER_METRICS_VALUE_METRIC_AGGREGATIONS).",
+	)
+
+	metricsValueMetricAggregationWindow = flag.Duration(
+		"metrics.value-metric-aggregation-window", 1*time.Minute,
+		"Sliding window over which the Value Metrics are aggregated ($FIREHOSE_EXPORTER_METRICS_VALUE_METRIC_AGGREGATION_WINDOW).",
+	)
+
+	metricsAggregationRulesFile = flag.String(
+		"metrics.aggregation-rules-file", "",
+		"YAML file with the rules aggregating Container Metrics and Value Metrics across instances ($FIREHOSE_EXPORTER_METRICS_AGGREGATION_RULES_FILE).",
+	)
+
+	metricsMaxSeries = flag.Uint(
+		"metrics.max-series", 0,
+		"Maximum number of Counter Event and Value Metric series, 0 means unlimited ($FIREHOSE_EXPORTER_METRICS_MAX_SERIES).",
+	)
+
+	metricsMaxSeriesPerOrigin = flag.Uint(
+		"metrics.max-series-per-origin", 0,
+		"Maximum number of Counter Event and Value Metric series per origin, 0 means unlimited ($FIREHOSE_EXPORTER_METRICS_MAX_SERIES_PER_ORIGIN).",
+	)
+
+	metricsMaxSeriesPerMetric = flag.Uint(
+		"metrics.max-series-per-metric", 0,
+		"Maximum number of Counter Event and Value Metric series per origin and metric name, 0 means unlimited ($FIREHOSE_EXPORTER_METRICS_MAX_SERIES_PER_METRIC).",
+	)
+
+	metricsSeriesLimitAction = flag.String(
+		"metrics.series-limit-action", "evict",
+		"What to do with a new series when a series limit is reached: evict the least recently updated series or reject the new one ($FIREHOSE_EXPORTER_METRICS_SERIES_LIMIT_ACTION).",
+	)
+
+	metricsEnvelopeTimestamps = flag.Bool(
+		"metrics.envelope-timestamps", false,
+		"Expose the envelope timestamps of Container Metrics, Counter Events and Value Metrics as sample timestamps ($FIREHOSE_EXPORTER_METRICS_ENVELOPE_TIMESTAMPS).",
+	)
+
+	metricsEnvelopeTimestampsMaxAge = flag.Duration(
+		"metrics.envelope-timestamps-max-age", 5*time.Minute,
+		"Maximum age of an envelope timestamp before its sample is no longer exposed, 0 means unlimited ($FIREHOSE_EXPORTER_METRICS_ENVELOPE_TIMESTAMPS_MAX_AGE).",
+	)
+
+	metricsSnapshotFile = flag.String(
+		"metrics.snapshot-file", "",
+		"File where to save a snapshot of the metrics, restored at startup ($FIREHOSE_EXPORTER_METRICS_SNAPSHOT_FILE).",
+	)
+
+	metricsSnapshotInterval = flag.Duration(
+		"metrics.snapshot-interval", 1*time.Minute,
+		"Metrics snapshot interval, a snapshot is also saved on shutdown ($FIREHOSE_EXPORTER_METRICS_SNAPSHOT_INTERVAL).",
+	)
+
+	pipelineWorkers = flag.Uint(
+		"pipeline.workers", 4,
+		"Number of workers processing the received envelopes, 0 processes them on the connection read loop ($FIREHOSE_EXPORTER_PIPELINE_WORKERS).",
+	)
+
+	pipelineQueueSize = flag.Uint(
+		"pipeline.queue-size", 10000,
+		"Maximum number of received envelopes waiting to be processed, receiving waits while the queue is full ($FIREHOSE_EXPORTER_PIPELINE_QUEUE_SIZE).",
+	)
+
+	pipelineDropWhenFull = flag.Bool(
+		"pipeline.drop-when-full", false,
+		"Drop the received envelopes while the queue is full instead of waiting, dropped envelopes are accounted in total_envelopes_dropped ($FIREHOSE_EXPORTER_PIPELINE_DROP_WHEN_FULL).",
+	)
+
+	captureFile = flag.String(
+		"capture.file", "",
+		"File where to capture the received envelopes ($FIREHOSE_EXPORTER_CAPTURE_FILE).",
+	)
+
+	captureGzip = flag.Bool(
+		"capture.gzip", false,
+		"Compress the capture file with gzip ($FIREHOSE_EXPORTER_CAPTURE_GZIP).",
+	)
+
+	captureMaxFileSizeMB = flag.Uint(
+		"capture.max-file-size-mb", 0,
+		"Maximum capture file size in megabytes, before compression, before rotating to a new file, 0 means unlimited ($FIREHOSE_EXPORTER_CAPTURE_MAX_FILE_SIZE_MB).",
+	)
+
+	replayFile = flag.String(
+		"replay.file", "",
+		"Capture file to replay instead of connecting to Cloud Foundry Doppler ($FIREHOSE_EXPORTER_REPLAY_FILE).",
+	)
+
+	replaySpeed = flag.Float64(
+		"replay.speed", 1,
+		"Replay speed factor, 0 replays as fast as possible ($FIREHOSE_EXPORTER_REPLAY_SPEED).",
+	)
+
+	shutdownTimeout = flag.Duration(
+		"shutdown.timeout", 10*time.Second,
+		"Maximum time to wait for a graceful shutdown before exiting ($FIREHOSE_EXPORTER_SHUTDOWN_TIMEOUT).",
+	)
+
 	showVersion = flag.Bool(
 		"version", false,
 		"Print version information.",
@@ -102,18 +333,62 @@ func init() {
 }
 
 func overrideFlagsWithEnvVars() {
+	overrideWithEnvVar("FIREHOSE_EXPORTER_CONFIG_FILE", configFile)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_UAA_URL", uaaUrl)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_UAA_CLIENT_ID", uaaClientID)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_UAA_CLIENT_SECRET", uaaClientSecret)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_DOPPLER_URL", dopplerUrl)
+	overrideWithEnvDuration("FIREHOSE_EXPORTER_DOPPLER_FAILBACK_INTERVAL", dopplerFailbackInterval)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_DOPPLER_API_VERSION", dopplerAPIVersion)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_DOPPLER_SUBSCRIPTION_ID", dopplerSubscriptionID)
 	overrideWithEnvUint("FIREHOSE_EXPORTER_DOPPLER_IDLE_TIMEOUT_SECONDS", dopplerIdleTimeoutSeconds)
+	overrideWithEnvDuration("FIREHOSE_EXPORTER_DOPPLER_RECONNECT_MIN_BACKOFF", dopplerReconnectMinBackoff)
+	overrideWithEnvDuration("FIREHOSE_EXPORTER_DOPPLER_RECONNECT_MAX_BACKOFF", dopplerReconnectMaxBackoff)
+	overrideWithEnvUint("FIREHOSE_EXPORTER_DOPPLER_RECONNECT_MAX_RETRIES", dopplerReconnectMaxRetries)
 	overrideWithEnvDuration("FIREHOSE_EXPORTER_DOPPLER_METRIC_EXPIRATION", dopplerMetricExpiration)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_DOPPLER_DEPLOYMENTS", dopplerDeployments)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_DOPPLER_EVENTS", dopplerEvents)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_INCLUDE_DEPLOYMENTS", filterIncludeDeployments)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_EXCLUDE_DEPLOYMENTS", filterExcludeDeployments)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_INCLUDE_JOBS", filterIncludeJobs)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_EXCLUDE_JOBS", filterExcludeJobs)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_INCLUDE_ORIGINS", filterIncludeOrigins)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_EXCLUDE_ORIGINS", filterExcludeOrigins)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_ALLOW_COUNTER_EVENTS", filterAllowCounterEvents)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_DENY_COUNTER_EVENTS", filterDenyCounterEvents)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_ALLOW_VALUE_METRICS", filterAllowValueMetrics)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_DENY_VALUE_METRICS", filterDenyValueMetrics)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FILTER_EXPRESSION", filterExpression)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_FOUNDATIONS_FILE", foundationsFile)
 	overrideWithEnvBool("FIREHOSE_EXPORTER_SKIP_SSL_VERIFY", skipSSLValidation)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_PROXY_URL", proxyURL)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_TLS_CA_CERT_FILE", tlsCACertFile)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_TLS_CLIENT_CERT_FILE", tlsClientCertFile)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_TLS_CLIENT_KEY_FILE", tlsClientKeyFile)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_METRICS_NAMESPACE", metricsNamespace)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_METRICS_HTTP_ROUTE_TEMPLATES", metricsHttpRouteTemplates)
 	overrideWithEnvDuration("FIREHOSE_EXPORTER_METRICS_CLEANUP_INTERVAL", metricsCleanupInterval)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_METRICS_EXPIRATION_POLICIES_FILE", metricsExpirationPoliciesFile)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_METRICS_VALUE_METRIC_AGGREGATIONS", metricsValueMetricAggregations)
+	overrideWithEnvDuration("FIREHOSE_EXPORTER_METRICS_VALUE_METRIC_AGGREGATION_WINDOW", metricsValueMetricAggregationWindow)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_METRICS_AGGREGATION_RULES_FILE", metricsAggregationRulesFile)
+	overrideWithEnvUint("FIREHOSE_EXPORTER_METRICS_MAX_SERIES", metricsMaxSeries)
+	overrideWithEnvUint("FIREHOSE_EXPORTER_METRICS_MAX_SERIES_PER_ORIGIN", metricsMaxSeriesPerOrigin)
+	overrideWithEnvUint("FIREHOSE_EXPORTER_METRICS_MAX_SERIES_PER_METRIC", metricsMaxSeriesPerMetric)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_METRICS_SERIES_LIMIT_ACTION", metricsSeriesLimitAction)
+	overrideWithEnvBool("FIREHOSE_EXPORTER_METRICS_ENVELOPE_TIMESTAMPS", metricsEnvelopeTimestamps)
+	overrideWithEnvDuration("FIREHOSE_EXPORTER_METRICS_ENVELOPE_TIMESTAMPS_MAX_AGE", metricsEnvelopeTimestampsMaxAge)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_METRICS_SNAPSHOT_FILE", metricsSnapshotFile)
+	overrideWithEnvDuration("FIREHOSE_EXPORTER_METRICS_SNAPSHOT_INTERVAL", metricsSnapshotInterval)
+	overrideWithEnvUint("FIREHOSE_EXPORTER_PIPELINE_WORKERS", pipelineWorkers)
+	overrideWithEnvUint("FIREHOSE_EXPORTER_PIPELINE_QUEUE_SIZE", pipelineQueueSize)
+	overrideWithEnvBool("FIREHOSE_EXPORTER_PIPELINE_DROP_WHEN_FULL", pipelineDropWhenFull)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_CAPTURE_FILE", captureFile)
+	overrideWithEnvBool("FIREHOSE_EXPORTER_CAPTURE_GZIP", captureGzip)
+	overrideWithEnvUint("FIREHOSE_EXPORTER_CAPTURE_MAX_FILE_SIZE_MB", captureMaxFileSizeMB)
+	overrideWithEnvVar("FIREHOSE_EXPORTER_REPLAY_FILE", replayFile)
+	overrideWithEnvFloat64("FIREHOSE_EXPORTER_REPLAY_SPEED", replaySpeed)
+	overrideWithEnvDuration("FIREHOSE_EXPORTER_SHUTDOWN_TIMEOUT", shutdownTimeout)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_WEB_LISTEN_ADDRESS", listenAddress)
 	overrideWithEnvVar("FIREHOSE_EXPORTER_WEB_TELEMETRY_PATH", metricsPath)
 }
@@ -147,6 +422,17 @@ func overrideWithEnvDuration(name string, value *time.Duration) {
 	}
 }
 
+func overrideWithEnvFloat64(name string, value *float64) {
+	envValue := os.Getenv(name)
+	if envValue != "" {
+		var err error
+		*value, err = strconv.ParseFloat(envValue, 64)
+		if err != nil {
+			log.Fatalf("Invalid `%s`: %s", name, err)
+		}
+	}
+}
+
 func overrideWithEnvBool(name string, value *bool) {
 	envValue := os.Getenv(name)
 	if envValue != "" {
@@ -158,6 +444,394 @@ func overrideWithEnvBool(name string, value *bool) {
 	}
 }
 
+// configIgnoredFlags cannot be set by the configuration file.
+var configIgnoredFlags = []string{"config.file", "version", "log.level", "log.format"}
+
+// reloadableFlags are applied again when reloading the configuration file, the other flags are only
+// applied on startup.
+var reloadableFlags = []string{
+	"doppler.deployments",
+	"doppler.events",
+	"filter.include-deployments",
+	"filter.exclude-deployments",
+	"filter.include-jobs",
+	"filter.exclude-jobs",
+	"filter.include-origins",
+	"filter.exclude-origins",
+	"filter.allow-counter-events",
+	"filter.deny-counter-events",
+	"filter.allow-value-metrics",
+	"filter.deny-value-metrics",
+	"filter.expression",
+	"metrics.expiration-policies-file",
+	"metrics.value-metric-aggregations",
+	"metrics.value-metric-aggregation-window",
+	"metrics.aggregation-rules-file",
+	"metrics.envelope-timestamps",
+	"metrics.envelope-timestamps-max-age",
+}
+
+func newEnvelopeWriter() (*capture.EnvelopeWriter, error) {
+	if *captureFile == "" || *replayFile != "" {
+		return nil, nil
+	}
+
+	envelopeWriter, err := capture.NewEnvelopeWriter(*captureFile, *captureGzip, int64(*captureMaxFileSizeMB)*1024*1024)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating capture file: %s", err.Error())
+	}
+
+	return envelopeWriter, nil
+}
+
+func newFoundations() ([]foundations.Foundation, error) {
+	defaults := foundations.Foundation{
+		UAAURL:                *uaaUrl,
+		UAAClientID:           *uaaClientID,
+		UAAClientSecret:       *uaaClientSecret,
+		DopplerURL:            *dopplerUrl,
+		DopplerAPIVersion:     *dopplerAPIVersion,
+		DopplerSubscriptionID: *dopplerSubscriptionID,
+		ProxyURL:              *proxyURL,
+		CACertFile:            *tlsCACertFile,
+		ClientCertFile:        *tlsClientCertFile,
+		ClientKeyFile:         *tlsClientKeyFile,
+		SkipSSLValidation:     *skipSSLValidation,
+	}
+	if *dopplerDeployments != "" {
+		defaults.DopplerDeployments = strings.Split(*dopplerDeployments, ",")
+	}
+	if *dopplerEvents != "" {
+		defaults.DopplerEvents = strings.Split(*dopplerEvents, ",")
+	}
+
+	if *foundationsFile == "" {
+		return []foundations.Foundation{defaults}, nil
+	}
+
+	if *captureFile != "" || *replayFile != "" {
+		return nil, errors.New("Capture and replay are not supported with a foundations file")
+	}
+
+	return foundations.Load(*foundationsFile, defaults)
+}
+
+func newNozzle(foundation foundations.Foundation, metricsStore *metrics.Store, envelopeWriter *capture.EnvelopeWriter) (firehosenozzle.Nozzle, error) {
+	if *replayFile != "" {
+		return firehosenozzle.NewReplayNozzle(*replayFile, *replaySpeed, metricsStore), nil
+	}
+
+	httpClientConfig := httpclient.Config{
+		ProxyURL:          foundation.ProxyURL,
+		CACertFile:        foundation.CACertFile,
+		ClientCertFile:    foundation.ClientCertFile,
+		ClientKeyFile:     foundation.ClientKeyFile,
+		SkipSSLValidation: foundation.SkipSSLValidation,
+	}
+	tlsConfig, err := httpClientConfig.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading TLS settings: %s", err.Error())
+	}
+	proxy, err := httpClientConfig.Proxy()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading proxy settings: %s", err.Error())
+	}
+
+	authTokenRefresher, err := uaatokenrefresher.New(
+		foundation.UAAURL,
+		foundation.UAAClientID,
+		foundation.UAAClientSecret,
+		tlsConfig,
+		proxy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating UAA client: %s", err.Error())
+	}
+
+	switch foundation.DopplerAPIVersion {
+	case "v1":
+		nozzle := firehosenozzle.New(
+			strings.Split(foundation.DopplerURL, ","),
+			*dopplerFailbackInterval,
+			tlsConfig,
+			proxy,
+			foundation.DopplerSubscriptionID,
+			uint32(*dopplerIdleTimeoutSeconds),
+			*dopplerReconnectMinBackoff,
+			*dopplerReconnectMaxBackoff,
+			*dopplerReconnectMaxRetries,
+			authTokenRefresher,
+			metricsStore,
+		)
+		nozzle.SetEnvelopeWriter(envelopeWriter)
+		return nozzle, nil
+	case "v2":
+		nozzle := firehosenozzle.NewRLPGatewayNozzle(
+			strings.Split(foundation.DopplerURL, ","),
+			*dopplerFailbackInterval,
+			tlsConfig,
+			proxy,
+			foundation.DopplerSubscriptionID,
+			uint32(*dopplerIdleTimeoutSeconds),
+			*dopplerReconnectMinBackoff,
+			*dopplerReconnectMaxBackoff,
+			*dopplerReconnectMaxRetries,
+			authTokenRefresher,
+			metricsStore,
+		)
+		nozzle.SetEnvelopeWriter(envelopeWriter)
+		return nozzle, nil
+	}
+
+	return nil, fmt.Errorf("Doppler API version `%s` is not supported, must be `v1` or `v2`", foundation.DopplerAPIVersion)
+}
+
+func newEnvelopeFilter() (*filters.EnvelopeFilter, error) {
+	deploymentFilter, err := filters.NewPatternFilter(splitPatterns(*filterIncludeDeployments), splitPatterns(*filterExcludeDeployments))
+	if err != nil {
+		return nil, err
+	}
+
+	jobFilter, err := filters.NewPatternFilter(splitPatterns(*filterIncludeJobs), splitPatterns(*filterExcludeJobs))
+	if err != nil {
+		return nil, err
+	}
+
+	originFilter, err := filters.NewPatternFilter(splitPatterns(*filterIncludeOrigins), splitPatterns(*filterExcludeOrigins))
+	if err != nil {
+		return nil, err
+	}
+
+	return filters.NewEnvelopeFilter(deploymentFilter, jobFilter, originFilter), nil
+}
+
+func newMetricNameFilter() (*filters.MetricNameFilter, error) {
+	counterEventFilter, err := filters.NewPatternFilter(splitPatterns(*filterAllowCounterEvents), splitPatterns(*filterDenyCounterEvents))
+	if err != nil {
+		return nil, err
+	}
+
+	valueMetricFilter, err := filters.NewPatternFilter(splitPatterns(*filterAllowValueMetrics), splitPatterns(*filterDenyValueMetrics))
+	if err != nil {
+		return nil, err
+	}
+
+	return filters.NewMetricNameFilter(counterEventFilter, valueMetricFilter), nil
+}
+
+func splitPatterns(patterns string) []string {
+	if patterns == "" {
+		return nil
+	}
+	return strings.Split(patterns, ",")
+}
+
+func newSeriesLimits() (metrics.SeriesLimits, error) {
+	limits := metrics.SeriesLimits{
+		MaxSeries:          int(*metricsMaxSeries),
+		MaxSeriesPerOrigin: int(*metricsMaxSeriesPerOrigin),
+		MaxSeriesPerMetric: int(*metricsMaxSeriesPerMetric),
+	}
+
+	switch *metricsSeriesLimitAction {
+	case "evict":
+	case "reject":
+		limits.Reject = true
+	default:
+		return limits, fmt.Errorf("Series limit action `%s` is not supported, must be `evict` or `reject`", *metricsSeriesLimitAction)
+	}
+
+	return limits, nil
+}
+
+func snapshotFile(foundation string) string {
+	if *metricsSnapshotFile == "" || foundation == "" {
+		return *metricsSnapshotFile
+	}
+	return *metricsSnapshotFile + "." + foundation
+}
+
+// reloadableSettings are built from the reloadableFlags.
+type reloadableSettings struct {
+	foundationFilters  map[string]foundationFilters
+	envelopeFilter     *filters.EnvelopeFilter
+	metricNameFilter   *filters.MetricNameFilter
+	expressionFilter   *filters.ExpressionFilter
+	expirationPolicies *metrics.ExpirationPolicies
+	valueAggregations  *metrics.ValueMetricAggregations
+	aggregationRules   *collectors.AggregationRules
+	sampleTimestamps   collectors.SampleTimestamps
+}
+
+// foundationFilters are the deployment and event filters of a foundation.
+type foundationFilters struct {
+	deploymentFilter *filters.DeploymentFilter
+	eventFilter      *filters.EventFilter
+}
+
+func newReloadableSettings(foundationsList []foundations.Foundation) (reloadableSettings, error) {
+	settings := reloadableSettings{
+		foundationFilters:  map[string]foundationFilters{},
+		expirationPolicies: metrics.NewExpirationPolicies(*dopplerMetricExpiration),
+		sampleTimestamps: collectors.SampleTimestamps{
+			Enabled: *metricsEnvelopeTimestamps,
+			MaxAge:  *metricsEnvelopeTimestampsMaxAge,
+		},
+	}
+
+	for _, foundation := range foundationsList {
+		eventFilter, err := filters.NewEventFilter(foundation.DopplerEvents)
+		if err != nil {
+			return settings, err
+		}
+		settings.foundationFilters[foundation.Name] = foundationFilters{
+			deploymentFilter: filters.NewDeploymentFilter(foundation.DopplerDeployments),
+			eventFilter:      eventFilter,
+		}
+	}
+
+	var err error
+	if *metricsExpirationPoliciesFile != "" {
+		settings.expirationPolicies, err = metrics.LoadExpirationPolicies(*metricsExpirationPoliciesFile, *dopplerMetricExpiration)
+		if err != nil {
+			return settings, fmt.Errorf("Error loading expiration policies: %v", err)
+		}
+	}
+
+	if *metricsValueMetricAggregations != "" {
+		settings.valueAggregations, err = metrics.NewValueMetricAggregations(strings.Split(*metricsValueMetricAggregations, ","), *metricsValueMetricAggregationWindow)
+		if err != nil {
+			return settings, err
+		}
+	}
+
+	if *metricsAggregationRulesFile != "" {
+		settings.aggregationRules, err = collectors.LoadAggregationRules(*metricsAggregationRulesFile)
+		if err != nil {
+			return settings, fmt.Errorf("Error loading aggregation rules: %v", err)
+		}
+	}
+
+	settings.envelopeFilter, err = newEnvelopeFilter()
+	if err != nil {
+		return settings, err
+	}
+
+	settings.metricNameFilter, err = newMetricNameFilter()
+	if err != nil {
+		return settings, err
+	}
+
+	if *filterExpression != "" {
+		settings.expressionFilter, err = filters.NewExpressionFilter(*filterExpression)
+		if err != nil {
+			return settings, err
+		}
+	}
+
+	return settings, nil
+}
+
+func (s reloadableSettings) apply(foundation string, metricsStore *metrics.Store) {
+	if foundationFilters, ok := s.foundationFilters[foundation]; ok {
+		metricsStore.SetDeploymentFilter(foundationFilters.deploymentFilter)
+		metricsStore.SetEventFilter(foundationFilters.eventFilter)
+	}
+	metricsStore.SetEnvelopeFilter(s.envelopeFilter)
+	metricsStore.SetMetricNameFilter(s.metricNameFilter)
+	metricsStore.SetExpressionFilter(s.expressionFilter)
+	metricsStore.SetExpirationPolicies(s.expirationPolicies)
+	metricsStore.SetValueMetricAggregations(s.valueAggregations)
+}
+
+func newCollectors(foundation string, settings reloadableSettings, metricsStore *metrics.Store) []prometheus.Collector {
+	foundationCollectors := []prometheus.Collector{
+		collectors.NewInternalMetricsCollector(*metricsNamespace, foundation, metricsStore),
+		collectors.NewContainerMetricsCollector(*metricsNamespace, foundation, settings.sampleTimestamps, settings.aggregationRules, metricsStore),
+		collectors.NewCounterEventsCollector(*metricsNamespace, foundation, settings.sampleTimestamps, metricsStore),
+		collectors.NewErrorsCollector(*metricsNamespace, foundation, metricsStore),
+		collectors.NewValueMetricsCollector(*metricsNamespace, foundation, settings.sampleTimestamps, settings.aggregationRules, metricsStore),
+		collectors.NewHttpStartStopCollector(*metricsNamespace, foundation, metricsStore),
+		collectors.NewHttpRoutesCollector(*metricsNamespace, foundation, metricsStore),
+		collectors.NewLogMessagesCollector(*metricsNamespace, foundation, metricsStore),
+	}
+	if settings.aggregationRules != nil {
+		foundationCollectors = append(foundationCollectors, collectors.NewAggregationRulesCollector(*metricsNamespace, foundation, settings.aggregationRules, metricsStore))
+	}
+	return foundationCollectors
+}
+
+// reloader applies the reloadable settings of the configuration file to the consumed foundations.
+type reloader struct {
+	lock          sync.Mutex
+	flags         *config.Flags
+	foundations   []string
+	metricsStores []*metrics.Store
+	collectors    []prometheus.Collector
+}
+
+func (r *reloader) add(foundation string, metricsStore *metrics.Store, foundationCollectors []prometheus.Collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.foundations = append(r.foundations, foundation)
+	r.metricsStores = append(r.metricsStores, metricsStore)
+	r.collectors = append(r.collectors, foundationCollectors...)
+}
+
+// reload keeps the previous configuration when the configuration file, any of its reloadable settings,
+// or the collectors they configure are invalid.
+func (r *reloader) reload() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if *configFile == "" {
+		return errors.New("No configuration file to reload")
+	}
+
+	configSettings, err := r.flags.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("Error loading configuration file: %v", err)
+	}
+
+	unchanged, err := r.flags.Reload(configSettings, r.apply)
+	if err != nil {
+		return err
+	}
+	for _, name := range unchanged {
+		log.Warnf("Setting `%s` changed, restart firehose_exporter to apply it", name)
+	}
+
+	log.Infof("Reloaded configuration file `%s`", *configFile)
+	return nil
+}
+
+func (r *reloader) apply() error {
+	foundationsList, err := newFoundations()
+	if err != nil {
+		return err
+	}
+
+	settings, err := newReloadableSettings(foundationsList)
+	if err != nil {
+		return err
+	}
+
+	nextCollectors := []prometheus.Collector{}
+	for i, metricsStore := range r.metricsStores {
+		nextCollectors = append(nextCollectors, newCollectors(r.foundations[i], settings, metricsStore)...)
+	}
+	if err := collectors.ReplaceCollectors(prometheus.DefaultRegisterer, r.collectors, nextCollectors); err != nil {
+		return fmt.Errorf("Error registering collectors: %v", err)
+	}
+	r.collectors = nextCollectors
+
+	for i, metricsStore := range r.metricsStores {
+		settings.apply(r.foundations[i], metricsStore)
+	}
+	return nil
+}
+
 func main() {
 	flag.Parse()
 	overrideFlagsWithEnvVars()
@@ -167,61 +841,55 @@ func main() {
 		os.Exit(0)
 	}
 
+	reloader := &reloader{flags: config.NewFlags(flag.CommandLine, "FIREHOSE_EXPORTER_", configIgnoredFlags, reloadableFlags)}
+	if *configFile != "" {
+		configSettings, err := reloader.flags.Load(*configFile)
+		if err != nil {
+			log.Errorf("Error loading configuration file: %v", err)
+			os.Exit(1)
+		}
+		if err := reloader.flags.Apply(configSettings); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	}
+
 	log.Infoln("Starting firehose_exporter", version.Info())
 	log.Infoln("Build context", version.BuildContext())
 
-	authTokenRefresher, err := uaatokenrefresher.New(
-		*uaaUrl,
-		*uaaClientID,
-		*uaaClientSecret,
-		*skipSSLValidation,
-	)
+	var httpRouteTemplates []string
+	if *metricsHttpRouteTemplates != "" {
+		httpRouteTemplates = strings.Split(*metricsHttpRouteTemplates, ",")
+	}
+	routeTemplates, err := utils.NewRouteTemplates(httpRouteTemplates)
 	if err != nil {
-		log.Errorf("Error creating UAA client: %s", err.Error())
+		log.Error(err)
 		os.Exit(1)
 	}
 
-	var deployments []string
-	if *dopplerDeployments != "" {
-		deployments = strings.Split(*dopplerDeployments, ",")
+	seriesLimits, err := newSeriesLimits()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
 	}
-	deploymentFilter := filters.NewDeploymentFilter(deployments)
 
-	var events []string
-	if *dopplerEvents != "" {
-		events = strings.Split(*dopplerEvents, ",")
-	}
-	eventFilter, err := filters.NewEventFilter(events)
+	foundationsList, err := newFoundations()
 	if err != nil {
 		log.Error(err)
 		os.Exit(1)
 	}
 
-	metricsStore := metrics.NewStore(*dopplerMetricExpiration, *metricsCleanupInterval, deploymentFilter, eventFilter)
-
-	nozzle := firehosenozzle.New(
-		*dopplerUrl,
-		*skipSSLValidation,
-		*dopplerSubscriptionID,
-		uint32(*dopplerIdleTimeoutSeconds),
-		authTokenRefresher,
-		metricsStore,
-	)
-	go func() {
-		log.Fatal(nozzle.Start())
-	}()
-
-	internalMetricsCollector := collectors.NewInternalMetricsCollector(*metricsNamespace, metricsStore)
-	prometheus.MustRegister(internalMetricsCollector)
-
-	containerMetricsCollector := collectors.NewContainerMetricsCollector(*metricsNamespace, metricsStore)
-	prometheus.MustRegister(containerMetricsCollector)
-
-	counterEventsCollector := collectors.NewCounterEventsCollector(*metricsNamespace, metricsStore)
-	prometheus.MustRegister(counterEventsCollector)
+	settings, err := newReloadableSettings(foundationsList)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
 
-	valueMetricsCollector := collectors.NewValueMetricsCollector(*metricsNamespace, metricsStore)
-	prometheus.MustRegister(valueMetricsCollector)
+	envelopeWriter, err := newEnvelopeWriter()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
 
 	http.Handle(*metricsPath, prometheus.Handler())
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -234,6 +902,164 @@ func main() {
              </html>`))
 	})
 
-	log.Infoln("Listening on", *listenAddress)
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	http.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Only POST requests are allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reloader.reload(); err != nil {
+			log.Errorf("Error while reloading, keeping the previous configuration: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	server := &http.Server{Addr: *listenAddress}
+
+	runGroup := rungroup.New()
+
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	reloadsDone := make(chan struct{})
+	runGroup.Add(
+		func() error {
+			for {
+				select {
+				case <-reloadSignals:
+					log.Info("Received SIGHUP signal, reloading the configuration file")
+					if err := reloader.reload(); err != nil {
+						log.Errorf("Error while reloading, keeping the previous configuration: %v", err)
+					}
+				case <-reloadsDone:
+					return nil
+				}
+			}
+		},
+		func(error) {
+			signal.Stop(reloadSignals)
+			close(reloadsDone)
+		},
+	)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	signalsDone := make(chan struct{})
+	runGroup.Add(
+		func() error {
+			select {
+			case sig := <-signals:
+				log.Infof("Received %s signal", sig)
+			case <-signalsDone:
+			}
+			return nil
+		},
+		func(error) {
+			log.Infof("Shutting down firehose_exporter (timeout %s)...", *shutdownTimeout)
+			time.AfterFunc(*shutdownTimeout, func() {
+				log.Errorf("Graceful shutdown did not complete in %s, exiting", *shutdownTimeout)
+				os.Exit(1)
+			})
+			close(signalsDone)
+		},
+	)
+
+	runGroup.Add(
+		func() error {
+			log.Infoln("Listening on", *listenAddress)
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		},
+		func(error) {
+			ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+			defer cancel()
+			if err := server.Shutdown(ctx); err != nil {
+				log.Errorf("Error while shutting down the web server: %v", err)
+			}
+		},
+	)
+
+	metricsStores := make([]*metrics.Store, 0, len(foundationsList))
+	for _, foundation := range foundationsList {
+		foundationFilters := settings.foundationFilters[foundation.Name]
+		metricsStore := metrics.NewStore(*dopplerMetricExpiration, *metricsCleanupInterval, foundationFilters.deploymentFilter, foundationFilters.eventFilter, routeTemplates)
+		if snapshotFile := snapshotFile(foundation.Name); snapshotFile != "" {
+			if err := metricsStore.LoadSnapshot(snapshotFile); err == nil {
+				log.Infof("Restored metrics snapshot from `%s`", snapshotFile)
+			} else if !os.IsNotExist(err) {
+				log.Errorf("Error while restoring metrics snapshot from `%s`: %v", snapshotFile, err)
+			}
+		}
+		settings.apply(foundation.Name, metricsStore)
+		metricsStore.SetSeriesLimits(seriesLimits)
+		metricsStores = append(metricsStores, metricsStore)
+		metricsStore.StartPipeline(int(*pipelineWorkers), int(*pipelineQueueSize), *pipelineDropWhenFull)
+
+		nozzle, err := newNozzle(foundation, metricsStore, envelopeWriter)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+
+		foundationCollectors := newCollectors(foundation.Name, settings, metricsStore)
+		prometheus.MustRegister(foundationCollectors...)
+		reloader.add(foundation.Name, metricsStore, foundationCollectors)
+
+		if foundation.Name != "" {
+			log.Infof("Consuming foundation `%s` from %s", foundation.Name, foundation.DopplerURL)
+		}
+
+		nozzleDone := make(chan struct{})
+		runGroup.Add(
+			func() error {
+				err := nozzle.Start()
+				if err == nil {
+					// A replay nozzle returns at the end of the capture, keep serving the replayed metrics.
+					<-nozzleDone
+				}
+
+				metricsStore.StopPipeline()
+				return err
+			},
+			func(error) {
+				nozzle.Stop()
+				close(nozzleDone)
+			},
+		)
+
+		runGroup.Add(metricsStore.RunCleanup, func(error) {
+			metricsStore.StopCleanup()
+		})
+
+		if snapshotFile := snapshotFile(foundation.Name); snapshotFile != "" {
+			runGroup.Add(
+				func() error {
+					return metricsStore.RunSnapshots(snapshotFile, *metricsSnapshotInterval)
+				},
+				func(error) {
+					metricsStore.StopSnapshots()
+				},
+			)
+		}
+	}
+
+	err = runGroup.Run()
+	for i, foundation := range foundationsList {
+		if snapshotFile := snapshotFile(foundation.Name); snapshotFile != "" {
+			if saveErr := metricsStores[i].SaveSnapshot(snapshotFile); saveErr != nil {
+				log.Errorf("Error while saving metrics snapshot to `%s`: %v", snapshotFile, saveErr)
+			}
+		}
+	}
+	if envelopeWriter != nil {
+		if closeErr := envelopeWriter.Close(); closeErr != nil {
+			log.Errorf("Error while closing capture file: %v", closeErr)
+		}
+	}
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	log.Info("firehose_exporter stopped")
 }
@@ -0,0 +1,141 @@
+package firehosenozzle
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/firehose_exporter/httpclient"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var endpointHealthCheckTimeout = 5 * time.Second
+
+// endpoints keeps track of the active endpoint of an ordered list, the first one being the preferred.
+type endpoints struct {
+	urls             []string
+	failbackInterval time.Duration
+	metricsStore     *metrics.Store
+	client           *http.Client
+	active           int
+	lastHealthCheck  time.Time
+	lock             sync.Mutex
+}
+
+func newEndpoints(
+	urls []string,
+	failbackInterval time.Duration,
+	tlsConfig *tls.Config,
+	proxy func(*http.Request) (*url.URL, error),
+	metricsStore *metrics.Store,
+) *endpoints {
+	e := &endpoints{
+		urls:             urls,
+		failbackInterval: failbackInterval,
+		metricsStore:     metricsStore,
+		client:           httpclient.New(tlsConfig, proxy, endpointHealthCheckTimeout),
+	}
+	if len(urls) > 0 {
+		metricsStore.SetActiveDopplerEndpoint(urls[0])
+	}
+
+	return e
+}
+
+func (e *endpoints) current() string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if len(e.urls) == 0 {
+		return ""
+	}
+
+	// Connections that keep dropping never live long enough for watchFailback, check before reconnecting too.
+	if e.active != 0 && e.failbackInterval > 0 && time.Since(e.lastHealthCheck) >= e.failbackInterval {
+		e.lastHealthCheck = time.Now()
+		if e.healthy(e.urls[0]) {
+			e.failback()
+		}
+	}
+
+	return e.urls[e.active]
+}
+
+// failover makes the next endpoint of the list the active one.
+func (e *endpoints) failover() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if len(e.urls) < 2 {
+		return
+	}
+
+	e.active = (e.active + 1) % len(e.urls)
+	e.lastHealthCheck = time.Now()
+	log.Infof("Failing over to `%s`", e.urls[e.active])
+	e.metricsStore.AddDopplerEndpointFailover()
+	e.metricsStore.SetActiveDopplerEndpoint(e.urls[e.active])
+}
+
+// watchFailback checks the health of the preferred endpoint every failback interval while another one
+// is active. As soon as it is healthy, the preferred endpoint becomes the active one and reconnect is called.
+func (e *endpoints) watchFailback(done <-chan struct{}, reconnect func()) {
+	e.lock.Lock()
+	active := e.active
+	e.lock.Unlock()
+
+	if active == 0 || e.failbackInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.failbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !e.healthy(e.urls[0]) {
+				continue
+			}
+
+			e.lock.Lock()
+			e.failback()
+			e.lock.Unlock()
+
+			reconnect()
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// failback makes the preferred endpoint the active one, the caller must hold the lock.
+func (e *endpoints) failback() {
+	e.active = 0
+	log.Infof("Failing back to `%s`", e.urls[0])
+	e.metricsStore.SetActiveDopplerEndpoint(e.urls[0])
+}
+
+// healthy checks whether the endpoint answers an HTTP request sent with the proxy and TLS settings of
+// the nozzle, whatever the response status.
+func (e *endpoints) healthy(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	u.Scheme = strings.Replace(u.Scheme, "ws", "http", 1)
+
+	resp, err := e.client.Get(u.String())
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return true
+}
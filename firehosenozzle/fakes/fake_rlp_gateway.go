@@ -0,0 +1,101 @@
+package fakes
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type FakeRLPGateway struct {
+	server *httptest.Server
+	lock   sync.Mutex
+
+	validToken string
+
+	lastAuthorization string
+	lastQuery         url.Values
+	requested         bool
+
+	envelopes []string
+}
+
+func NewFakeRLPGateway(validToken string) *FakeRLPGateway {
+	return &FakeRLPGateway{
+		validToken: validToken,
+	}
+}
+
+func (f *FakeRLPGateway) Start() {
+	f.server = httptest.NewUnstartedServer(f)
+	f.server.Start()
+}
+
+func (f *FakeRLPGateway) Close() {
+	f.server.Close()
+}
+
+func (f *FakeRLPGateway) URL() string {
+	return f.server.URL
+}
+
+func (f *FakeRLPGateway) LastAuthorization() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.lastAuthorization
+}
+
+func (f *FakeRLPGateway) LastQuery() url.Values {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.lastQuery
+}
+
+func (f *FakeRLPGateway) Requested() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.requested
+}
+
+// AddEnvelope adds a JSON encoded v2 envelope to be sent on every connection.
+func (f *FakeRLPGateway) AddEnvelope(envelope string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.envelopes = append(f.envelopes, envelope)
+}
+
+func (f *FakeRLPGateway) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.lastAuthorization = r.Header.Get("Authorization")
+	f.lastQuery = r.URL.Query()
+	f.requested = true
+
+	if f.lastAuthorization != f.validToken {
+		log.Printf("Bad token passed to RLP Gateway: %s", f.lastAuthorization)
+		rw.WriteHeader(403)
+		r.Body.Close()
+		return
+	}
+
+	if r.URL.Path != "/v2/read" {
+		rw.WriteHeader(404)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.WriteHeader(200)
+
+	fmt.Fprint(rw, "event: heartbeat\ndata: 1500000000\n\n")
+	for _, envelope := range f.envelopes {
+		fmt.Fprintf(rw, "data: {\"batch\":[%s]}\n\n", strings.TrimSpace(envelope))
+	}
+
+	if flusher, ok := rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
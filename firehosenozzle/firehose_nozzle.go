@@ -2,6 +2,11 @@ package firehosenozzle
 
 import (
 	"crypto/tls"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cloudfoundry/noaa/consumer"
@@ -9,34 +14,49 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/prometheus/common/log"
 
+	"github.com/cloudfoundry-community/firehose_exporter/capture"
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
 )
 
+var authorizationHeaderRegexp = regexp.MustCompile(`(?m)^Authorization: .*$`)
+
 type FirehoseNozzle struct {
-	url                string
-	skipSSLValidation  bool
+	endpoints          *endpoints
+	tlsConfig          *tls.Config
+	proxy              func(*http.Request) (*url.URL, error)
 	subscriptionID     string
 	idleTimeoutSeconds uint32
+	reconnector        *reconnector
 	authTokenRefresher consumer.TokenRefresher
 	metricsStore       *metrics.Store
+	envelopeWriter     *capture.EnvelopeWriter
 	errs               <-chan error
 	messages           <-chan *events.Envelope
 	consumer           *consumer.Consumer
+	consumerLock       sync.Mutex
+	connected          int32
 }
 
 func New(
-	url string,
-	skipSSLValidation bool,
+	urls []string,
+	failbackInterval time.Duration,
+	tlsConfig *tls.Config,
+	proxy func(*http.Request) (*url.URL, error),
 	subscriptionID string,
 	idleTimeoutSeconds uint32,
+	reconnectMinBackoff time.Duration,
+	reconnectMaxBackoff time.Duration,
+	reconnectMaxRetries uint,
 	authTokenRefresher consumer.TokenRefresher,
 	metricsStore *metrics.Store,
 ) *FirehoseNozzle {
 	return &FirehoseNozzle{
-		url:                url,
-		skipSSLValidation:  skipSSLValidation,
+		endpoints:          newEndpoints(urls, failbackInterval, tlsConfig, proxy, metricsStore),
+		tlsConfig:          tlsConfig,
+		proxy:              proxy,
 		subscriptionID:     subscriptionID,
 		idleTimeoutSeconds: idleTimeoutSeconds,
+		reconnector:        newReconnector(reconnectMinBackoff, reconnectMaxBackoff, reconnectMaxRetries, metricsStore),
 		authTokenRefresher: authTokenRefresher,
 		metricsStore:       metricsStore,
 		errs:               make(<-chan error),
@@ -44,33 +64,93 @@ func New(
 	}
 }
 
+// SetEnvelopeWriter captures every received envelope with the given writer.
+func (n *FirehoseNozzle) SetEnvelopeWriter(envelopeWriter *capture.EnvelopeWriter) {
+	n.envelopeWriter = envelopeWriter
+}
+
 func (n *FirehoseNozzle) Start() error {
 	log.Info("Starting Firehose Nozzle...")
-	n.consumeFirehose()
-	err := n.parseEnvelopes()
+	err := n.reconnector.run(n.connect)
 	log.Info("Firehose Nozzle shutting down...")
 	return err
 }
 
-func (n *FirehoseNozzle) consumeFirehose() {
+// Stop closes the connection with the Firehose and makes Start return.
+func (n *FirehoseNozzle) Stop() {
+	n.consumerLock.Lock()
+	defer n.consumerLock.Unlock()
+
+	n.reconnector.stop()
+	if n.consumer != nil {
+		log.Info("Closing connection with Firehose...")
+		n.consumer.Close()
+	}
+}
+
+func (n *FirehoseNozzle) connect() (bool, error) {
+	if !n.consumeFirehose() {
+		return false, nil
+	}
+
+	failbackDone := make(chan struct{})
+	go n.endpoints.watchFailback(failbackDone, n.closeConsumer)
+	err := n.parseEnvelopes()
+	close(failbackDone)
+
+	connected := atomic.LoadInt32(&n.connected) == 1
+	if connected {
+		n.metricsStore.AddFirehoseDisconnect(closeCode(err))
+	} else if err != nil && !n.reconnector.stopped() {
+		n.endpoints.failover()
+	}
+	n.handleError(err)
+	return connected, err
+}
+
+func (n *FirehoseNozzle) consumeFirehose() bool {
+	n.consumerLock.Lock()
+	defer n.consumerLock.Unlock()
+
+	if n.reconnector.stopped() {
+		return false
+	}
+
+	atomic.StoreInt32(&n.connected, 0)
+
 	n.consumer = consumer.New(
-		n.url,
-		&tls.Config{InsecureSkipVerify: n.skipSSLValidation},
-		nil,
+		n.endpoints.current(),
+		n.tlsConfig,
+		n.proxy,
 	)
 	n.consumer.RefreshTokenFrom(n.authTokenRefresher)
 	n.consumer.SetIdleTimeout(time.Duration(n.idleTimeoutSeconds) * time.Second)
-	n.messages, n.errs = n.consumer.Firehose(n.subscriptionID, "")
+	n.consumer.SetOnConnectCallback(n.onConnect)
+	n.consumer.SetDebugPrinter(debugPrinter{})
+	n.messages, n.errs = n.consumer.FirehoseWithoutReconnect(n.subscriptionID, "")
+
+	return true
+}
+
+func (n *FirehoseNozzle) onConnect() {
+	log.Info("Connected to the Firehose")
+	atomic.StoreInt32(&n.connected, 1)
+	n.metricsStore.AddFirehoseConnect()
 }
 
 func (n *FirehoseNozzle) parseEnvelopes() error {
 	for {
 		select {
-		case envelope := <-n.messages:
+		case envelope, ok := <-n.messages:
+			if !ok {
+				n.messages = nil
+				continue
+			}
+			n.metricsStore.AddEnvelopeBytesDecoded(int64(envelope.Size()))
 			n.handleMessage(envelope)
+			captureEnvelope(n.envelopeWriter, envelope)
 			n.metricsStore.AddMetric(envelope)
 		case err := <-n.errs:
-			n.handleError(err)
 			return err
 		}
 	}
@@ -85,6 +165,8 @@ func (n *FirehoseNozzle) handleMessage(envelope *events.Envelope) {
 
 func (n *FirehoseNozzle) handleError(err error) {
 	switch closeErr := err.(type) {
+	case nil:
+	// no op
 	case *websocket.CloseError:
 		switch closeErr.Code {
 		case websocket.CloseNormalClosure:
@@ -100,6 +182,33 @@ func (n *FirehoseNozzle) handleError(err error) {
 		log.Errorf("Error while reading from the Firehose: %v", err)
 	}
 
+	n.closeConsumer()
+}
+
+func (n *FirehoseNozzle) closeConsumer() {
+	n.consumerLock.Lock()
+	defer n.consumerLock.Unlock()
+
 	log.Info("Closing connection with Firehose...")
 	n.consumer.Close()
 }
+
+// closeCode returns the websocket close code of the error ending a connection.
+// Errors other than close frames are reported as an abnormal closure.
+func closeCode(err error) int {
+	switch closeErr := err.(type) {
+	case nil:
+		return websocket.CloseNormalClosure
+	case *websocket.CloseError:
+		return closeErr.Code
+	default:
+		return websocket.CloseAbnormalClosure
+	}
+}
+
+// debugPrinter logs the websocket handshakes at debug level, hiding the auth token.
+type debugPrinter struct{}
+
+func (debugPrinter) Print(title, dump string) {
+	log.Debugf("%s\n%s", title, authorizationHeaderRegexp.ReplaceAllString(dump, "Authorization: [HIDDEN]"))
+}
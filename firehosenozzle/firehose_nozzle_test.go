@@ -1,19 +1,28 @@
 package firehosenozzle_test
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/cloudfoundry-community/firehose_exporter/capture"
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
 	firehosefakes "github.com/cloudfoundry-community/firehose_exporter/firehosenozzle/fakes"
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
 	"github.com/cloudfoundry-community/firehose_exporter/uaatokenrefresher"
 	"github.com/cloudfoundry-community/firehose_exporter/uaatokenrefresher/fakes"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/gogo/protobuf/proto"
 	"github.com/gorilla/websocket"
@@ -27,9 +36,13 @@ func init() {
 
 var _ = Describe("FirehoseNozzle", func() {
 	var (
-		skipSSLValidation  bool
-		subscriptionID     string
-		idleTimeoutSeconds uint32
+		tlsConfig           *tls.Config
+		proxy               func(*http.Request) (*url.URL, error)
+		subscriptionID      string
+		idleTimeoutSeconds  uint32
+		reconnectMinBackoff time.Duration
+		reconnectMaxBackoff time.Duration
+		reconnectMaxRetries uint
 
 		fakeUAA   *fakes.FakeUAA
 		fakeToken string
@@ -42,18 +55,26 @@ var _ = Describe("FirehoseNozzle", func() {
 		metricsCleanupInterval time.Duration
 		deploymentFilter       *filters.DeploymentFilter
 		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
 		metricsStore           *metrics.Store
 
-		firehoseNozzle *FirehoseNozzle
+		firehoseURLs     []string
+		failbackInterval time.Duration
+		firehoseNozzle   *FirehoseNozzle
+		startErr         chan error
 
 		envelope     events.Envelope
 		numEnvelopes = 10
 	)
 
 	BeforeEach(func() {
-		skipSSLValidation = true
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+		proxy = nil
 		subscriptionID = "fake-subscription-id"
 		idleTimeoutSeconds = 5
+		reconnectMinBackoff = 1 * time.Minute
+		reconnectMaxBackoff = 1 * time.Minute
+		reconnectMaxRetries = 0
 
 		fakeUAA = fakes.NewFakeUAA("bearer", "123456789")
 		fakeToken = fakeUAA.AuthToken()
@@ -61,14 +82,17 @@ var _ = Describe("FirehoseNozzle", func() {
 
 		fakeFirehose = firehosefakes.NewFakeFirehose(fakeToken)
 		fakeFirehose.Start()
+		firehoseURLs = []string{strings.Replace(fakeFirehose.URL(), "http:", "ws:", 1)}
+		failbackInterval = 1 * time.Minute
 
 		authTokenRefresher, _ = uaatokenrefresher.New(
-			fakeUAA.URL(), "client-id", "client-secret", true,
+			fakeUAA.URL(), "client-id", "client-secret", tlsConfig, proxy,
 		)
 
 		deploymentFilter = filters.NewDeploymentFilter([]string{})
 		eventFilter, _ = filters.NewEventFilter([]string{})
-		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter)
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
 
 		for i := 0; i < numEnvelopes; i++ {
 			envelope = events.Envelope{
@@ -91,14 +115,22 @@ var _ = Describe("FirehoseNozzle", func() {
 
 	JustBeforeEach(func() {
 		firehoseNozzle = New(
-			strings.Replace(fakeFirehose.URL(), "http:", "ws:", 1),
-			skipSSLValidation,
+			firehoseURLs,
+			failbackInterval,
+			tlsConfig,
+			proxy,
 			subscriptionID,
 			idleTimeoutSeconds,
+			reconnectMinBackoff,
+			reconnectMaxBackoff,
+			reconnectMaxRetries,
 			authTokenRefresher,
 			metricsStore,
 		)
-		go firehoseNozzle.Start()
+		startErr = make(chan error, 1)
+		go func() {
+			startErr <- firehoseNozzle.Start()
+		}()
 	})
 
 	AfterEach(func() {
@@ -157,6 +189,10 @@ var _ = Describe("FirehoseNozzle", func() {
 				Eventually(fakeFirehose.Requested).Should(BeTrue())
 				Consistently(metricsStore.GetInternalMetrics().SlowConsumerAlert).Should(BeTrue())
 			})
+
+			It("accounts the disconnect by close code", func() {
+				Eventually(func() map[int]int64 { return metricsStore.GetInternalMetrics().TotalFirehoseDisconnects }).Should(HaveKeyWithValue(websocket.ClosePolicyViolation, int64(1)))
+			})
 		})
 
 		Context("for other reasons", func() {
@@ -170,4 +206,193 @@ var _ = Describe("FirehoseNozzle", func() {
 			})
 		})
 	})
+
+	Context("when the firehose disconnects", func() {
+		BeforeEach(func() {
+			reconnectMinBackoff = 10 * time.Millisecond
+			reconnectMaxBackoff = 10 * time.Millisecond
+		})
+
+		It("reconnects to the firehose", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalFirehoseReconnects }).Should(BeNumerically(">=", 1))
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalEnvelopesReceived }).Should(BeNumerically(">=", 2*numEnvelopes))
+		})
+
+		It("accounts the connections and disconnects", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalFirehoseConnections }).Should(BeNumerically(">=", 2))
+			Eventually(func() int64 {
+				return metricsStore.GetInternalMetrics().TotalFirehoseDisconnects[websocket.CloseNormalClosure]
+			}).Should(BeNumerically(">=", 1))
+			Expect(metricsStore.GetInternalMetrics().LastFirehoseConnectTimestamp).ToNot(Equal(int64(0)))
+		})
+
+		It("accounts the bytes of the decoded envelopes", func() {
+			data, err := proto.Marshal(&envelope)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				internalMetrics := metricsStore.GetInternalMetrics()
+				return internalMetrics.TotalEnvelopesReceived >= int64(numEnvelopes) &&
+					internalMetrics.TotalEnvelopeBytesDecoded == internalMetrics.TotalEnvelopesReceived*int64(len(data))
+			}).Should(BeTrue())
+		})
+
+		It("does not stop the nozzle", func() {
+			Consistently(startErr).ShouldNot(Receive())
+		})
+	})
+
+	Context("when stopped", func() {
+		It("stops the nozzle", func() {
+			Eventually(fakeFirehose.Requested).Should(BeTrue())
+			firehoseNozzle.Stop()
+			Eventually(startErr).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("when the firehose cannot be reached", func() {
+		BeforeEach(func() {
+			firehoseURLs = []string{"ws://127.0.0.1:1"}
+			reconnectMinBackoff = 10 * time.Millisecond
+			reconnectMaxBackoff = 20 * time.Millisecond
+			reconnectMaxRetries = 3
+		})
+
+		It("gives up after the maximum number of retries", func() {
+			Eventually(startErr, 5*time.Second).Should(Receive(HaveOccurred()))
+			Expect(metricsStore.GetInternalMetrics().TotalFirehoseReconnects).To(Equal(int64(3)))
+			Expect(metricsStore.GetInternalMetrics().FirehoseConnected).To(BeFalse())
+		})
+	})
+
+	Context("when the preferred endpoint cannot be reached", func() {
+		var (
+			rejectingFirehose *firehosefakes.FakeFirehose
+		)
+
+		BeforeEach(func() {
+			rejectingFirehose = firehosefakes.NewFakeFirehose("invalid-token")
+			rejectingFirehose.Start()
+
+			firehoseURLs = []string{strings.Replace(rejectingFirehose.URL(), "http:", "ws:", 1), firehoseURLs[0]}
+			reconnectMinBackoff = 10 * time.Millisecond
+			reconnectMaxBackoff = 10 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			rejectingFirehose.Close()
+		})
+
+		It("fails over to the next endpoint", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalEnvelopesReceived }).Should(BeNumerically(">=", numEnvelopes))
+			Expect(metricsStore.GetInternalMetrics().TotalDopplerEndpointFailovers).To(BeNumerically(">=", 1))
+		})
+
+		It("reports the active endpoint", func() {
+			Eventually(func() string { return metricsStore.GetInternalMetrics().ActiveDopplerEndpoint }).Should(Equal(firehoseURLs[1]))
+		})
+
+		Context("and it is healthy again", func() {
+			BeforeEach(func() {
+				failbackInterval = 10 * time.Millisecond
+			})
+
+			It("fails back to the preferred endpoint", func() {
+				Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalDopplerEndpointFailovers }).Should(BeNumerically(">=", 2))
+				Expect(rejectingFirehose.Requested()).To(BeTrue())
+			})
+
+			Context("and it is only reachable through a proxy", func() {
+				var (
+					fakeProxy     *httptest.Server
+					proxiedHosts  chan string
+					preferredHost = "preferred-doppler.invalid"
+				)
+
+				BeforeEach(func() {
+					proxiedHosts = make(chan string, 100)
+					fakeProxy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						select {
+						case proxiedHosts <- r.Host:
+						default:
+						}
+						w.WriteHeader(http.StatusBadGateway)
+					}))
+
+					proxyURL, _ := url.Parse(fakeProxy.URL)
+					proxy = func(r *http.Request) (*url.URL, error) {
+						if r.URL.Hostname() == preferredHost {
+							return proxyURL, nil
+						}
+						return nil, nil
+					}
+					firehoseURLs[0] = "ws://" + preferredHost
+				})
+
+				AfterEach(func() {
+					fakeProxy.Close()
+				})
+
+				It("checks the health of the preferred endpoint through the proxy", func() {
+					Eventually(proxiedHosts).Should(Receive(Equal(preferredHost)))
+					Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalDopplerEndpointFailovers }).Should(BeNumerically(">=", 2))
+				})
+			})
+		})
+	})
+
+	Context("when capturing envelopes", func() {
+		var (
+			dir  string
+			path string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "capture")
+			Expect(err).ToNot(HaveOccurred())
+			path = filepath.Join(dir, "envelopes.pb")
+		})
+
+		JustBeforeEach(func() {
+			envelopeWriter, err := capture.NewEnvelopeWriter(path, false, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			captureStore := metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+			captureNozzle := New(
+				firehoseURLs,
+				failbackInterval,
+				tlsConfig,
+				proxy,
+				subscriptionID,
+				idleTimeoutSeconds,
+				reconnectMinBackoff,
+				reconnectMaxBackoff,
+				reconnectMaxRetries,
+				authTokenRefresher,
+				captureStore,
+			)
+			captureNozzle.SetEnvelopeWriter(envelopeWriter)
+			go captureNozzle.Start()
+
+			Eventually(func() int64 { return captureStore.GetInternalMetrics().TotalEnvelopesReceived }).Should(Equal(int64(numEnvelopes)))
+			Expect(envelopeWriter.Close()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("writes the received envelopes", func() {
+			envelopeReader, err := capture.NewEnvelopeReader(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer envelopeReader.Close()
+
+			for i := 0; i < numEnvelopes; i++ {
+				envelope, err := envelopeReader.Next()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(envelope.GetValueMetric().GetName()).To(Equal(fmt.Sprintf("fake-metric-%d", i)))
+			}
+		})
+	})
 })
@@ -0,0 +1,23 @@
+package firehosenozzle
+
+import (
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/firehose_exporter/capture"
+)
+
+type Nozzle interface {
+	Start() error
+	Stop()
+}
+
+func captureEnvelope(envelopeWriter *capture.EnvelopeWriter, envelope *events.Envelope) {
+	if envelopeWriter == nil {
+		return
+	}
+
+	if err := envelopeWriter.Write(envelope); err != nil {
+		log.Errorf("Error while capturing envelope: %v", err)
+	}
+}
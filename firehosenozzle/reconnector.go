@@ -0,0 +1,97 @@
+package firehosenozzle
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+type reconnector struct {
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	maxRetries   uint
+	metricsStore *metrics.Store
+	done         chan struct{}
+	doneOnce     sync.Once
+}
+
+func newReconnector(
+	minBackoff time.Duration,
+	maxBackoff time.Duration,
+	maxRetries uint,
+	metricsStore *metrics.Store,
+) *reconnector {
+	return &reconnector{
+		minBackoff:   minBackoff,
+		maxBackoff:   maxBackoff,
+		maxRetries:   maxRetries,
+		metricsStore: metricsStore,
+		done:         make(chan struct{}),
+	}
+}
+
+// run keeps calling connect until the maximum number of consecutive failed attempts is reached,
+// or until the reconnector is stopped. An attempt that managed to connect resets the backoff.
+func (r *reconnector) run(connect func() (bool, error)) error {
+	retries := uint(0)
+	for {
+		connected, err := connect()
+		if r.stopped() {
+			return nil
+		}
+		if connected {
+			retries = 0
+		}
+
+		if r.maxRetries > 0 && retries >= r.maxRetries {
+			log.Errorf("Giving up reconnecting after %d attempts", retries)
+			return err
+		}
+
+		backoff := r.backoff(retries)
+		retries++
+		log.Infof("Reconnecting in %s (attempt %d)...", backoff, retries)
+		r.metricsStore.AddFirehoseReconnect()
+
+		select {
+		case <-time.After(backoff):
+		case <-r.done:
+			return nil
+		}
+	}
+}
+
+func (r *reconnector) stop() {
+	r.doneOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+func (r *reconnector) stopped() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *reconnector) backoff(retries uint) time.Duration {
+	backoff := r.minBackoff
+	for i := uint(0); i < retries && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
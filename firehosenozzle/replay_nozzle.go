@@ -0,0 +1,91 @@
+package firehosenozzle
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/firehose_exporter/capture"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+type ReplayNozzle struct {
+	path         string
+	speed        float64
+	metricsStore *metrics.Store
+	done         chan struct{}
+	doneOnce     sync.Once
+}
+
+// NewReplayNozzle creates a nozzle feeding the envelopes of a capture file into the metrics store.
+// Envelopes are replayed at `speed` times the pace they were received, or as fast as possible when speed is 0.
+func NewReplayNozzle(
+	path string,
+	speed float64,
+	metricsStore *metrics.Store,
+) *ReplayNozzle {
+	return &ReplayNozzle{
+		path:         path,
+		speed:        speed,
+		metricsStore: metricsStore,
+		done:         make(chan struct{}),
+	}
+}
+
+func (n *ReplayNozzle) Start() error {
+	log.Infof("Starting Replay Nozzle from `%s`...", n.path)
+
+	envelopeReader, err := capture.NewEnvelopeReader(n.path)
+	if err != nil {
+		return err
+	}
+	defer envelopeReader.Close()
+
+	var (
+		firstTimestamp int64
+		startTime      time.Time
+	)
+	for {
+		select {
+		case <-n.done:
+			log.Info("Replay Nozzle stopped")
+			return nil
+		default:
+		}
+
+		envelope, err := envelopeReader.Next()
+		if err == io.EOF {
+			log.Info("Replay Nozzle reached the end of the capture")
+			return nil
+		}
+		if err != nil {
+			log.Errorf("Error while reading the capture: %v", err)
+			return err
+		}
+
+		if n.speed > 0 {
+			if firstTimestamp == 0 {
+				firstTimestamp = envelope.GetTimestamp()
+				startTime = time.Now()
+			}
+			elapsed := time.Duration(float64(envelope.GetTimestamp()-firstTimestamp) / n.speed)
+			if wait := startTime.Add(elapsed).Sub(time.Now()); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-n.done:
+					log.Info("Replay Nozzle stopped")
+					return nil
+				}
+			}
+		}
+
+		n.metricsStore.AddMetric(envelope)
+	}
+}
+
+// Stop makes Start return before the end of the capture.
+func (n *ReplayNozzle) Stop() {
+	n.doneOnce.Do(func() { close(n.done) })
+}
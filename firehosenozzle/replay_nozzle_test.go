@@ -0,0 +1,141 @@
+package firehosenozzle_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/capture"
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/firehosenozzle"
+)
+
+var _ = Describe("ReplayNozzle", func() {
+	var (
+		err  error
+		dir  string
+		path string
+
+		speed float64
+
+		metricsExpiration      time.Duration
+		metricsCleanupInterval time.Duration
+		deploymentFilter       *filters.DeploymentFilter
+		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
+		metricsStore           *metrics.Store
+
+		replayNozzle *ReplayNozzle
+		startErr     chan error
+
+		numEnvelopes      = 5
+		envelopeInterval  = int64(100 * time.Millisecond)
+		envelopeTimestamp = int64(1500000000000000000)
+	)
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "replay")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "envelopes.pb")
+		speed = 0
+
+		envelopeWriter, err := capture.NewEnvelopeWriter(path, true, 0)
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < numEnvelopes; i++ {
+			err = envelopeWriter.Write(&events.Envelope{
+				Origin:     proto.String("fake-origin"),
+				EventType:  events.Envelope_ValueMetric.Enum(),
+				Timestamp:  proto.Int64(envelopeTimestamp + int64(i)*envelopeInterval),
+				Deployment: proto.String("fake-deployment-name"),
+				Job:        proto.String("fake-job-name"),
+				Index:      proto.String("0"),
+				Ip:         proto.String("1.2.3.4"),
+				ValueMetric: &events.ValueMetric{
+					Name:  proto.String(fmt.Sprintf("fake-metric-%d", i)),
+					Value: proto.Float64(float64(i)),
+					Unit:  proto.String("counter"),
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(envelopeWriter.Close()).To(Succeed())
+
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+	})
+
+	JustBeforeEach(func() {
+		replayNozzle = NewReplayNozzle(path, speed, metricsStore)
+		startErr = make(chan error, 1)
+		go func() {
+			startErr <- replayNozzle.Start()
+		}()
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("replays all envelopes", func() {
+		Eventually(startErr).Should(Receive(BeNil()))
+		Expect(metricsStore.GetInternalMetrics().TotalEnvelopesReceived).To(Equal(int64(numEnvelopes)))
+		Expect(metricsStore.GetValueMetrics()).To(HaveLen(numEnvelopes))
+	})
+
+	Context("when replaying in real time", func() {
+		BeforeEach(func() {
+			speed = 1
+		})
+
+		It("replays the envelopes at the pace they were received", func() {
+			Consistently(startErr, 300*time.Millisecond).ShouldNot(Receive())
+			Eventually(startErr).Should(Receive(BeNil()))
+			Expect(metricsStore.GetInternalMetrics().TotalEnvelopesReceived).To(Equal(int64(numEnvelopes)))
+		})
+	})
+
+	Context("when replaying faster", func() {
+		BeforeEach(func() {
+			speed = 100
+		})
+
+		It("replays the envelopes at N times the pace they were received", func() {
+			Eventually(startErr, 200*time.Millisecond).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("when stopped", func() {
+		BeforeEach(func() {
+			speed = 1
+		})
+
+		It("stops replaying", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalEnvelopesReceived }).Should(BeNumerically(">=", 1))
+			replayNozzle.Stop()
+			Eventually(startErr).Should(Receive(BeNil()))
+			Expect(metricsStore.GetInternalMetrics().TotalEnvelopesReceived).To(BeNumerically("<", numEnvelopes))
+		})
+	})
+
+	Context("when the capture file does not exist", func() {
+		BeforeEach(func() {
+			path = filepath.Join(dir, "missing.pb")
+		})
+
+		It("returns an error", func() {
+			Eventually(startErr).Should(Receive(HaveOccurred()))
+		})
+	})
+})
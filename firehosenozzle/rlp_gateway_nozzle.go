@@ -0,0 +1,273 @@
+package firehosenozzle
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/noaa/consumer"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/firehose_exporter/capture"
+	"github.com/cloudfoundry-community/firehose_exporter/httpclient"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var rlpGatewayEnvelopeTypes = []string{"log", "counter", "gauge", "timer", "event"}
+
+type RLPGatewayNozzle struct {
+	endpoints          *endpoints
+	subscriptionID     string
+	idleTimeoutSeconds uint32
+	reconnector        *reconnector
+	authTokenRefresher consumer.TokenRefresher
+	metricsStore       *metrics.Store
+	envelopeWriter     *capture.EnvelopeWriter
+	client             *http.Client
+	cancel             context.CancelFunc
+	cancelLock         sync.Mutex
+}
+
+func NewRLPGatewayNozzle(
+	urls []string,
+	failbackInterval time.Duration,
+	tlsConfig *tls.Config,
+	proxy func(*http.Request) (*url.URL, error),
+	subscriptionID string,
+	idleTimeoutSeconds uint32,
+	reconnectMinBackoff time.Duration,
+	reconnectMaxBackoff time.Duration,
+	reconnectMaxRetries uint,
+	authTokenRefresher consumer.TokenRefresher,
+	metricsStore *metrics.Store,
+) *RLPGatewayNozzle {
+	trimmedURLs := make([]string, 0, len(urls))
+	for _, endpoint := range urls {
+		trimmedURLs = append(trimmedURLs, strings.TrimSuffix(endpoint, "/"))
+	}
+
+	return &RLPGatewayNozzle{
+		endpoints:          newEndpoints(trimmedURLs, failbackInterval, tlsConfig, proxy, metricsStore),
+		subscriptionID:     subscriptionID,
+		idleTimeoutSeconds: idleTimeoutSeconds,
+		reconnector:        newReconnector(reconnectMinBackoff, reconnectMaxBackoff, reconnectMaxRetries, metricsStore),
+		authTokenRefresher: authTokenRefresher,
+		metricsStore:       metricsStore,
+		client:             httpclient.New(tlsConfig, proxy, 0), // the stream is guarded by the idle timeout instead
+	}
+}
+
+// SetEnvelopeWriter captures every received envelope with the given writer.
+func (n *RLPGatewayNozzle) SetEnvelopeWriter(envelopeWriter *capture.EnvelopeWriter) {
+	n.envelopeWriter = envelopeWriter
+}
+
+func (n *RLPGatewayNozzle) Start() error {
+	log.Info("Starting RLP Gateway Nozzle...")
+	err := n.reconnector.run(n.connect)
+	log.Info("RLP Gateway Nozzle shutting down...")
+	return err
+}
+
+// Stop closes the connection with the RLP Gateway and makes Start return.
+func (n *RLPGatewayNozzle) Stop() {
+	n.cancelLock.Lock()
+	defer n.cancelLock.Unlock()
+
+	n.reconnector.stop()
+	if n.cancel != nil {
+		n.cancel()
+	}
+}
+
+func (n *RLPGatewayNozzle) closeStream() {
+	n.cancelLock.Lock()
+	defer n.cancelLock.Unlock()
+
+	if n.cancel != nil {
+		n.cancel()
+	}
+}
+
+func (n *RLPGatewayNozzle) connect() (bool, error) {
+	ctx, ok := n.newConnectionContext()
+	if !ok {
+		return false, nil
+	}
+
+	body, err := n.openStream(ctx)
+	if err != nil {
+		if n.reconnector.stopped() {
+			return false, nil
+		}
+		log.Errorf("Error while connecting to the RLP Gateway: %v", err)
+		n.endpoints.failover()
+		return false, err
+	}
+
+	log.Info("Connected to the RLP Gateway")
+	n.metricsStore.AddFirehoseConnect()
+
+	failbackDone := make(chan struct{})
+	go n.endpoints.watchFailback(failbackDone, n.closeStream)
+	err = n.parseEvents(body)
+	close(failbackDone)
+
+	// The stream was closed on purpose when stopping or failing back.
+	if ctx.Err() != nil {
+		err = nil
+	}
+	if err != nil {
+		n.metricsStore.AddFirehoseDisconnect(websocket.CloseAbnormalClosure)
+	} else {
+		n.metricsStore.AddFirehoseDisconnect(websocket.CloseNormalClosure)
+	}
+	if err != nil {
+		log.Errorf("Error while reading from the RLP Gateway: %v", err)
+	}
+
+	log.Info("Closing connection with RLP Gateway...")
+	body.Close()
+
+	return true, err
+}
+
+func (n *RLPGatewayNozzle) newConnectionContext() (context.Context, bool) {
+	n.cancelLock.Lock()
+	defer n.cancelLock.Unlock()
+
+	if n.reconnector.stopped() {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+	return ctx, true
+}
+
+func (n *RLPGatewayNozzle) openStream(ctx context.Context) (io.ReadCloser, error) {
+	authToken, err := n.authTokenRefresher.RefreshAuthToken()
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("shard_id", n.subscriptionID)
+	for _, envelopeType := range rlpGatewayEnvelopeTypes {
+		query.Set(envelopeType, "")
+	}
+
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/v2/read?%s", n.endpoints.current(), query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Authorization", authToken)
+	request.Header.Set("Accept", "text/event-stream")
+
+	response, err := n.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("unexpected status `%s`", response.Status)
+	}
+
+	return response.Body, nil
+}
+
+func (n *RLPGatewayNozzle) parseEvents(body io.ReadCloser) error {
+	idleTimeout := time.Duration(n.idleTimeoutSeconds) * time.Second
+	if idleTimeout > 0 {
+		idleTimer := time.AfterFunc(idleTimeout, func() {
+			log.Errorf("No data received from the RLP Gateway in %s", idleTimeout)
+			body.Close()
+		})
+		defer idleTimer.Stop()
+		body = &idleTimeoutReader{ReadCloser: body, timer: idleTimer, timeout: idleTimeout}
+	}
+
+	var (
+		event string
+		data  bytes.Buffer
+	)
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case len(line) == 0:
+			// Heartbeats and other named events do not carry envelopes.
+			if data.Len() > 0 && (event == "" || event == "message") {
+				n.handleEvent(data.Bytes())
+			}
+			event = ""
+			data.Reset()
+		case bytes.HasPrefix(line, []byte("event:")):
+			event = string(bytes.TrimSpace(bytes.TrimPrefix(line, []byte("event:"))))
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+		}
+	}
+}
+
+func (n *RLPGatewayNozzle) handleEvent(data []byte) {
+	var batch v2EnvelopeBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		log.Errorf("Error while decoding RLP Gateway envelopes: %v", err)
+		return
+	}
+
+	for _, v2Envelope := range batch.Batch {
+		for _, envelope := range v2Envelope.toV1Envelopes() {
+			n.metricsStore.AddEnvelopeBytesDecoded(int64(envelope.Size()))
+			n.handleMessage(envelope)
+			captureEnvelope(n.envelopeWriter, envelope)
+			n.metricsStore.AddMetric(envelope)
+		}
+	}
+}
+
+func (n *RLPGatewayNozzle) handleMessage(envelope *events.Envelope) {
+	if envelope.GetEventType() == events.Envelope_CounterEvent && envelope.CounterEvent.GetName() == "dropped" && envelope.GetTags()["direction"] == "egress" {
+		log.Infof("We've intercepted an upstream message which indicates that the Nozzle or the RLP Gateway is not keeping up. Please try scaling up the Nozzle.")
+		n.metricsStore.AlertSlowConsumerError()
+	}
+}
+
+type idleTimeoutReader struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
@@ -0,0 +1,316 @@
+package firehosenozzle_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	firehosefakes "github.com/cloudfoundry-community/firehose_exporter/firehosenozzle/fakes"
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+	"github.com/cloudfoundry-community/firehose_exporter/uaatokenrefresher"
+	"github.com/cloudfoundry-community/firehose_exporter/uaatokenrefresher/fakes"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/firehosenozzle"
+)
+
+var _ = Describe("RLPGatewayNozzle", func() {
+	var (
+		tlsConfig           *tls.Config
+		proxy               func(*http.Request) (*url.URL, error)
+		subscriptionID      string
+		idleTimeoutSeconds  uint32
+		reconnectMinBackoff time.Duration
+		reconnectMaxBackoff time.Duration
+		reconnectMaxRetries uint
+
+		fakeUAA   *fakes.FakeUAA
+		fakeToken string
+
+		fakeRLPGateway *firehosefakes.FakeRLPGateway
+
+		authTokenRefresher *uaatokenrefresher.UAATokenRefresher
+
+		metricsExpiration      time.Duration
+		metricsCleanupInterval time.Duration
+		deploymentFilter       *filters.DeploymentFilter
+		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
+		metricsStore           *metrics.Store
+
+		rlpGatewayURLs   []string
+		failbackInterval time.Duration
+		rlpGatewayNozzle *RLPGatewayNozzle
+		startErr         chan error
+
+		envelopeTags = `"tags":{"origin":"fake-origin","deployment":"fake-deployment-name","job":"fake-job-name","index":"0","ip":"1.2.3.4"}`
+	)
+
+	BeforeEach(func() {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+		proxy = nil
+		subscriptionID = "fake-subscription-id"
+		idleTimeoutSeconds = 5
+		reconnectMinBackoff = 1 * time.Minute
+		reconnectMaxBackoff = 1 * time.Minute
+		reconnectMaxRetries = 0
+
+		fakeUAA = fakes.NewFakeUAA("bearer", "123456789")
+		fakeToken = fakeUAA.AuthToken()
+		fakeUAA.Start()
+
+		fakeRLPGateway = firehosefakes.NewFakeRLPGateway(fakeToken)
+		fakeRLPGateway.Start()
+		rlpGatewayURLs = []string{fakeRLPGateway.URL()}
+		failbackInterval = 1 * time.Minute
+
+		authTokenRefresher, _ = uaatokenrefresher.New(
+			fakeUAA.URL(), "client-id", "client-secret", tlsConfig, proxy,
+		)
+
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = metrics.NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+	})
+
+	JustBeforeEach(func() {
+		rlpGatewayNozzle = NewRLPGatewayNozzle(
+			rlpGatewayURLs,
+			failbackInterval,
+			tlsConfig,
+			proxy,
+			subscriptionID,
+			idleTimeoutSeconds,
+			reconnectMinBackoff,
+			reconnectMaxBackoff,
+			reconnectMaxRetries,
+			authTokenRefresher,
+			metricsStore,
+		)
+		startErr = make(chan error, 1)
+		go func() {
+			startErr <- rlpGatewayNozzle.Start()
+		}()
+	})
+
+	AfterEach(func() {
+		fakeRLPGateway.Close()
+		fakeUAA.Close()
+	})
+
+	It("requests all envelope types for the subscription", func() {
+		Eventually(fakeRLPGateway.Requested).Should(BeTrue())
+		Expect(fakeRLPGateway.LastAuthorization()).To(Equal(fakeToken))
+
+		query := fakeRLPGateway.LastQuery()
+		Expect(query.Get("shard_id")).To(Equal(subscriptionID))
+		for _, envelopeType := range []string{"log", "counter", "gauge", "timer", "event"} {
+			Expect(query).To(HaveKey(envelopeType))
+		}
+	})
+
+	Context("when receives a gauge envelope", func() {
+		BeforeEach(func() {
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-origin",` + envelopeTags + `,"gauge":{"metrics":{"fake-metric-1":{"unit":"counter","value":1},"fake-metric-2":{"unit":"counter","value":2}}}}`)
+		})
+
+		It("adds a value metric per gauge metric", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalValueMetricsProcessed }).Should(Equal(int64(2)))
+
+			valueMetrics := metricsStore.GetValueMetrics()
+			Expect(valueMetrics).To(HaveLen(2))
+			Expect(valueMetrics).To(ContainElement(metrics.ValueMetric{
+				Origin:     "fake-origin",
+				Timestamp:  1500000000000000000,
+				Deployment: "fake-deployment-name",
+				Job:        "fake-job-name",
+				Index:      "0",
+				IP:         "1.2.3.4",
+				Tags:       map[string]string{},
+				Name:       "fake-metric-1",
+				Value:      1,
+				Unit:       "counter",
+			}))
+		})
+	})
+
+	Context("when receives a container gauge envelope", func() {
+		BeforeEach(func() {
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-app-id","instance_id":"1",` + envelopeTags + `,"gauge":{"metrics":{"cpu":{"unit":"percentage","value":0.5},"memory":{"unit":"bytes","value":1024},"disk":{"unit":"bytes","value":2048},"memory_quota":{"unit":"bytes","value":4096},"disk_quota":{"unit":"bytes","value":8192}}}}`)
+		})
+
+		It("adds a container metric", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalContainerMetricsProcessed }).Should(Equal(int64(1)))
+
+			containerMetrics := metricsStore.GetContainerMetrics()
+			Expect(containerMetrics).To(HaveLen(1))
+			Expect(containerMetrics[0].ApplicationId).To(Equal("fake-app-id"))
+			Expect(containerMetrics[0].InstanceIndex).To(Equal(int32(1)))
+			Expect(containerMetrics[0].CpuPercentage).To(Equal(0.5))
+			Expect(containerMetrics[0].MemoryBytes).To(Equal(uint64(1024)))
+			Expect(containerMetrics[0].DiskBytes).To(Equal(uint64(2048)))
+			Expect(containerMetrics[0].MemoryBytesQuota).To(Equal(uint64(4096)))
+			Expect(containerMetrics[0].DiskBytesQuota).To(Equal(uint64(8192)))
+		})
+
+		Context("and the envelope carries extra gauges", func() {
+			BeforeEach(func() {
+				fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-app-id-2","instance_id":"0",` + envelopeTags + `,"gauge":{"metrics":{"cpu":{"unit":"percentage","value":0.5},"memory":{"unit":"bytes","value":1024},"disk":{"unit":"bytes","value":2048},"memory_quota":{"unit":"bytes","value":4096},"disk_quota":{"unit":"bytes","value":8192},"log_rate":{"unit":"B/s","value":10},"log_rate_limit":{"unit":"B/s","value":-1}}}}`)
+			})
+
+			It("still adds a container metric", func() {
+				Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalContainerMetricsProcessed }).Should(Equal(int64(2)))
+				Expect(metricsStore.GetInternalMetrics().TotalValueMetricsProcessed).To(Equal(int64(0)))
+				Expect(metricsStore.GetContainerMetrics()).To(HaveLen(2))
+			})
+		})
+	})
+
+	Context("when receives a counter envelope", func() {
+		BeforeEach(func() {
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-origin",` + envelopeTags + `,"counter":{"name":"fake-counter","delta":"5","total":"50"}}`)
+		})
+
+		It("adds a counter event", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalCounterEventsProcessed }).Should(Equal(int64(1)))
+
+			counterEvents := metricsStore.GetCounterEvents()
+			Expect(counterEvents).To(HaveLen(1))
+			Expect(counterEvents[0].Name).To(Equal("fake-counter"))
+			Expect(counterEvents[0].Delta).To(Equal(uint64(5)))
+			Expect(counterEvents[0].Total).To(Equal(uint64(50)))
+		})
+	})
+
+	Context("when receives a timer envelope", func() {
+		BeforeEach(func() {
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"f47ac10b-58cc-4372-a567-0e02b2c3d479","instance_id":"0","tags":{"origin":"gorouter","method":"GET","uri":"https://example.com/v2/apps","status_code":"200","content_length":"42","peer_type":"Client"},"timer":{"name":"http","start":"1500000000000000000","stop":"1500000000500000000"}}`)
+		})
+
+		It("adds a http start stop", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalHttpStartStopsProcessed }).Should(Equal(int64(1)))
+
+			httpStartStops := metricsStore.GetHttpStartStops()
+			Expect(httpStartStops).To(HaveLen(1))
+			Expect(httpStartStops[0].ApplicationId).To(Equal("f47ac10b-58cc-4372-a567-0e02b2c3d479"))
+			Expect(httpStartStops[0].Method).To(Equal("GET"))
+			Expect(httpStartStops[0].Requests).To(Equal(map[string]uint64{"2xx": 1}))
+			Expect(httpStartStops[0].DurationSum).To(Equal(0.5))
+		})
+	})
+
+	Context("when receives log and event envelopes", func() {
+		BeforeEach(func() {
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-app-id","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"ZmFrZS1sb2c=","type":"ERR"}}`)
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-app-id","event":{"title":"fake-title","body":"fake-body"}}`)
+		})
+
+		It("adds log messages", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalLogMessagesProcessed }).Should(Equal(int64(2)))
+
+			logMessages := metricsStore.GetLogMessages()
+			Expect(logMessages).To(HaveLen(2))
+			Expect(logMessages).To(ContainElement(metrics.LogMessage{
+				Timestamp:     1500000000000000000,
+				ApplicationId: "fake-app-id",
+				SourceType:    "APP/PROC/WEB",
+				MessageType:   "ERR",
+				Messages:      1,
+				Bytes:         8,
+			}))
+			Expect(logMessages).To(ContainElement(metrics.LogMessage{
+				Timestamp:     1500000000000000000,
+				ApplicationId: "fake-app-id",
+				SourceType:    "EVENT",
+				MessageType:   "OUT",
+				Messages:      1,
+				Bytes:         21,
+			}))
+		})
+	})
+
+	Context("when the RLP Gateway closes the stream", func() {
+		BeforeEach(func() {
+			reconnectMinBackoff = 10 * time.Millisecond
+			reconnectMaxBackoff = 10 * time.Millisecond
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-origin",` + envelopeTags + `,"counter":{"name":"fake-counter","delta":"1","total":"1"}}`)
+		})
+
+		It("reconnects to the RLP Gateway", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalFirehoseReconnects }).Should(BeNumerically(">=", 1))
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalCounterEventsReceived }).Should(BeNumerically(">=", 2))
+		})
+
+		It("accounts the connections, disconnects and decoded bytes", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalFirehoseConnections }).Should(BeNumerically(">=", 2))
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalFirehoseDisconnects[1000] }).Should(BeNumerically(">=", 1))
+			Expect(metricsStore.GetInternalMetrics().TotalEnvelopeBytesDecoded).To(BeNumerically(">", 0))
+		})
+	})
+
+	Context("when stopped", func() {
+		It("stops the nozzle", func() {
+			Eventually(fakeRLPGateway.Requested).Should(BeTrue())
+			rlpGatewayNozzle.Stop()
+			Eventually(startErr).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("when the preferred endpoint cannot be reached", func() {
+		var (
+			rejectingRLPGateway *firehosefakes.FakeRLPGateway
+		)
+
+		BeforeEach(func() {
+			rejectingRLPGateway = firehosefakes.NewFakeRLPGateway("bearer invalid-token")
+			rejectingRLPGateway.Start()
+
+			rlpGatewayURLs = []string{rejectingRLPGateway.URL(), rlpGatewayURLs[0]}
+			reconnectMinBackoff = 10 * time.Millisecond
+			reconnectMaxBackoff = 10 * time.Millisecond
+			fakeRLPGateway.AddEnvelope(`{"timestamp":"1500000000000000000","source_id":"fake-origin",` + envelopeTags + `,"counter":{"name":"fake-counter","delta":"1","total":"1"}}`)
+		})
+
+		AfterEach(func() {
+			rejectingRLPGateway.Close()
+		})
+
+		It("fails over to the next endpoint", func() {
+			Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalCounterEventsReceived }).Should(BeNumerically(">=", 1))
+			Expect(metricsStore.GetInternalMetrics().TotalDopplerEndpointFailovers).To(BeNumerically(">=", 1))
+		})
+
+		Context("and it is healthy again", func() {
+			BeforeEach(func() {
+				failbackInterval = 10 * time.Millisecond
+			})
+
+			It("fails back to the preferred endpoint", func() {
+				Eventually(func() int64 { return metricsStore.GetInternalMetrics().TotalDopplerEndpointFailovers }).Should(BeNumerically(">=", 2))
+			})
+		})
+	})
+
+	Context("when the token is rejected", func() {
+		BeforeEach(func() {
+			fakeRLPGateway.Close()
+			fakeRLPGateway = firehosefakes.NewFakeRLPGateway("bearer invalid-token")
+			fakeRLPGateway.Start()
+			rlpGatewayURLs = []string{fakeRLPGateway.URL()}
+			reconnectMinBackoff = 10 * time.Millisecond
+			reconnectMaxBackoff = 10 * time.Millisecond
+			reconnectMaxRetries = 2
+		})
+
+		It("gives up after the maximum number of retries", func() {
+			Eventually(startErr, 5*time.Second).Should(Receive(HaveOccurred()))
+			Expect(metricsStore.GetInternalMetrics().TotalFirehoseReconnects).To(Equal(int64(2)))
+		})
+	})
+})
@@ -0,0 +1,268 @@
+package firehosenozzle
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+)
+
+// The RLP Gateway encodes 64 bit integers as JSON strings.
+type jsonInt64 int64
+
+func (i *jsonInt64) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt64(value)
+	return nil
+}
+
+type jsonUint64 uint64
+
+func (i *jsonUint64) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseUint(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonUint64(value)
+	return nil
+}
+
+type v2EnvelopeBatch struct {
+	Batch []v2Envelope `json:"batch"`
+}
+
+type v2Envelope struct {
+	Timestamp  jsonInt64         `json:"timestamp"`
+	SourceId   string            `json:"source_id"`
+	InstanceId string            `json:"instance_id"`
+	Tags       map[string]string `json:"tags"`
+	Log        *v2Log            `json:"log"`
+	Counter    *v2Counter        `json:"counter"`
+	Gauge      *v2Gauge          `json:"gauge"`
+	Timer      *v2Timer          `json:"timer"`
+	Event      *v2Event          `json:"event"`
+}
+
+type v2Log struct {
+	Payload []byte `json:"payload"`
+	Type    string `json:"type"`
+}
+
+type v2Counter struct {
+	Name  string     `json:"name"`
+	Delta jsonUint64 `json:"delta"`
+	Total jsonUint64 `json:"total"`
+}
+
+type v2Gauge struct {
+	Metrics map[string]v2GaugeValue `json:"metrics"`
+}
+
+type v2GaugeValue struct {
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+}
+
+type v2Timer struct {
+	Name  string    `json:"name"`
+	Start jsonInt64 `json:"start"`
+	Stop  jsonInt64 `json:"stop"`
+}
+
+type v2Event struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+var (
+	v2EnvelopeTags = []string{"origin", "deployment", "job", "index", "ip"}
+
+	v2ContainerMetricGauges = []string{"cpu", "memory", "disk", "memory_quota", "disk_quota"}
+)
+
+// toV1Envelopes converts a v2 envelope into the v1 envelopes understood by the metrics store.
+func (e v2Envelope) toV1Envelopes() []*events.Envelope {
+	switch {
+	case e.Log != nil:
+		return []*events.Envelope{e.logMessageEnvelope()}
+	case e.Counter != nil:
+		return []*events.Envelope{e.counterEventEnvelope()}
+	case e.Gauge != nil:
+		if e.isContainerMetric() {
+			return []*events.Envelope{e.containerMetricEnvelope()}
+		}
+		return e.valueMetricEnvelopes()
+	case e.Timer != nil:
+		return []*events.Envelope{e.httpStartStopEnvelope()}
+	case e.Event != nil:
+		return []*events.Envelope{e.eventEnvelope()}
+	}
+
+	return []*events.Envelope{}
+}
+
+func (e v2Envelope) newV1Envelope(eventType events.Envelope_EventType) *events.Envelope {
+	tags := map[string]string{}
+	for key, value := range e.Tags {
+		tags[key] = value
+	}
+	for _, key := range v2EnvelopeTags {
+		delete(tags, key)
+	}
+
+	origin := e.Tags["origin"]
+	if origin == "" {
+		origin = e.SourceId
+	}
+
+	return &events.Envelope{
+		Origin:     proto.String(origin),
+		EventType:  eventType.Enum(),
+		Timestamp:  proto.Int64(int64(e.Timestamp)),
+		Deployment: proto.String(e.Tags["deployment"]),
+		Job:        proto.String(e.Tags["job"]),
+		Index:      proto.String(e.Tags["index"]),
+		Ip:         proto.String(e.Tags["ip"]),
+		Tags:       tags,
+	}
+}
+
+func (e v2Envelope) logMessageEnvelope() *events.Envelope {
+	messageType := events.LogMessage_OUT
+	if e.Log.Type == "ERR" {
+		messageType = events.LogMessage_ERR
+	}
+
+	envelope := e.newV1Envelope(events.Envelope_LogMessage)
+	envelope.LogMessage = &events.LogMessage{
+		Message:        e.Log.Payload,
+		MessageType:    messageType.Enum(),
+		Timestamp:      proto.Int64(int64(e.Timestamp)),
+		AppId:          proto.String(e.SourceId),
+		SourceType:     proto.String(e.Tags["source_type"]),
+		SourceInstance: proto.String(e.InstanceId),
+	}
+
+	return envelope
+}
+
+// Events have no v1 equivalent, they are accounted as log messages with an `EVENT` source type.
+func (e v2Envelope) eventEnvelope() *events.Envelope {
+	envelope := e.newV1Envelope(events.Envelope_LogMessage)
+	envelope.LogMessage = &events.LogMessage{
+		Message:        []byte(e.Event.Title + ": " + e.Event.Body),
+		MessageType:    events.LogMessage_OUT.Enum(),
+		Timestamp:      proto.Int64(int64(e.Timestamp)),
+		AppId:          proto.String(e.SourceId),
+		SourceType:     proto.String("EVENT"),
+		SourceInstance: proto.String(e.InstanceId),
+	}
+
+	return envelope
+}
+
+func (e v2Envelope) counterEventEnvelope() *events.Envelope {
+	envelope := e.newV1Envelope(events.Envelope_CounterEvent)
+	envelope.CounterEvent = &events.CounterEvent{
+		Name:  proto.String(e.Counter.Name),
+		Delta: proto.Uint64(uint64(e.Counter.Delta)),
+		Total: proto.Uint64(uint64(e.Counter.Total)),
+	}
+
+	return envelope
+}
+
+func (e v2Envelope) isContainerMetric() bool {
+	for _, name := range v2ContainerMetricGauges {
+		if _, ok := e.Gauge.Metrics[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e v2Envelope) containerMetricEnvelope() *events.Envelope {
+	instanceIndex, _ := strconv.Atoi(e.InstanceId)
+
+	envelope := e.newV1Envelope(events.Envelope_ContainerMetric)
+	envelope.ContainerMetric = &events.ContainerMetric{
+		ApplicationId:    proto.String(e.SourceId),
+		InstanceIndex:    proto.Int32(int32(instanceIndex)),
+		CpuPercentage:    proto.Float64(e.Gauge.Metrics["cpu"].Value),
+		MemoryBytes:      proto.Uint64(uint64(e.Gauge.Metrics["memory"].Value)),
+		DiskBytes:        proto.Uint64(uint64(e.Gauge.Metrics["disk"].Value)),
+		MemoryBytesQuota: proto.Uint64(uint64(e.Gauge.Metrics["memory_quota"].Value)),
+		DiskBytesQuota:   proto.Uint64(uint64(e.Gauge.Metrics["disk_quota"].Value)),
+	}
+
+	return envelope
+}
+
+func (e v2Envelope) valueMetricEnvelopes() []*events.Envelope {
+	names := make([]string, 0, len(e.Gauge.Metrics))
+	for name := range e.Gauge.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	envelopes := make([]*events.Envelope, 0, len(names))
+	for _, name := range names {
+		envelope := e.newV1Envelope(events.Envelope_ValueMetric)
+		envelope.ValueMetric = &events.ValueMetric{
+			Name:  proto.String(name),
+			Value: proto.Float64(e.Gauge.Metrics[name].Value),
+			Unit:  proto.String(e.Gauge.Metrics[name].Unit),
+		}
+		envelopes = append(envelopes, envelope)
+	}
+
+	return envelopes
+}
+
+func (e v2Envelope) httpStartStopEnvelope() *events.Envelope {
+	peerType := events.PeerType_Server
+	if strings.ToLower(e.Tags["peer_type"]) == "client" {
+		peerType = events.PeerType_Client
+	}
+
+	method := events.Method_GET
+	if value, ok := events.Method_value[strings.ToUpper(e.Tags["method"])]; ok {
+		method = events.Method(value)
+	}
+
+	statusCode, _ := strconv.Atoi(e.Tags["status_code"])
+	contentLength, _ := strconv.ParseInt(e.Tags["content_length"], 10, 64)
+	instanceIndex, _ := strconv.Atoi(e.InstanceId)
+
+	requestId := utils.ParseUUID(e.Tags["request_id"])
+	if requestId == nil {
+		requestId = &events.UUID{Low: proto.Uint64(0), High: proto.Uint64(0)}
+	}
+
+	envelope := e.newV1Envelope(events.Envelope_HttpStartStop)
+	envelope.HttpStartStop = &events.HttpStartStop{
+		StartTimestamp: proto.Int64(int64(e.Timer.Start)),
+		StopTimestamp:  proto.Int64(int64(e.Timer.Stop)),
+		RequestId:      requestId,
+		PeerType:       peerType.Enum(),
+		Method:         method.Enum(),
+		Uri:            proto.String(e.Tags["uri"]),
+		RemoteAddress:  proto.String(e.Tags["remote_address"]),
+		UserAgent:      proto.String(e.Tags["user_agent"]),
+		StatusCode:     proto.Int32(int32(statusCode)),
+		ContentLength:  proto.Int64(contentLength),
+		ApplicationId:  utils.ParseUUID(e.SourceId),
+		InstanceIndex:  proto.Int32(int32(instanceIndex)),
+		InstanceId:     proto.String(e.InstanceId),
+	}
+
+	return envelope
+}
@@ -0,0 +1,99 @@
+package foundations
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Foundation holds the settings to consume the Firehose of a Cloud Foundry environment.
+type Foundation struct {
+	Name                  string   `yaml:"name"`
+	UAAURL                string   `yaml:"uaa_url"`
+	UAAClientID           string   `yaml:"uaa_client_id"`
+	UAAClientSecret       string   `yaml:"uaa_client_secret"`
+	DopplerURL            string   `yaml:"doppler_url"`
+	DopplerAPIVersion     string   `yaml:"doppler_api_version"`
+	DopplerSubscriptionID string   `yaml:"doppler_subscription_id"`
+	DopplerDeployments    []string `yaml:"doppler_deployments"`
+	DopplerEvents         []string `yaml:"doppler_events"`
+	ProxyURL              string   `yaml:"proxy_url"`
+	CACertFile            string   `yaml:"ca_cert_file"`
+	ClientCertFile        string   `yaml:"client_cert_file"`
+	ClientKeyFile         string   `yaml:"client_key_file"`
+	SkipSSLValidation     bool     `yaml:"skip_ssl_verify"`
+}
+
+type foundationsFile struct {
+	Foundations []Foundation `yaml:"foundations"`
+}
+
+// Load reads the foundations listed in a YAML file.
+func Load(path string, defaults Foundation) ([]Foundation, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data, defaults)
+}
+
+// Parse decodes a YAML list of foundations. The doppler API version, subscription ID, deployments,
+// events, proxy, CA certificate and client certificate a foundation does not set are taken from defaults.
+func Parse(data []byte, defaults Foundation) ([]Foundation, error) {
+	file := foundationsFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if len(file.Foundations) == 0 {
+		return nil, errors.New("No foundations configured")
+	}
+
+	names := map[string]bool{}
+	for i := range file.Foundations {
+		foundation := &file.Foundations[i]
+
+		if foundation.Name == "" {
+			return nil, fmt.Errorf("Foundation #%d has no name", i+1)
+		}
+		if names[foundation.Name] {
+			return nil, fmt.Errorf("Foundation `%s` is configured more than once", foundation.Name)
+		}
+		names[foundation.Name] = true
+
+		if foundation.UAAURL == "" {
+			return nil, fmt.Errorf("Foundation `%s` has no uaa_url", foundation.Name)
+		}
+		if foundation.DopplerURL == "" {
+			return nil, fmt.Errorf("Foundation `%s` has no doppler_url", foundation.Name)
+		}
+
+		if foundation.DopplerAPIVersion == "" {
+			foundation.DopplerAPIVersion = defaults.DopplerAPIVersion
+		}
+		if foundation.DopplerSubscriptionID == "" {
+			foundation.DopplerSubscriptionID = defaults.DopplerSubscriptionID
+		}
+		if foundation.DopplerDeployments == nil {
+			foundation.DopplerDeployments = defaults.DopplerDeployments
+		}
+		if foundation.DopplerEvents == nil {
+			foundation.DopplerEvents = defaults.DopplerEvents
+		}
+		if foundation.ProxyURL == "" {
+			foundation.ProxyURL = defaults.ProxyURL
+		}
+		if foundation.CACertFile == "" {
+			foundation.CACertFile = defaults.CACertFile
+		}
+		if foundation.ClientCertFile == "" && foundation.ClientKeyFile == "" {
+			foundation.ClientCertFile = defaults.ClientCertFile
+			foundation.ClientKeyFile = defaults.ClientKeyFile
+		}
+	}
+
+	return file.Foundations, nil
+}
@@ -0,0 +1,13 @@
+package foundations_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFoundations(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Foundations Suite")
+}
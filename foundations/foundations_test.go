@@ -0,0 +1,166 @@
+package foundations_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/foundations"
+)
+
+var _ = Describe("Foundations", func() {
+	var (
+		defaults Foundation
+	)
+
+	BeforeEach(func() {
+		defaults = Foundation{
+			DopplerAPIVersion:     "v1",
+			DopplerSubscriptionID: "prometheus",
+			DopplerEvents:         []string{"ValueMetric"},
+			ProxyURL:              "http://proxy.example.com:3128",
+			CACertFile:            "/etc/ssl/ca.pem",
+			ClientCertFile:        "/etc/ssl/client.pem",
+			ClientKeyFile:         "/etc/ssl/client.key",
+		}
+	})
+
+	Describe("Parse", func() {
+		It("parses the foundations", func() {
+			foundations, err := Parse([]byte(`
+foundations:
+- name: dev
+  uaa_url: https://uaa.dev.example.com
+  uaa_client_id: dev-client
+  uaa_client_secret: dev-secret
+  doppler_url: wss://doppler.dev.example.com
+  doppler_deployments: [cf]
+  ca_cert_file: /etc/ssl/dev-ca.pem
+  client_cert_file: /etc/ssl/dev-client.pem
+  client_key_file: /etc/ssl/dev-client.key
+  skip_ssl_verify: true
+- name: prod
+  uaa_url: https://uaa.prod.example.com
+  uaa_client_id: prod-client
+  uaa_client_secret: prod-secret
+  doppler_url: https://log-stream.prod.example.com
+  doppler_api_version: v2
+  doppler_subscription_id: prod-exporter
+  doppler_events: [CounterEvent]
+`), defaults)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(foundations).To(Equal([]Foundation{
+				{
+					Name:                  "dev",
+					UAAURL:                "https://uaa.dev.example.com",
+					UAAClientID:           "dev-client",
+					UAAClientSecret:       "dev-secret",
+					DopplerURL:            "wss://doppler.dev.example.com",
+					DopplerAPIVersion:     "v1",
+					DopplerSubscriptionID: "prometheus",
+					DopplerDeployments:    []string{"cf"},
+					DopplerEvents:         []string{"ValueMetric"},
+					ProxyURL:              "http://proxy.example.com:3128",
+					CACertFile:            "/etc/ssl/dev-ca.pem",
+					ClientCertFile:        "/etc/ssl/dev-client.pem",
+					ClientKeyFile:         "/etc/ssl/dev-client.key",
+					SkipSSLValidation:     true,
+				},
+				{
+					Name:                  "prod",
+					UAAURL:                "https://uaa.prod.example.com",
+					UAAClientID:           "prod-client",
+					UAAClientSecret:       "prod-secret",
+					DopplerURL:            "https://log-stream.prod.example.com",
+					DopplerAPIVersion:     "v2",
+					DopplerSubscriptionID: "prod-exporter",
+					DopplerEvents:         []string{"CounterEvent"},
+					ProxyURL:              "http://proxy.example.com:3128",
+					CACertFile:            "/etc/ssl/ca.pem",
+					ClientCertFile:        "/etc/ssl/client.pem",
+					ClientKeyFile:         "/etc/ssl/client.key",
+				},
+			}))
+		})
+
+		It("returns an error when there are no foundations", func() {
+			_, err := Parse([]byte(`foundations: []`), defaults)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error when a foundation has no name", func() {
+			_, err := Parse([]byte(`
+foundations:
+- uaa_url: https://uaa.dev.example.com
+  doppler_url: wss://doppler.dev.example.com
+`), defaults)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error when a foundation is configured twice", func() {
+			_, err := Parse([]byte(`
+foundations:
+- name: dev
+  uaa_url: https://uaa.dev.example.com
+  doppler_url: wss://doppler.dev.example.com
+- name: dev
+  uaa_url: https://uaa.dev.example.com
+  doppler_url: wss://doppler.dev.example.com
+`), defaults)
+			Expect(err).To(MatchError(ContainSubstring("more than once")))
+		})
+
+		It("returns an error when a foundation has no doppler_url", func() {
+			_, err := Parse([]byte(`
+foundations:
+- name: dev
+  uaa_url: https://uaa.dev.example.com
+`), defaults)
+			Expect(err).To(MatchError(ContainSubstring("doppler_url")))
+		})
+
+		It("returns an error when the YAML is invalid", func() {
+			_, err := Parse([]byte(`foundations: {`), defaults)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Load", func() {
+		var (
+			dir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "foundations")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("reads the foundations file", func() {
+			path := filepath.Join(dir, "foundations.yml")
+			Expect(ioutil.WriteFile(path, []byte(`
+foundations:
+- name: dev
+  uaa_url: https://uaa.dev.example.com
+  doppler_url: wss://doppler.dev.example.com
+`), 0644)).To(Succeed())
+
+			foundations, err := Load(path, defaults)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(foundations).To(HaveLen(1))
+			Expect(foundations[0].Name).To(Equal("dev"))
+		})
+
+		It("returns an error when the file does not exist", func() {
+			_, err := Load(filepath.Join(dir, "missing.yml"), defaults)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	dialTimeout           = 30 * time.Second
+	tlsHandshakeTimeout   = 10 * time.Second
+	responseHeaderTimeout = 30 * time.Second
+)
+
+// Config holds the proxy and TLS settings used to connect to Cloud Foundry.
+type Config struct {
+	ProxyURL          string
+	CACertFile        string
+	ClientCertFile    string
+	ClientKeyFile     string
+	SkipSSLValidation bool
+}
+
+// TLSConfig trusts the CA certificates in CACertFile on top of the system ones, and presents the
+// ClientCertFile and ClientKeyFile key pair when set.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.SkipSSLValidation}
+
+	if c.CACertFile != "" {
+		caCerts, err := ioutil.ReadFile(c.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caCerts) {
+			return nil, fmt.Errorf("No PEM certificates found in `%s`", c.CACertFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		return nil, errors.New("Client certificate and key must be set together")
+	}
+	if c.ClientCertFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Proxy returns a proxy function sending every request through ProxyURL, or honoring the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables when not set.
+func (c Config) Proxy() (func(*http.Request) (*url.URL, error), error) {
+	if c.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("Proxy URL `%s` must include a scheme and a host", c.ProxyURL)
+	}
+
+	return http.ProxyURL(proxyURL), nil
+}
+
+// New returns an HTTP client using the proxy and TLS settings. Requests are aborted after timeout, 0
+// meaning no limit, which only suits streamed responses: connecting and waiting for the response
+// headers are always limited.
+func New(tlsConfig *tls.Config, proxy func(*http.Request) (*url.URL, error), timeout time.Duration) *http.Client {
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 proxy,
+			TLSClientConfig:       tlsConfig,
+			DialContext:           (&net.Dialer{Timeout: dialTimeout}).DialContext,
+			TLSHandshakeTimeout:   tlsHandshakeTimeout,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+		},
+	}
+}
@@ -0,0 +1,13 @@
+package httpclient_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHttpclient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Httpclient Suite")
+}
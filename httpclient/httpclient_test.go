@@ -0,0 +1,213 @@
+package httpclient_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/httpclient"
+)
+
+func writeKeyPair(dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	Expect(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)).To(Succeed())
+
+	return certFile, keyFile
+}
+
+var _ = Describe("Config", func() {
+	var (
+		err      error
+		tmpDir   string
+		certFile string
+		keyFile  string
+		config   Config
+	)
+
+	BeforeEach(func() {
+		tmpDir, err = ioutil.TempDir("", "httpclient_test")
+		Expect(err).ToNot(HaveOccurred())
+		certFile, keyFile = writeKeyPair(tmpDir)
+
+		config = Config{}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("TLSConfig", func() {
+		var (
+			tlsConfig *tls.Config
+		)
+
+		JustBeforeEach(func() {
+			tlsConfig, err = config.TLSConfig()
+		})
+
+		It("uses the system CAs and no client certificate", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tlsConfig.InsecureSkipVerify).To(BeFalse())
+			Expect(tlsConfig.RootCAs).To(BeNil())
+			Expect(tlsConfig.Certificates).To(BeEmpty())
+		})
+
+		Context("when skipping SSL validation", func() {
+			BeforeEach(func() {
+				config.SkipSSLValidation = true
+			})
+
+			It("does not verify the server certificate", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tlsConfig.InsecureSkipVerify).To(BeTrue())
+			})
+		})
+
+		Context("when a CA certificate file is set", func() {
+			BeforeEach(func() {
+				config.CACertFile = certFile
+			})
+
+			It("trusts the CA certificate", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tlsConfig.RootCAs).ToNot(BeNil())
+			})
+
+			Context("and it does not contain any certificate", func() {
+				BeforeEach(func() {
+					config.CACertFile = keyFile
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(MatchError("No PEM certificates found in `" + keyFile + "`"))
+				})
+			})
+
+			Context("and it does not exist", func() {
+				BeforeEach(func() {
+					config.CACertFile = filepath.Join(tmpDir, "missing.pem")
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when a client certificate and key are set", func() {
+			BeforeEach(func() {
+				config.ClientCertFile = certFile
+				config.ClientKeyFile = keyFile
+			})
+
+			It("presents the client certificate", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tlsConfig.Certificates).To(HaveLen(1))
+			})
+		})
+
+		Context("when only a client certificate is set", func() {
+			BeforeEach(func() {
+				config.ClientCertFile = certFile
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError("Client certificate and key must be set together"))
+			})
+		})
+	})
+
+	Describe("Proxy", func() {
+		var (
+			request *http.Request
+		)
+
+		BeforeEach(func() {
+			request, err = http.NewRequest("GET", "https://uaa.example.com/oauth/token", nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when a proxy URL is set", func() {
+			BeforeEach(func() {
+				config.ProxyURL = "http://proxy.example.com:3128"
+			})
+
+			It("sends the requests through the proxy", func() {
+				proxy, err := config.Proxy()
+				Expect(err).ToNot(HaveOccurred())
+
+				proxyURL, err := proxy(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(proxyURL.String()).To(Equal("http://proxy.example.com:3128"))
+			})
+		})
+
+		Context("when the proxy URL has no scheme", func() {
+			BeforeEach(func() {
+				config.ProxyURL = "proxy.example.com"
+			})
+
+			It("returns an error", func() {
+				_, err := config.Proxy()
+				Expect(err).To(MatchError("Proxy URL `proxy.example.com` must include a scheme and a host"))
+			})
+		})
+	})
+})
+
+var _ = Describe("New", func() {
+	It("uses the TLS config", func() {
+		tlsConfig := &tls.Config{InsecureSkipVerify: true}
+		client := New(tlsConfig, nil, 0)
+
+		transport, ok := client.Transport.(*http.Transport)
+		Expect(ok).To(BeTrue())
+		Expect(transport.TLSClientConfig).To(Equal(tlsConfig))
+		Expect(transport.Proxy).ToNot(BeNil())
+	})
+
+	It("sets the timeouts", func() {
+		client := New(nil, nil, 5*time.Second)
+		Expect(client.Timeout).To(Equal(5 * time.Second))
+
+		transport, ok := client.Transport.(*http.Transport)
+		Expect(ok).To(BeTrue())
+		Expect(transport.DialContext).ToNot(BeNil())
+		Expect(transport.TLSHandshakeTimeout).To(BeNumerically(">", 0))
+		Expect(transport.ResponseHeaderTimeout).To(BeNumerically(">", 0))
+	})
+})
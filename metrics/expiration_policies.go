@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/patrickmn/go-cache"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+)
+
+// ExpirationPolicies decides how long a metric is kept after its last update: a default per event
+// type, unless an override matching the envelope origin, deployment, metric name or event type applies.
+type ExpirationPolicies struct {
+	defaults  map[events.Envelope_EventType]time.Duration
+	overrides []expirationOverride
+}
+
+type expirationOverride struct {
+	origin     *filters.PatternFilter
+	deployment *filters.PatternFilter
+	metricName *filters.PatternFilter
+	eventTypes map[events.Envelope_EventType]bool
+	expiration time.Duration
+}
+
+type expirationPoliciesFile struct {
+	Defaults  map[string]expirationDuration `yaml:"defaults"`
+	Overrides []struct {
+		Origin     string             `yaml:"origin"`
+		Deployment string             `yaml:"deployment"`
+		MetricName string             `yaml:"metric_name"`
+		EventTypes []string           `yaml:"event_types"`
+		Expiration expirationDuration `yaml:"expiration"`
+	} `yaml:"overrides"`
+}
+
+// expirationDuration is a duration like `10m`, or `never`.
+type expirationDuration time.Duration
+
+func (d *expirationDuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+
+	if value == "never" {
+		*d = expirationDuration(cache.NoExpiration)
+		return nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return fmt.Errorf("Expiration `%s` must be positive or `never`", value)
+	}
+
+	*d = expirationDuration(duration)
+	return nil
+}
+
+// NewExpirationPolicies expires Container Metrics, Http Start Stops and Log Messages after
+// metricsExpiration, and never expires Counter Events, Value Metrics and Errors.
+func NewExpirationPolicies(metricsExpiration time.Duration) *ExpirationPolicies {
+	if metricsExpiration <= 0 {
+		metricsExpiration = cache.NoExpiration
+	}
+
+	return &ExpirationPolicies{
+		defaults: map[events.Envelope_EventType]time.Duration{
+			events.Envelope_ContainerMetric: metricsExpiration,
+			events.Envelope_CounterEvent:    cache.NoExpiration,
+			events.Envelope_ValueMetric:     cache.NoExpiration,
+			events.Envelope_HttpStartStop:   metricsExpiration,
+			events.Envelope_LogMessage:      metricsExpiration,
+			events.Envelope_Error:           cache.NoExpiration,
+		},
+	}
+}
+
+// LoadExpirationPolicies reads the expiration policies from a YAML file.
+func LoadExpirationPolicies(path string, metricsExpiration time.Duration) (*ExpirationPolicies, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseExpirationPolicies(data, metricsExpiration)
+}
+
+// ParseExpirationPolicies decodes YAML expiration policies. The event types without a default keep the
+// ones of NewExpirationPolicies.
+func ParseExpirationPolicies(data []byte, metricsExpiration time.Duration) (*ExpirationPolicies, error) {
+	file := expirationPoliciesFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	policies := NewExpirationPolicies(metricsExpiration)
+	for eventName, expiration := range file.Defaults {
+		eventType, err := parseExpirationEventName(eventName)
+		if err != nil {
+			return nil, err
+		}
+		policies.defaults[eventType] = time.Duration(expiration)
+	}
+
+	for i, o := range file.Overrides {
+		if o.Expiration == 0 {
+			return nil, fmt.Errorf("Expiration override #%d has no expiration", i+1)
+		}
+
+		patterns := []*filters.PatternFilter{}
+		for _, pattern := range []string{o.Origin, o.Deployment, o.MetricName} {
+			filter, err := filters.NewSinglePatternFilter(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("Expiration override #%d has an invalid pattern `%s`", i+1, pattern)
+			}
+			patterns = append(patterns, filter)
+		}
+
+		override := expirationOverride{
+			origin:     patterns[0],
+			deployment: patterns[1],
+			metricName: patterns[2],
+			eventTypes: map[events.Envelope_EventType]bool{},
+			expiration: time.Duration(o.Expiration),
+		}
+		for _, eventName := range o.EventTypes {
+			eventType, err := parseExpirationEventName(eventName)
+			if err != nil {
+				return nil, err
+			}
+			override.eventTypes[eventType] = true
+		}
+
+		policies.overrides = append(policies.overrides, override)
+	}
+
+	return policies, nil
+}
+
+// Expiration returns how long to keep the metric of an envelope, cache.NoExpiration meaning forever.
+// The first matching override applies.
+func (p *ExpirationPolicies) Expiration(envelope *events.Envelope) time.Duration {
+	for _, override := range p.overrides {
+		if override.matches(envelope) {
+			return override.expiration
+		}
+	}
+
+	if expiration, ok := p.defaults[envelope.GetEventType()]; ok {
+		return expiration
+	}
+	return cache.NoExpiration
+}
+
+func (o expirationOverride) matches(envelope *events.Envelope) bool {
+	if len(o.eventTypes) > 0 && !o.eventTypes[envelope.GetEventType()] {
+		return false
+	}
+
+	return o.origin.Enabled(envelope.GetOrigin()) &&
+		o.deployment.Enabled(envelope.GetDeployment()) &&
+		o.metricName.Enabled(envelopeMetricName(envelope))
+}
+
+func envelopeMetricName(envelope *events.Envelope) string {
+	switch envelope.GetEventType() {
+	case events.Envelope_CounterEvent:
+		return envelope.GetCounterEvent().GetName()
+	case events.Envelope_ValueMetric:
+		return envelope.GetValueMetric().GetName()
+	}
+	return ""
+}
+
+func parseExpirationEventName(name string) (events.Envelope_EventType, error) {
+	if eventType, ok := events.Envelope_EventType_value[name]; ok {
+		return events.Envelope_EventType(eventType), nil
+	}
+	return events.Envelope_Error, errors.New(fmt.Sprintf("Expiration event type `%s` is not supported", name))
+}
@@ -0,0 +1,162 @@
+package metrics_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/patrickmn/go-cache"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var _ = Describe("ExpirationPolicies", func() {
+	var (
+		metricsExpiration = 5 * time.Minute
+
+		envelope = func(eventType events.Envelope_EventType, origin string, deployment string, name string) *events.Envelope {
+			envelope := &events.Envelope{
+				Origin:     proto.String(origin),
+				EventType:  eventType.Enum(),
+				Timestamp:  proto.Int64(time.Now().UnixNano()),
+				Deployment: proto.String(deployment),
+				Job:        proto.String("fake-job-name"),
+				Index:      proto.String("0"),
+				Ip:         proto.String("1.2.3.4"),
+			}
+			switch eventType {
+			case events.Envelope_ValueMetric:
+				envelope.ValueMetric = &events.ValueMetric{Name: proto.String(name), Value: proto.Float64(1), Unit: proto.String("count")}
+			case events.Envelope_CounterEvent:
+				envelope.CounterEvent = &events.CounterEvent{Name: proto.String(name), Delta: proto.Uint64(1), Total: proto.Uint64(1)}
+			case events.Envelope_ContainerMetric:
+				envelope.ContainerMetric = &events.ContainerMetric{ApplicationId: proto.String("fake-app"), InstanceIndex: proto.Int32(0)}
+			}
+			return envelope
+		}
+	)
+
+	Describe("NewExpirationPolicies", func() {
+		var (
+			expirationPolicies *ExpirationPolicies
+		)
+
+		BeforeEach(func() {
+			expirationPolicies = NewExpirationPolicies(metricsExpiration)
+		})
+
+		It("expires Container Metrics after the metrics expiration", func() {
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ContainerMetric, "rep", "cf", ""))).To(Equal(metricsExpiration))
+		})
+
+		It("never expires Counter Events and Value Metrics", func() {
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_CounterEvent, "gorouter", "cf", "total_requests"))).To(Equal(cache.NoExpiration))
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ValueMetric, "gorouter", "cf", "latency"))).To(Equal(cache.NoExpiration))
+		})
+	})
+
+	Describe("ParseExpirationPolicies", func() {
+		var (
+			expirationPolicies *ExpirationPolicies
+			err                error
+		)
+
+		BeforeEach(func() {
+			expirationPolicies, err = ParseExpirationPolicies([]byte(`
+defaults:
+  ValueMetric: 1h
+  CounterEvent: 2h
+overrides:
+- deployment: service-instance_*
+  expiration: 10m
+- origin: gorouter
+  metric_name: latency.*
+  event_types: [ValueMetric]
+  expiration: never
+`), metricsExpiration)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("applies the defaults per event type", func() {
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ValueMetric, "gorouter", "cf", "total_routes"))).To(Equal(1 * time.Hour))
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_CounterEvent, "gorouter", "cf", "total_requests"))).To(Equal(2 * time.Hour))
+		})
+
+		It("keeps the built-in defaults of the other event types", func() {
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ContainerMetric, "rep", "cf", ""))).To(Equal(metricsExpiration))
+		})
+
+		It("applies the overrides matching the deployment", func() {
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ValueMetric, "redis", "service-instance_1234", "memory"))).To(Equal(10 * time.Minute))
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ContainerMetric, "rep", "service-instance_1234", ""))).To(Equal(10 * time.Minute))
+		})
+
+		It("applies the overrides matching the origin, metric name and event type", func() {
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ValueMetric, "gorouter", "cf", "latency.uaa"))).To(Equal(cache.NoExpiration))
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_CounterEvent, "gorouter", "cf", "latency.uaa"))).To(Equal(2 * time.Hour))
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ValueMetric, "rep", "cf", "latency.uaa"))).To(Equal(1 * time.Hour))
+		})
+
+		It("applies the overrides matching a regular expression", func() {
+			expirationPolicies, err := ParseExpirationPolicies([]byte(`overrides: [{origin: "/gorouter|uaa/", expiration: 10m}]`), metricsExpiration)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ValueMetric, "uaa", "cf", "requests"))).To(Equal(10 * time.Minute))
+			Expect(expirationPolicies.Expiration(envelope(events.Envelope_ValueMetric, "rep", "cf", "requests"))).ToNot(Equal(10 * time.Minute))
+		})
+
+		It("returns an error when an event type is not supported", func() {
+			_, err := ParseExpirationPolicies([]byte(`defaults: {FakeEvent: 1h}`), metricsExpiration)
+			Expect(err).To(MatchError("Expiration event type `FakeEvent` is not supported"))
+		})
+
+		It("returns an error when an expiration is invalid", func() {
+			_, err := ParseExpirationPolicies([]byte(`defaults: {ValueMetric: 0s}`), metricsExpiration)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error when an override has no expiration", func() {
+			_, err := ParseExpirationPolicies([]byte(`overrides: [{origin: gorouter}]`), metricsExpiration)
+			Expect(err).To(MatchError("Expiration override #1 has no expiration"))
+		})
+
+		It("returns an error when a pattern is invalid", func() {
+			_, err := ParseExpirationPolicies([]byte(`overrides: [{origin: "[", expiration: 1m}]`), metricsExpiration)
+			Expect(err).To(MatchError("Expiration override #1 has an invalid pattern `[`"))
+		})
+	})
+
+	Describe("Store", func() {
+		var (
+			metricsStore *Store
+		)
+
+		BeforeEach(func() {
+			deploymentFilter := filters.NewDeploymentFilter([]string{})
+			eventFilter, _ := filters.NewEventFilter([]string{})
+			routeTemplates, _ := utils.NewRouteTemplates([]string{})
+			metricsStore = NewStore(metricsExpiration, 0, deploymentFilter, eventFilter, routeTemplates)
+
+			expirationPolicies, err := ParseExpirationPolicies([]byte(`
+overrides:
+- deployment: service-instance_*
+  expiration: 20ms
+`), metricsExpiration)
+			Expect(err).ToNot(HaveOccurred())
+			metricsStore.SetExpirationPolicies(expirationPolicies)
+
+			metricsStore.AddMetric(envelope(events.Envelope_ValueMetric, "redis", "service-instance_1234", "memory"))
+			metricsStore.AddMetric(envelope(events.Envelope_ValueMetric, "gorouter", "cf", "total_routes"))
+		})
+
+		It("expires the metrics according to the policies", func() {
+			Expect(metricsStore.GetValueMetrics()).To(HaveLen(2))
+			Eventually(metricsStore.GetValueMetrics).Should(HaveLen(1))
+			Expect(metricsStore.GetValueMetrics()[0].Deployment).To(Equal("cf"))
+		})
+	})
+})
@@ -14,6 +14,32 @@ const (
 	TotalValueMetricsReceivedKey            = "TotalValueMetricsReceived"
 	TotalValueMetricsProcessedKey           = "TotalValueMetricsProcessed"
 	LastValueMetricReceivedTimestampKey     = "LastValueMetricReceivedTimestamp"
+	TotalHttpStartStopsReceivedKey          = "TotalHttpStartStopsReceived"
+	TotalHttpStartStopsProcessedKey         = "TotalHttpStartStopsProcessed"
+	LastHttpStartStopReceivedTimestampKey   = "LastHttpStartStopReceivedTimestamp"
+	TotalLogMessagesReceivedKey             = "TotalLogMessagesReceived"
+	TotalLogMessagesProcessedKey            = "TotalLogMessagesProcessed"
+	LastLogMessageReceivedTimestampKey      = "LastLogMessageReceivedTimestamp"
+	TotalErrorsReceivedKey                  = "TotalErrorsReceived"
+	TotalErrorsProcessedKey                 = "TotalErrorsProcessed"
+	LastErrorReceivedTimestampKey           = "LastErrorReceivedTimestamp"
+	FirehoseConnectedKey                    = "FirehoseConnected"
+	TotalFirehoseReconnectsKey              = "TotalFirehoseReconnects"
+	LastFirehoseReconnectTimestampKey       = "LastFirehoseReconnectTimestamp"
+	EnvelopeQueueDepthKey                   = "EnvelopeQueueDepth"
+	TotalEnvelopesDequeuedKey               = "TotalEnvelopesDequeued"
+	TotalEnvelopeQueueLatencyNanosecondsKey = "TotalEnvelopeQueueLatencyNanoseconds"
+	TotalEnvelopesDroppedKey                = "TotalEnvelopesDropped"
+	TotalFirehoseConnectionsKey             = "TotalFirehoseConnections"
+	LastFirehoseConnectTimestampKey         = "LastFirehoseConnectTimestamp"
+	TotalEnvelopeBytesDecodedKey            = "TotalEnvelopeBytesDecoded"
+	TotalFirehoseDisconnectsKey             = "TotalFirehoseDisconnects"
+	TotalDopplerEndpointFailoversKey        = "TotalDopplerEndpointFailovers"
+	ActiveDopplerEndpointKey                = "ActiveDopplerEndpoint"
+	TotalSeriesEvictedKey                   = "TotalSeriesEvicted"
+	TotalSeriesRejectedKey                  = "TotalSeriesRejected"
+	TotalCounterEventResetsKey              = "TotalCounterEventResets"
+	TotalEnvelopesFilteredKey               = "TotalEnvelopesFiltered"
 	SlowConsumerAlertKey                    = "SlowConsumerAlert"
 	LastSlowConsumerAlertTimestampKey       = "LastSlowConsumerAlertTimestamp"
 )
@@ -32,10 +58,42 @@ type InternalMetrics struct {
 	TotalValueMetricsReceived            int64
 	TotalValueMetricsProcessed           int64
 	LastValueMetricReceivedTimestamp     int64
+	TotalHttpStartStopsReceived          int64
+	TotalHttpStartStopsProcessed         int64
+	LastHttpStartStopReceivedTimestamp   int64
+	TotalLogMessagesReceived             int64
+	TotalLogMessagesProcessed            int64
+	LastLogMessageReceivedTimestamp      int64
+	TotalErrorsReceived                  int64
+	TotalErrorsProcessed                 int64
+	LastErrorReceivedTimestamp           int64
+	FirehoseConnected                    bool
+	TotalFirehoseReconnects              int64
+	LastFirehoseReconnectTimestamp       int64
+	EnvelopeQueueDepth                   int64
+	TotalEnvelopesDequeued               int64
+	TotalEnvelopeQueueLatencyNanoseconds int64
+	TotalEnvelopesDropped                int64
+	TotalFirehoseConnections             int64
+	LastFirehoseConnectTimestamp         int64
+	TotalEnvelopeBytesDecoded            int64
+	TotalFirehoseDisconnects             map[int]int64
+	TotalDopplerEndpointFailovers        int64
+	ActiveDopplerEndpoint                string
+	TotalSeriesEvicted                   map[string]int64
+	TotalSeriesRejected                  map[string]int64
+	TotalCounterEventResets              int64
+	TotalEnvelopesFiltered               map[FilteredEnvelopes]int64
 	SlowConsumerAlert                    bool
 	LastSlowConsumerAlertTimestamp       int64
 }
 
+// FilteredEnvelopes identifies the envelopes of an origin dropped by a filter.
+type FilteredEnvelopes struct {
+	Filter string
+	Origin string
+}
+
 type ContainerMetrics []ContainerMetric
 
 type ContainerMetric struct {
@@ -68,6 +126,8 @@ type CounterEvent struct {
 	Name       string
 	Delta      uint64
 	Total      uint64
+	// Counter adds up the increases of Total across the resets of the emitter.
+	Counter uint64
 }
 
 type ValueMetrics []ValueMetric
@@ -83,4 +143,62 @@ type ValueMetric struct {
 	Name       string
 	Value      float64
 	Unit       string
+	// Aggregate is only set for the Value Metrics selected by the ValueMetricAggregations.
+	Aggregate *ValueMetricAggregate
+	samples   []valueMetricSample
+}
+
+type ValueMetricAggregate struct {
+	Min   float64
+	Max   float64
+	Sum   float64
+	Count uint64
+}
+
+type HttpStartStops []HttpStartStop
+
+type HttpStartStop struct {
+	Timestamp       int64
+	ApplicationId   string
+	Method          string
+	Requests        map[string]uint64
+	DurationCount   uint64
+	DurationSum     float64
+	DurationBuckets map[float64]uint64
+}
+
+type HttpRoutes []HttpRoute
+
+type HttpRoute struct {
+	Timestamp       int64
+	Host            string
+	Path            string
+	Method          string
+	Requests        map[string]uint64
+	ResponseBytes   uint64
+	DurationCount   uint64
+	DurationSum     float64
+	DurationBuckets map[float64]uint64
+}
+
+type LogMessages []LogMessage
+
+type LogMessage struct {
+	Timestamp     int64
+	ApplicationId string
+	SourceType    string
+	MessageType   string
+	Messages      uint64
+	Bytes         uint64
+}
+
+type Errors []Error
+
+type Error struct {
+	Origin    string
+	Timestamp int64
+	Source    string
+	Code      int32
+	Message   string
+	Count     uint64
 }
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/patrickmn/go-cache"
+)
+
+var pipelineMetricsInterval = 1 * time.Second
+
+type queuedEnvelope struct {
+	envelope   *events.Envelope
+	enqueuedAt time.Time
+}
+
+type pipeline struct {
+	store        *Store
+	queues       []chan queuedEnvelope
+	dropWhenFull bool
+	lock         sync.RWMutex
+	stopped      bool
+	workers      sync.WaitGroup
+	done         chan struct{}
+
+	depth    int64
+	dequeued int64
+	latency  int64
+	dropped  int64
+}
+
+// StartPipeline decouples AddMetric from the envelope processing. Envelopes are queued and processed by
+// `workers` goroutines, envelopes of the same series are always processed by the same worker so they keep
+// their order. When the queue is full, the caller is blocked until envelopes can be queued, unless
+// dropWhenFull is set in which case they are dropped.
+func (s *Store) StartPipeline(workers int, queueSize int, dropWhenFull bool) {
+	if workers <= 0 || s.pipeline != nil {
+		return
+	}
+
+	workerQueueSize := (queueSize + workers - 1) / workers
+	if workerQueueSize < 1 {
+		workerQueueSize = 1
+	}
+
+	p := &pipeline{
+		store:        s,
+		dropWhenFull: dropWhenFull,
+		done:         make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		queue := make(chan queuedEnvelope, workerQueueSize)
+		p.queues = append(p.queues, queue)
+		p.workers.Add(1)
+		go p.work(queue)
+	}
+	go p.publishMetrics()
+
+	s.pipeline = p
+}
+
+// StopPipeline stops queueing envelopes and waits until the queued ones have been processed.
+// Envelopes added afterwards are processed synchronously.
+func (s *Store) StopPipeline() {
+	if s.pipeline == nil {
+		return
+	}
+
+	s.pipeline.stop()
+}
+
+func (p *pipeline) enqueue(envelope *events.Envelope) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.stopped {
+		p.store.processMetric(envelope)
+		return
+	}
+
+	queue := p.queues[seriesHash(envelope)%uint32(len(p.queues))]
+	atomic.AddInt64(&p.depth, 1)
+	if !p.dropWhenFull {
+		queue <- queuedEnvelope{envelope: envelope, enqueuedAt: time.Now()}
+		return
+	}
+
+	select {
+	case queue <- queuedEnvelope{envelope: envelope, enqueuedAt: time.Now()}:
+	default:
+		atomic.AddInt64(&p.depth, -1)
+		atomic.AddInt64(&p.dropped, 1)
+	}
+}
+
+func (p *pipeline) work(queue chan queuedEnvelope) {
+	defer p.workers.Done()
+
+	for queued := range queue {
+		atomic.AddInt64(&p.depth, -1)
+		atomic.AddInt64(&p.dequeued, 1)
+		atomic.AddInt64(&p.latency, int64(time.Since(queued.enqueuedAt)))
+		p.store.processMetric(queued.envelope)
+	}
+}
+
+func (p *pipeline) stop() {
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		return
+	}
+	p.stopped = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.lock.Unlock()
+
+	p.workers.Wait()
+	close(p.done)
+	p.publish()
+}
+
+func (p *pipeline) publishMetrics() {
+	ticker := time.NewTicker(pipelineMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.publish()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *pipeline) publish() {
+	p.store.internalMetrics.Set(EnvelopeQueueDepthKey, atomic.LoadInt64(&p.depth), cache.NoExpiration)
+	p.store.internalMetrics.Set(TotalEnvelopesDequeuedKey, atomic.LoadInt64(&p.dequeued), cache.NoExpiration)
+	p.store.internalMetrics.Set(TotalEnvelopeQueueLatencyNanosecondsKey, atomic.LoadInt64(&p.latency), cache.NoExpiration)
+	p.store.internalMetrics.Set(TotalEnvelopesDroppedKey, atomic.LoadInt64(&p.dropped), cache.NoExpiration)
+}
+
+func seriesHash(envelope *events.Envelope) uint32 {
+	hash := fnv.New32a()
+
+	hash.Write([]byte(envelope.GetOrigin()))
+	hash.Write([]byte(envelope.GetDeployment()))
+	hash.Write([]byte(envelope.GetJob()))
+	hash.Write([]byte(envelope.GetIndex()))
+	hash.Write([]byte(envelope.GetIp()))
+	hash.Write([]byte(envelope.GetEventType().String()))
+
+	switch envelope.GetEventType() {
+	case events.Envelope_ContainerMetric:
+		hash.Write([]byte(envelope.GetContainerMetric().GetApplicationId()))
+		hash.Write([]byte(strconv.Itoa(int(envelope.GetContainerMetric().GetInstanceIndex()))))
+	case events.Envelope_CounterEvent:
+		hash.Write([]byte(envelope.GetCounterEvent().GetName()))
+	case events.Envelope_ValueMetric:
+		hash.Write([]byte(envelope.GetValueMetric().GetName()))
+	case events.Envelope_HttpStartStop:
+		hash.Write([]byte(utils.FormatUUID(envelope.GetHttpStartStop().GetApplicationId())))
+	case events.Envelope_LogMessage:
+		hash.Write([]byte(envelope.GetLogMessage().GetAppId()))
+	case events.Envelope_Error:
+		hash.Write([]byte(envelope.GetError().GetSource()))
+	}
+
+	return hash.Sum32()
+}
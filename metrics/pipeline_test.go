@@ -0,0 +1,188 @@
+package metrics_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var _ = Describe("Pipeline", func() {
+	var (
+		metricsStore           *Store
+		metricsExpiration      time.Duration
+		metricsCleanupInterval time.Duration
+		deploymentFilter       *filters.DeploymentFilter
+		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
+
+		workers      int
+		queueSize    int
+		dropWhenFull bool
+		numEnvelopes = 1000
+
+		valueMetricEnvelope = func(name string, value float64) *events.Envelope {
+			return &events.Envelope{
+				Origin:     proto.String("fake-origin"),
+				EventType:  events.Envelope_ValueMetric.Enum(),
+				Timestamp:  proto.Int64(time.Now().UnixNano()),
+				Deployment: proto.String("fake-deployment-name"),
+				Job:        proto.String("fake-job-name"),
+				Index:      proto.String("0"),
+				Ip:         proto.String("1.2.3.4"),
+				ValueMetric: &events.ValueMetric{
+					Name:  proto.String(name),
+					Value: proto.Float64(value),
+					Unit:  proto.String("counter"),
+				},
+			}
+		}
+
+		httpStartStopEnvelope = func(index int) *events.Envelope {
+			return &events.Envelope{
+				Origin:     proto.String("gorouter"),
+				EventType:  events.Envelope_HttpStartStop.Enum(),
+				Timestamp:  proto.Int64(time.Now().UnixNano()),
+				Deployment: proto.String("fake-deployment-name"),
+				Job:        proto.String("router"),
+				Index:      proto.String(fmt.Sprintf("%d", index)),
+				Ip:         proto.String("1.2.3.4"),
+				HttpStartStop: &events.HttpStartStop{
+					StartTimestamp: proto.Int64(0),
+					StopTimestamp:  proto.Int64(1000000),
+					RequestId:      &events.UUID{Low: proto.Uint64(0), High: proto.Uint64(0)},
+					PeerType:       events.PeerType_Client.Enum(),
+					Method:         events.Method_GET.Enum(),
+					Uri:            proto.String("https://example.com/"),
+					RemoteAddress:  proto.String("1.2.3.4"),
+					UserAgent:      proto.String("fake-user-agent"),
+					StatusCode:     proto.Int32(200),
+					ContentLength:  proto.Int64(10),
+					ApplicationId: &events.UUID{
+						Low:  proto.Uint64(0x7243cc580bc17af4),
+						High: proto.Uint64(0x79d4c3b2020e67a5),
+					},
+				},
+			}
+		}
+	)
+
+	BeforeEach(func() {
+		workers = 4
+		queueSize = numEnvelopes * 2 * workers
+		dropWhenFull = false
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+	})
+
+	JustBeforeEach(func() {
+		metricsStore.StartPipeline(workers, queueSize, dropWhenFull)
+	})
+
+	AfterEach(func() {
+		metricsStore.StopPipeline()
+	})
+
+	It("keeps the order of envelopes of the same series", func() {
+		for i := 1; i <= numEnvelopes; i++ {
+			metricsStore.AddMetric(valueMetricEnvelope("fake-metric-1", float64(i)))
+			metricsStore.AddMetric(valueMetricEnvelope("fake-metric-2", float64(-i)))
+		}
+		metricsStore.StopPipeline()
+
+		valueMetrics := metricsStore.GetValueMetrics()
+		Expect(valueMetrics).To(HaveLen(2))
+		for _, valueMetric := range valueMetrics {
+			if valueMetric.Name == "fake-metric-1" {
+				Expect(valueMetric.Value).To(Equal(float64(numEnvelopes)))
+			} else {
+				Expect(valueMetric.Value).To(Equal(float64(-numEnvelopes)))
+			}
+		}
+	})
+
+	It("does not lose aggregated envelopes processed by different workers", func() {
+		for i := 0; i < numEnvelopes; i++ {
+			metricsStore.AddMetric(httpStartStopEnvelope(i % 8))
+		}
+		metricsStore.StopPipeline()
+
+		httpStartStops := metricsStore.GetHttpStartStops()
+		Expect(httpStartStops).To(HaveLen(1))
+		Expect(httpStartStops[0].Requests["2xx"]).To(Equal(uint64(numEnvelopes)))
+
+		httpRoutes := metricsStore.GetHttpRoutes()
+		Expect(httpRoutes).To(HaveLen(1))
+		Expect(httpRoutes[0].Requests["2xx"]).To(Equal(uint64(numEnvelopes)))
+	})
+
+	It("sets the queue internal metrics", func() {
+		for i := 0; i < numEnvelopes; i++ {
+			metricsStore.AddMetric(valueMetricEnvelope(fmt.Sprintf("fake-metric-%d", i), float64(i)))
+		}
+		metricsStore.StopPipeline()
+
+		internalMetrics := metricsStore.GetInternalMetrics()
+		Expect(internalMetrics.EnvelopeQueueDepth).To(Equal(int64(0)))
+		Expect(internalMetrics.TotalEnvelopesDequeued).To(Equal(int64(numEnvelopes)))
+		Expect(internalMetrics.TotalEnvelopesReceived).To(Equal(int64(numEnvelopes)))
+		Expect(internalMetrics.TotalEnvelopeQueueLatencyNanoseconds).To(BeNumerically(">", 0))
+		Expect(internalMetrics.TotalEnvelopesDropped).To(Equal(int64(0)))
+	})
+
+	Context("when the queue is full", func() {
+		BeforeEach(func() {
+			workers = 1
+			queueSize = 1
+		})
+
+		It("waits until the envelopes can be queued", func() {
+			for i := 0; i < numEnvelopes; i++ {
+				metricsStore.AddMetric(valueMetricEnvelope(fmt.Sprintf("fake-metric-%d", i), float64(i)))
+			}
+			metricsStore.StopPipeline()
+
+			internalMetrics := metricsStore.GetInternalMetrics()
+			Expect(internalMetrics.TotalEnvelopesDropped).To(Equal(int64(0)))
+			Expect(internalMetrics.TotalEnvelopesDequeued).To(Equal(int64(numEnvelopes)))
+			Expect(metricsStore.GetValueMetrics()).To(HaveLen(numEnvelopes))
+		})
+
+		Context("and envelopes are dropped when the queue is full", func() {
+			BeforeEach(func() {
+				dropWhenFull = true
+			})
+
+			It("drops the envelopes that cannot be queued", func() {
+				for i := 0; i < numEnvelopes; i++ {
+					metricsStore.AddMetric(valueMetricEnvelope(fmt.Sprintf("fake-metric-%d", i), float64(i)))
+				}
+				metricsStore.StopPipeline()
+
+				internalMetrics := metricsStore.GetInternalMetrics()
+				Expect(internalMetrics.TotalEnvelopesDropped).To(BeNumerically(">", 0))
+				Expect(internalMetrics.TotalEnvelopesDequeued + internalMetrics.TotalEnvelopesDropped).To(Equal(int64(numEnvelopes)))
+				Expect(internalMetrics.TotalEnvelopesReceived).To(Equal(internalMetrics.TotalEnvelopesDequeued))
+			})
+		})
+	})
+
+	Context("when the pipeline is stopped", func() {
+		It("processes the envelopes synchronously", func() {
+			metricsStore.StopPipeline()
+			metricsStore.AddMetric(valueMetricEnvelope("fake-metric-1", 1))
+
+			Expect(metricsStore.GetValueMetrics()).To(HaveLen(1))
+		})
+	})
+})
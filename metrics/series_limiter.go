@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	MaxSeriesReason          = "max_series"
+	MaxSeriesPerOriginReason = "max_series_per_origin"
+	MaxSeriesPerMetricReason = "max_series_per_metric"
+)
+
+// SeriesLimits caps the number of Counter Event and Value Metric series kept by the store. A zero limit
+// means unlimited. When a limit is reached, the least recently updated series of the same scope is
+// evicted to make room for a new one, or the new series is rejected when Reject is set.
+type SeriesLimits struct {
+	MaxSeries          int
+	MaxSeriesPerOrigin int
+	MaxSeriesPerMetric int
+	Reject             bool
+}
+
+func (l SeriesLimits) enabled() bool {
+	return l.MaxSeries > 0 || l.MaxSeriesPerOrigin > 0 || l.MaxSeriesPerMetric > 0
+}
+
+type trackedSeries struct {
+	cache      *cache.Cache
+	cacheKey   string
+	origin     string
+	metric     string
+	allElem    *list.Element
+	originElem *list.Element
+	metricElem *list.Element
+}
+
+type seriesEviction struct {
+	series *trackedSeries
+	reason string
+}
+
+// seriesLimiter keeps the series ordered from the least to the most recently updated, globally, by
+// origin and by metric name.
+type seriesLimiter struct {
+	limits   SeriesLimits
+	series   map[*cache.Cache]map[string]*trackedSeries
+	all      *list.List
+	byOrigin map[string]*list.List
+	byMetric map[string]*list.List
+	lock     sync.Mutex
+}
+
+func newSeriesLimiter(limits SeriesLimits) *seriesLimiter {
+	return &seriesLimiter{
+		limits:   limits,
+		series:   map[*cache.Cache]map[string]*trackedSeries{},
+		all:      list.New(),
+		byOrigin: map[string]*list.List{},
+		byMetric: map[string]*list.List{},
+	}
+}
+
+// admit records an update of a series. It returns the series to evict to make room for it, or false
+// and the reason when the series is rejected.
+func (l *seriesLimiter) admit(c *cache.Cache, cacheKey string, origin string, name string) ([]seriesEviction, bool, string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	metric := origin + "." + name
+	if series, ok := l.series[c][cacheKey]; ok {
+		l.all.MoveToBack(series.allElem)
+		l.byOrigin[series.origin].MoveToBack(series.originElem)
+		l.byMetric[series.metric].MoveToBack(series.metricElem)
+		return nil, true, ""
+	}
+
+	scopes := []struct {
+		series *list.List
+		limit  int
+		reason string
+	}{
+		{l.byMetric[metric], l.limits.MaxSeriesPerMetric, MaxSeriesPerMetricReason},
+		{l.byOrigin[origin], l.limits.MaxSeriesPerOrigin, MaxSeriesPerOriginReason},
+		{l.all, l.limits.MaxSeries, MaxSeriesReason},
+	}
+
+	var evictions []seriesEviction
+	for _, scope := range scopes {
+		if scope.limit <= 0 || scope.series == nil || scope.series.Len() < scope.limit {
+			continue
+		}
+		if l.limits.Reject {
+			return nil, false, scope.reason
+		}
+		for scope.series.Len() >= scope.limit {
+			series := scope.series.Front().Value.(*trackedSeries)
+			l.remove(series)
+			evictions = append(evictions, seriesEviction{series: series, reason: scope.reason})
+		}
+	}
+
+	l.add(&trackedSeries{cache: c, cacheKey: cacheKey, origin: origin, metric: metric})
+	return evictions, true, ""
+}
+
+func (l *seriesLimiter) add(series *trackedSeries) {
+	if l.series[series.cache] == nil {
+		l.series[series.cache] = map[string]*trackedSeries{}
+	}
+	if l.byOrigin[series.origin] == nil {
+		l.byOrigin[series.origin] = list.New()
+	}
+	if l.byMetric[series.metric] == nil {
+		l.byMetric[series.metric] = list.New()
+	}
+
+	l.series[series.cache][series.cacheKey] = series
+	series.allElem = l.all.PushBack(series)
+	series.originElem = l.byOrigin[series.origin].PushBack(series)
+	series.metricElem = l.byMetric[series.metric].PushBack(series)
+}
+
+func (l *seriesLimiter) remove(series *trackedSeries) {
+	delete(l.series[series.cache], series.cacheKey)
+	l.all.Remove(series.allElem)
+	l.byOrigin[series.origin].Remove(series.originElem)
+	if l.byOrigin[series.origin].Len() == 0 {
+		delete(l.byOrigin, series.origin)
+	}
+	l.byMetric[series.metric].Remove(series.metricElem)
+	if l.byMetric[series.metric].Len() == 0 {
+		delete(l.byMetric, series.metric)
+	}
+}
+
+// forget stops tracking a series deleted from the cache.
+func (l *seriesLimiter) forget(c *cache.Cache, cacheKey string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if series, ok := l.series[c][cacheKey]; ok {
+		l.remove(series)
+	}
+}
+
+// forgetAll stops tracking the series of a flushed cache.
+func (l *seriesLimiter) forgetAll(c *cache.Cache) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, series := range l.series[c] {
+		l.remove(series)
+	}
+}
+
+type existingSeries struct {
+	cacheKey  string
+	origin    string
+	name      string
+	timestamp int64
+}
+
+// track starts tracking series already in the cache, from the oldest to the newest timestamp.
+func (l *seriesLimiter) track(c *cache.Cache, existing []existingSeries) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	sort.SliceStable(existing, func(i, j int) bool { return existing[i].timestamp < existing[j].timestamp })
+
+	for _, e := range existing {
+		if _, ok := l.series[c][e.cacheKey]; ok {
+			continue
+		}
+		l.add(&trackedSeries{cache: c, cacheKey: e.cacheKey, origin: e.origin, metric: e.origin + "." + e.name})
+	}
+}
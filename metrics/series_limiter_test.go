@@ -0,0 +1,200 @@
+package metrics_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var _ = Describe("SeriesLimits", func() {
+	var (
+		metricsStore *Store
+		limits       SeriesLimits
+
+		valueMetric = func(origin string, name string, ip string) *events.Envelope {
+			return &events.Envelope{
+				Origin:     proto.String(origin),
+				EventType:  events.Envelope_ValueMetric.Enum(),
+				Timestamp:  proto.Int64(time.Now().UnixNano()),
+				Deployment: proto.String("fake-deployment-name"),
+				Job:        proto.String("fake-job-name"),
+				Index:      proto.String("0"),
+				Ip:         proto.String(ip),
+				ValueMetric: &events.ValueMetric{
+					Name:  proto.String(name),
+					Value: proto.Float64(1),
+					Unit:  proto.String("count"),
+				},
+			}
+		}
+
+		counterEvent = func(origin string, name string, ip string) *events.Envelope {
+			return &events.Envelope{
+				Origin:     proto.String(origin),
+				EventType:  events.Envelope_CounterEvent.Enum(),
+				Timestamp:  proto.Int64(time.Now().UnixNano()),
+				Deployment: proto.String("fake-deployment-name"),
+				Job:        proto.String("fake-job-name"),
+				Index:      proto.String("0"),
+				Ip:         proto.String(ip),
+				CounterEvent: &events.CounterEvent{
+					Name:  proto.String(name),
+					Delta: proto.Uint64(1),
+					Total: proto.Uint64(1),
+				},
+			}
+		}
+
+		valueMetricNames = func() []string {
+			names := []string{}
+			for _, valueMetric := range metricsStore.GetValueMetrics() {
+				names = append(names, valueMetric.Origin+"/"+valueMetric.Name+"/"+valueMetric.IP)
+			}
+			return names
+		}
+	)
+
+	BeforeEach(func() {
+		deploymentFilter := filters.NewDeploymentFilter([]string{})
+		eventFilter, _ := filters.NewEventFilter([]string{})
+		routeTemplates, _ := utils.NewRouteTemplates([]string{})
+		metricsStore = NewStore(0, 0, deploymentFilter, eventFilter, routeTemplates)
+
+		limits = SeriesLimits{}
+	})
+
+	JustBeforeEach(func() {
+		metricsStore.SetSeriesLimits(limits)
+	})
+
+	Context("when there are no limits", func() {
+		It("keeps every series", func() {
+			for _, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+				metricsStore.AddMetric(valueMetric("fake-origin", "fake-metric", ip))
+			}
+			Expect(metricsStore.GetValueMetrics()).To(HaveLen(3))
+		})
+	})
+
+	Context("when the number of series is limited", func() {
+		BeforeEach(func() {
+			limits.MaxSeries = 2
+		})
+
+		It("evicts the least recently updated series", func() {
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-b", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-c", "1.1.1.1"))
+
+			Expect(valueMetricNames()).To(ConsistOf("fake-origin/metric-a/1.1.1.1", "fake-origin/metric-c/1.1.1.1"))
+			Expect(metricsStore.GetInternalMetrics().TotalSeriesEvicted).To(Equal(map[string]int64{MaxSeriesReason: 1}))
+		})
+
+		It("counts Counter Events and Value Metrics together", func() {
+			metricsStore.AddMetric(counterEvent("fake-origin", "counter-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-b", "1.1.1.1"))
+
+			Expect(metricsStore.GetCounterEvents()).To(BeEmpty())
+			Expect(metricsStore.GetValueMetrics()).To(HaveLen(2))
+		})
+
+		It("forgets the flushed series", func() {
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-b", "1.1.1.1"))
+			metricsStore.FlushValueMetrics()
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-c", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-d", "1.1.1.1"))
+
+			Expect(metricsStore.GetValueMetrics()).To(HaveLen(2))
+			Expect(metricsStore.GetInternalMetrics().TotalSeriesEvicted).To(BeEmpty())
+		})
+
+		Context("and series already exist", func() {
+			BeforeEach(func() {
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-b", "1.1.1.1"))
+			})
+
+			It("accounts them", func() {
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-c", "1.1.1.1"))
+				Expect(metricsStore.GetValueMetrics()).To(HaveLen(2))
+			})
+		})
+
+		Context("and new series are rejected", func() {
+			BeforeEach(func() {
+				limits.Reject = true
+			})
+
+			It("keeps the existing series", func() {
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-b", "1.1.1.1"))
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-c", "1.1.1.1"))
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+
+				Expect(valueMetricNames()).To(ConsistOf("fake-origin/metric-a/1.1.1.1", "fake-origin/metric-b/1.1.1.1"))
+				Expect(metricsStore.GetInternalMetrics().TotalSeriesRejected).To(Equal(map[string]int64{MaxSeriesReason: 1}))
+				Expect(metricsStore.GetInternalMetrics().TotalSeriesEvicted).To(BeEmpty())
+			})
+
+			It("does not account the rejected envelopes as processed", func() {
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-b", "1.1.1.1"))
+				metricsStore.AddMetric(valueMetric("fake-origin", "metric-c", "1.1.1.1"))
+				metricsStore.AddMetric(counterEvent("fake-origin", "counter-a", "1.1.1.1"))
+
+				internalMetrics := metricsStore.GetInternalMetrics()
+				Expect(internalMetrics.TotalValueMetricsReceived).To(Equal(int64(3)))
+				Expect(internalMetrics.TotalValueMetricsProcessed).To(Equal(int64(2)))
+				Expect(internalMetrics.TotalCounterEventsReceived).To(Equal(int64(1)))
+				Expect(internalMetrics.TotalCounterEventsProcessed).To(Equal(int64(0)))
+				Expect(internalMetrics.TotalSeriesRejected).To(Equal(map[string]int64{MaxSeriesReason: 2}))
+			})
+		})
+	})
+
+	Context("when the number of series per origin is limited", func() {
+		BeforeEach(func() {
+			limits.MaxSeriesPerOrigin = 1
+		})
+
+		It("evicts the least recently updated series of the same origin", func() {
+			metricsStore.AddMetric(valueMetric("origin-a", "metric-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("origin-b", "metric-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("origin-a", "metric-b", "1.1.1.1"))
+
+			Expect(valueMetricNames()).To(ConsistOf("origin-b/metric-a/1.1.1.1", "origin-a/metric-b/1.1.1.1"))
+			Expect(metricsStore.GetInternalMetrics().TotalSeriesEvicted).To(Equal(map[string]int64{MaxSeriesPerOriginReason: 1}))
+		})
+	})
+
+	Context("when the number of series per metric is limited", func() {
+		BeforeEach(func() {
+			limits.MaxSeriesPerMetric = 2
+		})
+
+		It("evicts the least recently updated series of the same metric", func() {
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "2.2.2.2"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-b", "1.1.1.1"))
+			metricsStore.AddMetric(valueMetric("fake-origin", "metric-a", "3.3.3.3"))
+
+			Expect(valueMetricNames()).To(ConsistOf(
+				"fake-origin/metric-a/2.2.2.2",
+				"fake-origin/metric-a/3.3.3.3",
+				"fake-origin/metric-b/1.1.1.1",
+			))
+			Expect(metricsStore.GetInternalMetrics().TotalSeriesEvicted).To(Equal(map[string]int64{MaxSeriesPerMetricReason: 1}))
+		})
+	})
+})
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/common/log"
+)
+
+func init() {
+	gob.Register(ContainerMetric{})
+	gob.Register(CounterEvent{})
+	gob.Register(ValueMetric{})
+	gob.Register(HttpStartStop{})
+	gob.Register(HttpRoute{})
+	gob.Register(LogMessage{})
+	gob.Register(Error{})
+}
+
+// snapshot holds the store contents. Each cache is encoded with its expirations, so restored
+// metrics only live for the rest of their TTL.
+type snapshot struct {
+	InternalMetrics InternalMetrics
+	Caches          map[string][]byte
+}
+
+func (s *Store) snapshotCaches() map[string]*cache.Cache {
+	return map[string]*cache.Cache{
+		"container_metrics": s.containerMetrics,
+		"counter_events":    s.counterEvents,
+		"value_metrics":     s.valueMetrics,
+		"http_start_stops":  s.httpStartStops,
+		"http_routes":       s.httpRoutes,
+		"log_messages":      s.logMessages,
+		"errors":            s.errors,
+	}
+}
+
+// SaveSnapshot writes the store contents to path, replacing the previous snapshot atomically.
+func (s *Store) SaveSnapshot(path string) error {
+	snap := snapshot{
+		InternalMetrics: s.GetInternalMetrics(),
+		Caches:          map[string][]byte{},
+	}
+	for name, c := range s.snapshotCaches() {
+		var buffer bytes.Buffer
+		if err := c.Save(&buffer); err != nil {
+			return err
+		}
+		snap.Caches[name] = buffer.Bytes()
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if err := gob.NewEncoder(tmpFile).Encode(&snap); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// LoadSnapshot restores the store contents written by SaveSnapshot. Metrics expired in the meantime
+// are not restored, and the internal metrics describing the current state (connection, queue depth,
+// active endpoint, slow consumer alert) are kept.
+func (s *Store) LoadSnapshot(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	snap := snapshot{}
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return err
+	}
+
+	caches := s.snapshotCaches()
+	for name, data := range snap.Caches {
+		c, ok := caches[name]
+		if !ok {
+			continue
+		}
+		if err := c.Load(bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	s.deleteExpired()
+	s.trackExistingSeries()
+
+	current := s.GetInternalMetrics()
+	restored := snap.InternalMetrics
+	restored.FirehoseConnected = current.FirehoseConnected
+	restored.EnvelopeQueueDepth = current.EnvelopeQueueDepth
+	restored.ActiveDopplerEndpoint = current.ActiveDopplerEndpoint
+	restored.SlowConsumerAlert = current.SlowConsumerAlert
+	s.SetInternalMetrics(restored)
+
+	return nil
+}
+
+// RunSnapshots saves a snapshot to path every interval until StopSnapshots is called.
+func (s *Store) RunSnapshots(path string, interval time.Duration) error {
+	if interval <= 0 {
+		<-s.snapshotsDone
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.SaveSnapshot(path); err != nil {
+				log.Errorf("Error while saving metrics snapshot to `%s`: %v", path, err)
+			}
+		case <-s.snapshotsDone:
+			return nil
+		}
+	}
+}
+
+func (s *Store) StopSnapshots() {
+	s.snapshotsDoneOnce.Do(func() {
+		close(s.snapshotsDone)
+	})
+}
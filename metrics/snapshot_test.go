@@ -0,0 +1,172 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		err              error
+		tmpDir           string
+		snapshotFile     string
+		deploymentFilter *filters.DeploymentFilter
+		eventFilter      *filters.EventFilter
+		routeTemplates   *utils.RouteTemplates
+		metricsStore     *Store
+		restoredStore    *Store
+
+		newStore = func(metricsExpiration time.Duration) *Store {
+			return NewStore(metricsExpiration, 0, deploymentFilter, eventFilter, routeTemplates)
+		}
+
+		containerMetricEnvelope = &events.Envelope{
+			Origin:     proto.String("fake-origin"),
+			EventType:  events.Envelope_ContainerMetric.Enum(),
+			Timestamp:  proto.Int64(time.Now().UnixNano()),
+			Deployment: proto.String("fake-deployment-name"),
+			Job:        proto.String("fake-job-name"),
+			Index:      proto.String("0"),
+			Ip:         proto.String("1.2.3.4"),
+			ContainerMetric: &events.ContainerMetric{
+				ApplicationId: proto.String("FakeApplicationId1"),
+				InstanceIndex: proto.Int32(1),
+				CpuPercentage: proto.Float64(0.5),
+			},
+		}
+
+		valueMetricEnvelope = &events.Envelope{
+			Origin:     proto.String("fake-origin"),
+			EventType:  events.Envelope_ValueMetric.Enum(),
+			Timestamp:  proto.Int64(time.Now().UnixNano()),
+			Deployment: proto.String("fake-deployment-name"),
+			Job:        proto.String("fake-job-name"),
+			Index:      proto.String("0"),
+			Ip:         proto.String("1.2.3.4"),
+			Tags:       map[string]string{"source_id": "fake-source-id"},
+			ValueMetric: &events.ValueMetric{
+				Name:  proto.String("FakeValueMetric1"),
+				Value: proto.Float64(2000),
+				Unit:  proto.String("kb"),
+			},
+		}
+	)
+
+	BeforeEach(func() {
+		tmpDir, err = ioutil.TempDir("", "snapshot_test")
+		Expect(err).ToNot(HaveOccurred())
+		snapshotFile = filepath.Join(tmpDir, "snapshot")
+
+		deploymentFilter = filters.NewDeploymentFilter([]string{})
+		eventFilter, _ = filters.NewEventFilter([]string{})
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+
+		metricsStore = newStore(time.Minute)
+		metricsStore.AddMetric(containerMetricEnvelope)
+		metricsStore.AddMetric(valueMetricEnvelope)
+		metricsStore.AddFirehoseConnect()
+		metricsStore.AddFirehoseDisconnect(1006)
+		metricsStore.SetActiveDopplerEndpoint("wss://doppler-b.example.com")
+
+		restoredStore = newStore(time.Minute)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("SaveSnapshot and LoadSnapshot", func() {
+		JustBeforeEach(func() {
+			Expect(metricsStore.SaveSnapshot(snapshotFile)).To(Succeed())
+			Expect(restoredStore.LoadSnapshot(snapshotFile)).To(Succeed())
+		})
+
+		It("restores the metrics", func() {
+			Expect(restoredStore.GetContainerMetrics()).To(Equal(metricsStore.GetContainerMetrics()))
+			Expect(restoredStore.GetValueMetrics()).To(Equal(metricsStore.GetValueMetrics()))
+		})
+
+		It("restores the internal metrics counters", func() {
+			internalMetrics := restoredStore.GetInternalMetrics()
+			Expect(internalMetrics.TotalMetricsReceived).To(Equal(int64(2)))
+			Expect(internalMetrics.TotalContainerMetricsProcessed).To(Equal(int64(1)))
+			Expect(internalMetrics.TotalFirehoseConnections).To(Equal(int64(1)))
+			Expect(internalMetrics.TotalFirehoseDisconnects).To(Equal(map[int]int64{1006: 1}))
+		})
+
+		It("keeps the internal metrics describing the current state", func() {
+			internalMetrics := restoredStore.GetInternalMetrics()
+			Expect(internalMetrics.FirehoseConnected).To(BeFalse())
+			Expect(internalMetrics.ActiveDopplerEndpoint).To(BeEmpty())
+		})
+
+		It("does not leave temporary files", func() {
+			files, err := ioutil.ReadDir(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+		})
+
+		Context("when metrics expire", func() {
+			BeforeEach(func() {
+				metricsStore = newStore(50 * time.Millisecond)
+				metricsStore.AddMetric(containerMetricEnvelope)
+				metricsStore.AddMetric(valueMetricEnvelope)
+			})
+
+			It("restores them for the rest of their TTL only", func() {
+				Expect(restoredStore.GetContainerMetrics()).To(HaveLen(1))
+				Eventually(restoredStore.GetContainerMetrics).Should(BeEmpty())
+				Expect(restoredStore.GetValueMetrics()).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("LoadSnapshot", func() {
+		It("returns an error when the snapshot does not exist", func() {
+			err := restoredStore.LoadSnapshot(snapshotFile)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("returns an error when the snapshot is corrupted", func() {
+			Expect(ioutil.WriteFile(snapshotFile, []byte("corrupted"), 0600)).To(Succeed())
+			Expect(restoredStore.LoadSnapshot(snapshotFile)).ToNot(Succeed())
+		})
+	})
+
+	Describe("RunSnapshots", func() {
+		var snapshotsDone chan error
+
+		BeforeEach(func() {
+			snapshotsDone = make(chan error, 1)
+			go func(metricsStore *Store, snapshotFile string, snapshotsDone chan error) {
+				snapshotsDone <- metricsStore.RunSnapshots(snapshotFile, 10*time.Millisecond)
+			}(metricsStore, snapshotFile, snapshotsDone)
+		})
+
+		AfterEach(func() {
+			metricsStore.StopSnapshots()
+		})
+
+		It("saves snapshots periodically", func() {
+			Eventually(func() error { return restoredStore.LoadSnapshot(snapshotFile) }).Should(Succeed())
+			Expect(restoredStore.GetValueMetrics()).To(HaveLen(1))
+		})
+
+		It("returns when stopped", func() {
+			metricsStore.StopSnapshots()
+			Eventually(snapshotsDone).Should(Receive(BeNil()))
+		})
+	})
+})
@@ -2,23 +2,50 @@ package metrics
 
 import (
 	"bytes"
+	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/patrickmn/go-cache"
 )
 
+var (
+	HttpStartStopDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
 type Store struct {
 	metricsExpiration      time.Duration
 	metricsCleanupInterval time.Duration
 	deploymentFilter       *filters.DeploymentFilter
 	eventFilter            *filters.EventFilter
+	envelopeFilter         *filters.EnvelopeFilter
+	metricNameFilter       *filters.MetricNameFilter
+	expressionFilter       *filters.ExpressionFilter
+	routeTemplates         *utils.RouteTemplates
 	internalMetrics        *cache.Cache
 	containerMetrics       *cache.Cache
 	counterEvents          *cache.Cache
 	valueMetrics           *cache.Cache
+	httpStartStops         *cache.Cache
+	httpRoutes             *cache.Cache
+	logMessages            *cache.Cache
+	errors                 *cache.Cache
+	aggregationLock        sync.Mutex
+	settingsLock           sync.RWMutex
+	pipeline               *pipeline
+	cleanupDone            chan struct{}
+	cleanupDoneOnce        sync.Once
+	snapshotsDone          chan struct{}
+	snapshotsDoneOnce      sync.Once
+	seriesLimiter          *seriesLimiter
+	expirationPolicies     *ExpirationPolicies
+	valueAggregations      *ValueMetricAggregations
 }
 
 func NewStore(
@@ -26,27 +53,77 @@ func NewStore(
 	metricsCleanupInterval time.Duration,
 	deploymentFilter *filters.DeploymentFilter,
 	eventFilter *filters.EventFilter,
+	routeTemplates *utils.RouteTemplates,
 ) *Store {
-	internalMetrics := cache.New(metricsExpiration, metricsCleanupInterval)
-	containerMetrics := cache.New(metricsExpiration, metricsCleanupInterval)
-	counterEvents := cache.New(metricsExpiration, metricsCleanupInterval)
-	valueMetrics := cache.New(metricsExpiration, metricsCleanupInterval)
+	internalMetrics := cache.New(metricsExpiration, 0)
+	containerMetrics := cache.New(metricsExpiration, 0)
+	counterEvents := cache.New(metricsExpiration, 0)
+	valueMetrics := cache.New(metricsExpiration, 0)
+	httpStartStops := cache.New(metricsExpiration, 0)
+	httpRoutes := cache.New(metricsExpiration, 0)
+	logMessages := cache.New(metricsExpiration, 0)
+	errors := cache.New(metricsExpiration, 0)
 
 	store := &Store{
 		metricsExpiration:      metricsExpiration,
 		metricsCleanupInterval: metricsCleanupInterval,
 		deploymentFilter:       deploymentFilter,
 		eventFilter:            eventFilter,
+		routeTemplates:         routeTemplates,
 		internalMetrics:        internalMetrics,
 		containerMetrics:       containerMetrics,
 		counterEvents:          counterEvents,
 		valueMetrics:           valueMetrics,
+		httpStartStops:         httpStartStops,
+		httpRoutes:             httpRoutes,
+		logMessages:            logMessages,
+		errors:                 errors,
+		cleanupDone:            make(chan struct{}),
+		snapshotsDone:          make(chan struct{}),
+		expirationPolicies:     NewExpirationPolicies(metricsExpiration),
 	}
 	store.SetInternalMetrics(InternalMetrics{})
 
 	return store
 }
 
+// RunCleanup deletes the expired metrics every cleanup interval until StopCleanup is called.
+func (s *Store) RunCleanup() error {
+	if s.metricsCleanupInterval <= 0 {
+		<-s.cleanupDone
+		return nil
+	}
+
+	ticker := time.NewTicker(s.metricsCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.deleteExpired()
+		case <-s.cleanupDone:
+			return nil
+		}
+	}
+}
+
+func (s *Store) StopCleanup() {
+	s.cleanupDoneOnce.Do(func() {
+		close(s.cleanupDone)
+	})
+}
+
+func (s *Store) deleteExpired() {
+	s.internalMetrics.DeleteExpired()
+	s.containerMetrics.DeleteExpired()
+	s.counterEvents.DeleteExpired()
+	s.valueMetrics.DeleteExpired()
+	s.httpStartStops.DeleteExpired()
+	s.httpRoutes.DeleteExpired()
+	s.logMessages.DeleteExpired()
+	s.errors.DeleteExpired()
+}
+
 func (s *Store) GetInternalMetrics() InternalMetrics {
 	internalMetrics := InternalMetrics{}
 
@@ -94,6 +171,109 @@ func (s *Store) GetInternalMetrics() InternalMetrics {
 		internalMetrics.LastValueMetricReceivedTimestamp = lastValueMetricReceivedTimestamp.(int64)
 	}
 
+	if totalHttpStartStopsReceived, ok := s.internalMetrics.Get(TotalHttpStartStopsReceivedKey); ok {
+		internalMetrics.TotalHttpStartStopsReceived = totalHttpStartStopsReceived.(int64)
+	}
+	if totalHttpStartStopsProcessed, ok := s.internalMetrics.Get(TotalHttpStartStopsProcessedKey); ok {
+		internalMetrics.TotalHttpStartStopsProcessed = totalHttpStartStopsProcessed.(int64)
+	}
+	if lastHttpStartStopReceivedTimestamp, ok := s.internalMetrics.Get(LastHttpStartStopReceivedTimestampKey); ok {
+		internalMetrics.LastHttpStartStopReceivedTimestamp = lastHttpStartStopReceivedTimestamp.(int64)
+	}
+
+	if totalLogMessagesReceived, ok := s.internalMetrics.Get(TotalLogMessagesReceivedKey); ok {
+		internalMetrics.TotalLogMessagesReceived = totalLogMessagesReceived.(int64)
+	}
+	if totalLogMessagesProcessed, ok := s.internalMetrics.Get(TotalLogMessagesProcessedKey); ok {
+		internalMetrics.TotalLogMessagesProcessed = totalLogMessagesProcessed.(int64)
+	}
+	if lastLogMessageReceivedTimestamp, ok := s.internalMetrics.Get(LastLogMessageReceivedTimestampKey); ok {
+		internalMetrics.LastLogMessageReceivedTimestamp = lastLogMessageReceivedTimestamp.(int64)
+	}
+
+	if totalErrorsReceived, ok := s.internalMetrics.Get(TotalErrorsReceivedKey); ok {
+		internalMetrics.TotalErrorsReceived = totalErrorsReceived.(int64)
+	}
+	if totalErrorsProcessed, ok := s.internalMetrics.Get(TotalErrorsProcessedKey); ok {
+		internalMetrics.TotalErrorsProcessed = totalErrorsProcessed.(int64)
+	}
+	if lastErrorReceivedTimestamp, ok := s.internalMetrics.Get(LastErrorReceivedTimestampKey); ok {
+		internalMetrics.LastErrorReceivedTimestamp = lastErrorReceivedTimestamp.(int64)
+	}
+
+	if firehoseConnected, ok := s.internalMetrics.Get(FirehoseConnectedKey); ok {
+		internalMetrics.FirehoseConnected = firehoseConnected.(bool)
+	}
+	if totalFirehoseReconnects, ok := s.internalMetrics.Get(TotalFirehoseReconnectsKey); ok {
+		internalMetrics.TotalFirehoseReconnects = totalFirehoseReconnects.(int64)
+	}
+	if lastFirehoseReconnectTimestamp, ok := s.internalMetrics.Get(LastFirehoseReconnectTimestampKey); ok {
+		internalMetrics.LastFirehoseReconnectTimestamp = lastFirehoseReconnectTimestamp.(int64)
+	}
+
+	if envelopeQueueDepth, ok := s.internalMetrics.Get(EnvelopeQueueDepthKey); ok {
+		internalMetrics.EnvelopeQueueDepth = envelopeQueueDepth.(int64)
+	}
+	if totalEnvelopesDequeued, ok := s.internalMetrics.Get(TotalEnvelopesDequeuedKey); ok {
+		internalMetrics.TotalEnvelopesDequeued = totalEnvelopesDequeued.(int64)
+	}
+	if totalEnvelopeQueueLatencyNanoseconds, ok := s.internalMetrics.Get(TotalEnvelopeQueueLatencyNanosecondsKey); ok {
+		internalMetrics.TotalEnvelopeQueueLatencyNanoseconds = totalEnvelopeQueueLatencyNanoseconds.(int64)
+	}
+	if totalEnvelopesDropped, ok := s.internalMetrics.Get(TotalEnvelopesDroppedKey); ok {
+		internalMetrics.TotalEnvelopesDropped = totalEnvelopesDropped.(int64)
+	}
+
+	if totalFirehoseConnections, ok := s.internalMetrics.Get(TotalFirehoseConnectionsKey); ok {
+		internalMetrics.TotalFirehoseConnections = totalFirehoseConnections.(int64)
+	}
+	if lastFirehoseConnectTimestamp, ok := s.internalMetrics.Get(LastFirehoseConnectTimestampKey); ok {
+		internalMetrics.LastFirehoseConnectTimestamp = lastFirehoseConnectTimestamp.(int64)
+	}
+	if totalEnvelopeBytesDecoded, ok := s.internalMetrics.Get(TotalEnvelopeBytesDecodedKey); ok {
+		internalMetrics.TotalEnvelopeBytesDecoded = totalEnvelopeBytesDecoded.(int64)
+	}
+
+	if activeDopplerEndpoint, ok := s.internalMetrics.Get(ActiveDopplerEndpointKey); ok {
+		internalMetrics.ActiveDopplerEndpoint = activeDopplerEndpoint.(string)
+	}
+
+	internalMetrics.TotalFirehoseDisconnects = map[int]int64{}
+	if totalFirehoseDisconnects, ok := s.internalMetrics.Get(TotalFirehoseDisconnectsKey); ok {
+		for closeCode, disconnects := range totalFirehoseDisconnects.(map[int]int64) {
+			internalMetrics.TotalFirehoseDisconnects[closeCode] = disconnects
+		}
+	}
+
+	internalMetrics.TotalSeriesEvicted = map[string]int64{}
+	if totalSeriesEvicted, ok := s.internalMetrics.Get(TotalSeriesEvictedKey); ok {
+		for reason, evicted := range totalSeriesEvicted.(map[string]int64) {
+			internalMetrics.TotalSeriesEvicted[reason] = evicted
+		}
+	}
+
+	internalMetrics.TotalSeriesRejected = map[string]int64{}
+	if totalSeriesRejected, ok := s.internalMetrics.Get(TotalSeriesRejectedKey); ok {
+		for reason, rejected := range totalSeriesRejected.(map[string]int64) {
+			internalMetrics.TotalSeriesRejected[reason] = rejected
+		}
+	}
+
+	if totalDopplerEndpointFailovers, ok := s.internalMetrics.Get(TotalDopplerEndpointFailoversKey); ok {
+		internalMetrics.TotalDopplerEndpointFailovers = totalDopplerEndpointFailovers.(int64)
+	}
+
+	if totalCounterEventResets, ok := s.internalMetrics.Get(TotalCounterEventResetsKey); ok {
+		internalMetrics.TotalCounterEventResets = totalCounterEventResets.(int64)
+	}
+
+	internalMetrics.TotalEnvelopesFiltered = map[FilteredEnvelopes]int64{}
+	for key, item := range s.internalMetrics.Items() {
+		if filteredEnvelopes, ok := parseFilteredEnvelopesKey(key); ok {
+			internalMetrics.TotalEnvelopesFiltered[filteredEnvelopes] = item.Object.(int64)
+		}
+	}
+
 	if slowConsumerAlert, ok := s.internalMetrics.Get(SlowConsumerAlertKey); ok {
 		internalMetrics.SlowConsumerAlert = slowConsumerAlert.(bool)
 	} else {
@@ -120,6 +300,38 @@ func (s *Store) SetInternalMetrics(internalMetrics InternalMetrics) {
 	s.internalMetrics.Set(TotalValueMetricsReceivedKey, int64(internalMetrics.TotalValueMetricsReceived), cache.NoExpiration)
 	s.internalMetrics.Set(TotalValueMetricsProcessedKey, int64(internalMetrics.TotalValueMetricsProcessed), cache.NoExpiration)
 	s.internalMetrics.Set(LastValueMetricReceivedTimestampKey, int64(internalMetrics.LastValueMetricReceivedTimestamp), cache.NoExpiration)
+	s.internalMetrics.Set(TotalHttpStartStopsReceivedKey, int64(internalMetrics.TotalHttpStartStopsReceived), cache.NoExpiration)
+	s.internalMetrics.Set(TotalHttpStartStopsProcessedKey, int64(internalMetrics.TotalHttpStartStopsProcessed), cache.NoExpiration)
+	s.internalMetrics.Set(LastHttpStartStopReceivedTimestampKey, int64(internalMetrics.LastHttpStartStopReceivedTimestamp), cache.NoExpiration)
+	s.internalMetrics.Set(TotalLogMessagesReceivedKey, int64(internalMetrics.TotalLogMessagesReceived), cache.NoExpiration)
+	s.internalMetrics.Set(TotalLogMessagesProcessedKey, int64(internalMetrics.TotalLogMessagesProcessed), cache.NoExpiration)
+	s.internalMetrics.Set(LastLogMessageReceivedTimestampKey, int64(internalMetrics.LastLogMessageReceivedTimestamp), cache.NoExpiration)
+	s.internalMetrics.Set(TotalErrorsReceivedKey, int64(internalMetrics.TotalErrorsReceived), cache.NoExpiration)
+	s.internalMetrics.Set(TotalErrorsProcessedKey, int64(internalMetrics.TotalErrorsProcessed), cache.NoExpiration)
+	s.internalMetrics.Set(LastErrorReceivedTimestampKey, int64(internalMetrics.LastErrorReceivedTimestamp), cache.NoExpiration)
+	s.internalMetrics.Set(FirehoseConnectedKey, internalMetrics.FirehoseConnected, cache.NoExpiration)
+	s.internalMetrics.Set(TotalFirehoseReconnectsKey, int64(internalMetrics.TotalFirehoseReconnects), cache.NoExpiration)
+	s.internalMetrics.Set(LastFirehoseReconnectTimestampKey, int64(internalMetrics.LastFirehoseReconnectTimestamp), cache.NoExpiration)
+	s.internalMetrics.Set(EnvelopeQueueDepthKey, int64(internalMetrics.EnvelopeQueueDepth), cache.NoExpiration)
+	s.internalMetrics.Set(TotalEnvelopesDequeuedKey, int64(internalMetrics.TotalEnvelopesDequeued), cache.NoExpiration)
+	s.internalMetrics.Set(TotalEnvelopeQueueLatencyNanosecondsKey, int64(internalMetrics.TotalEnvelopeQueueLatencyNanoseconds), cache.NoExpiration)
+	s.internalMetrics.Set(TotalEnvelopesDroppedKey, int64(internalMetrics.TotalEnvelopesDropped), cache.NoExpiration)
+	s.internalMetrics.Set(TotalFirehoseConnectionsKey, int64(internalMetrics.TotalFirehoseConnections), cache.NoExpiration)
+	s.internalMetrics.Set(LastFirehoseConnectTimestampKey, int64(internalMetrics.LastFirehoseConnectTimestamp), cache.NoExpiration)
+	s.internalMetrics.Set(TotalEnvelopeBytesDecodedKey, int64(internalMetrics.TotalEnvelopeBytesDecoded), cache.NoExpiration)
+	s.internalMetrics.Set(ActiveDopplerEndpointKey, internalMetrics.ActiveDopplerEndpoint, cache.NoExpiration)
+	totalFirehoseDisconnects := map[int]int64{}
+	for closeCode, disconnects := range internalMetrics.TotalFirehoseDisconnects {
+		totalFirehoseDisconnects[closeCode] = disconnects
+	}
+	s.internalMetrics.Set(TotalFirehoseDisconnectsKey, totalFirehoseDisconnects, cache.NoExpiration)
+	s.internalMetrics.Set(TotalDopplerEndpointFailoversKey, int64(internalMetrics.TotalDopplerEndpointFailovers), cache.NoExpiration)
+	s.internalMetrics.Set(TotalSeriesEvictedKey, copyReasons(internalMetrics.TotalSeriesEvicted), cache.NoExpiration)
+	s.internalMetrics.Set(TotalSeriesRejectedKey, copyReasons(internalMetrics.TotalSeriesRejected), cache.NoExpiration)
+	s.internalMetrics.Set(TotalCounterEventResetsKey, int64(internalMetrics.TotalCounterEventResets), cache.NoExpiration)
+	for filteredEnvelopes, filtered := range internalMetrics.TotalEnvelopesFiltered {
+		s.internalMetrics.Set(filteredEnvelopesKey(filteredEnvelopes), filtered, cache.NoExpiration)
+	}
 	s.internalMetrics.Set(SlowConsumerAlertKey, internalMetrics.SlowConsumerAlert, cache.DefaultExpiration)
 	s.internalMetrics.Set(LastSlowConsumerAlertTimestampKey, int64(internalMetrics.LastSlowConsumerAlertTimestamp), cache.NoExpiration)
 }
@@ -129,7 +341,206 @@ func (s *Store) AlertSlowConsumerError() {
 	s.internalMetrics.Set(LastSlowConsumerAlertTimestampKey, time.Now().Unix(), cache.NoExpiration)
 }
 
+func (s *Store) SetFirehoseConnected(connected bool) {
+	s.internalMetrics.Set(FirehoseConnectedKey, connected, cache.NoExpiration)
+}
+
+func (s *Store) AddFirehoseReconnect() {
+	s.internalMetrics.IncrementInt64(TotalFirehoseReconnectsKey, 1)
+	s.internalMetrics.Set(LastFirehoseReconnectTimestampKey, time.Now().Unix(), cache.NoExpiration)
+}
+
+func (s *Store) AddFirehoseConnect() {
+	s.internalMetrics.IncrementInt64(TotalFirehoseConnectionsKey, 1)
+	s.internalMetrics.Set(LastFirehoseConnectTimestampKey, time.Now().Unix(), cache.NoExpiration)
+	s.SetFirehoseConnected(true)
+}
+
+// AddFirehoseDisconnect accounts a dropped connection by its websocket close code.
+func (s *Store) AddFirehoseDisconnect(closeCode int) {
+	s.aggregationLock.Lock()
+	defer s.aggregationLock.Unlock()
+
+	totalFirehoseDisconnects := map[int]int64{}
+	if current, ok := s.internalMetrics.Get(TotalFirehoseDisconnectsKey); ok {
+		for code, disconnects := range current.(map[int]int64) {
+			totalFirehoseDisconnects[code] = disconnects
+		}
+	}
+	totalFirehoseDisconnects[closeCode]++
+	s.internalMetrics.Set(TotalFirehoseDisconnectsKey, totalFirehoseDisconnects, cache.NoExpiration)
+	s.SetFirehoseConnected(false)
+}
+
+// SetExpirationPolicies replaces the default expiration policies of the metrics added afterwards.
+func (s *Store) SetExpirationPolicies(expirationPolicies *ExpirationPolicies) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.expirationPolicies = expirationPolicies
+}
+
+// SetDeploymentFilter replaces the deployment filter the store was created with.
+func (s *Store) SetDeploymentFilter(deploymentFilter *filters.DeploymentFilter) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.deploymentFilter = deploymentFilter
+}
+
+// SetEventFilter replaces the event filter the store was created with.
+func (s *Store) SetEventFilter(eventFilter *filters.EventFilter) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.eventFilter = eventFilter
+}
+
+// SetEnvelopeFilter only processes the envelopes enabled by envelopeFilter, on top of the deployment
+// and event filters.
+func (s *Store) SetEnvelopeFilter(envelopeFilter *filters.EnvelopeFilter) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.envelopeFilter = envelopeFilter
+}
+
+// SetMetricNameFilter only stores the Counter Events and Value Metrics enabled by metricNameFilter.
+func (s *Store) SetMetricNameFilter(metricNameFilter *filters.MetricNameFilter) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.metricNameFilter = metricNameFilter
+}
+
+// SetExpressionFilter only processes the envelopes for which the expression filter is true.
+func (s *Store) SetExpressionFilter(expressionFilter *filters.ExpressionFilter) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.expressionFilter = expressionFilter
+}
+
+// SetValueMetricAggregations aggregates the samples of the selected Value Metrics, nil disables it.
+func (s *Store) SetValueMetricAggregations(valueAggregations *ValueMetricAggregations) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.valueAggregations = valueAggregations
+}
+
+// SetSeriesLimits caps the number of Counter Event and Value Metric series, it must be called before
+// adding metrics.
+func (s *Store) SetSeriesLimits(limits SeriesLimits) {
+	if !limits.enabled() {
+		s.seriesLimiter = nil
+		return
+	}
+
+	s.seriesLimiter = newSeriesLimiter(limits)
+	s.counterEvents.OnEvicted(func(key string, _ interface{}) {
+		s.seriesLimiter.forget(s.counterEvents, key)
+	})
+	s.valueMetrics.OnEvicted(func(key string, _ interface{}) {
+		s.seriesLimiter.forget(s.valueMetrics, key)
+	})
+	s.trackExistingSeries()
+}
+
+func (s *Store) trackExistingSeries() {
+	if s.seriesLimiter == nil {
+		return
+	}
+
+	var counterEvents []existingSeries
+	for key, item := range s.counterEvents.Items() {
+		counterEvent := item.Object.(CounterEvent)
+		counterEvents = append(counterEvents, existingSeries{key, counterEvent.Origin, counterEvent.Name, counterEvent.Timestamp})
+	}
+	s.seriesLimiter.track(s.counterEvents, counterEvents)
+
+	var valueMetrics []existingSeries
+	for key, item := range s.valueMetrics.Items() {
+		valueMetric := item.Object.(ValueMetric)
+		valueMetrics = append(valueMetrics, existingSeries{key, valueMetric.Origin, valueMetric.Name, valueMetric.Timestamp})
+	}
+	s.seriesLimiter.track(s.valueMetrics, valueMetrics)
+}
+
+// admitSeries checks a series against the series limits, evicting the series making room for it.
+func (s *Store) admitSeries(c *cache.Cache, key string, origin string, name string) bool {
+	if s.seriesLimiter == nil {
+		return true
+	}
+
+	evictions, ok, reason := s.seriesLimiter.admit(c, key, origin, name)
+	if !ok {
+		s.addSeriesReason(TotalSeriesRejectedKey, reason)
+		return false
+	}
+
+	for _, eviction := range evictions {
+		eviction.series.cache.Delete(eviction.series.cacheKey)
+		s.addSeriesReason(TotalSeriesEvictedKey, eviction.reason)
+	}
+	return true
+}
+
+// addFilteredEnvelope counts the envelopes dropped by a filter under a key per filter and origin. The key
+// is added by the first envelope, the increment is retried when another one added it in between.
+func (s *Store) addFilteredEnvelope(filter string, origin string) {
+	key := filteredEnvelopesKey(FilteredEnvelopes{Filter: filter, Origin: origin})
+	if _, err := s.internalMetrics.IncrementInt64(key, 1); err == nil {
+		return
+	}
+	if err := s.internalMetrics.Add(key, int64(1), cache.NoExpiration); err != nil {
+		s.internalMetrics.IncrementInt64(key, 1)
+	}
+}
+
+func filteredEnvelopesKey(filteredEnvelopes FilteredEnvelopes) string {
+	return TotalEnvelopesFilteredKey + "/" + filteredEnvelopes.Filter + "/" + filteredEnvelopes.Origin
+}
+
+func parseFilteredEnvelopesKey(key string) (FilteredEnvelopes, bool) {
+	if !strings.HasPrefix(key, TotalEnvelopesFilteredKey+"/") {
+		return FilteredEnvelopes{}, false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(key, TotalEnvelopesFilteredKey+"/"), "/", 2)
+	if len(parts) != 2 {
+		return FilteredEnvelopes{}, false
+	}
+	return FilteredEnvelopes{Filter: parts[0], Origin: parts[1]}, true
+}
+
+func (s *Store) addSeriesReason(key string, reason string) {
+	s.aggregationLock.Lock()
+	defer s.aggregationLock.Unlock()
+
+	reasons := map[string]int64{}
+	if current, ok := s.internalMetrics.Get(key); ok {
+		reasons = copyReasons(current.(map[string]int64))
+	}
+	reasons[reason]++
+	s.internalMetrics.Set(key, reasons, cache.NoExpiration)
+}
+
+func (s *Store) AddEnvelopeBytesDecoded(bytesDecoded int64) {
+	s.internalMetrics.IncrementInt64(TotalEnvelopeBytesDecodedKey, bytesDecoded)
+}
+
+func (s *Store) SetActiveDopplerEndpoint(endpoint string) {
+	s.internalMetrics.Set(ActiveDopplerEndpointKey, endpoint, cache.NoExpiration)
+}
+
+func (s *Store) AddDopplerEndpointFailover() {
+	s.internalMetrics.IncrementInt64(TotalDopplerEndpointFailoversKey, 1)
+}
+
 func (s *Store) AddMetric(envelope *events.Envelope) {
+	if s.pipeline != nil {
+		s.pipeline.enqueue(envelope)
+		return
+	}
+
+	s.processMetric(envelope)
+}
+
+func (s *Store) processMetric(envelope *events.Envelope) {
 	s.internalMetrics.IncrementInt64(TotalEnvelopesReceivedKey, 1)
 	s.internalMetrics.Set(LastEnvelopReceivedTimestampKey, time.Now().Unix(), cache.NoExpiration)
 
@@ -140,6 +551,12 @@ func (s *Store) AddMetric(envelope *events.Envelope) {
 		s.addCounterEvent(envelope)
 	case events.Envelope_ValueMetric:
 		s.addValueMetric(envelope)
+	case events.Envelope_HttpStartStop:
+		s.addHttpStartStop(envelope)
+	case events.Envelope_LogMessage:
+		s.addLogMessage(envelope)
+	case events.Envelope_Error:
+		s.addError(envelope)
 	}
 }
 
@@ -169,13 +586,22 @@ func (s *Store) GetCounterEvents() CounterEvents {
 
 func (s *Store) FlushCounterEvents() {
 	s.counterEvents.Flush()
+	if s.seriesLimiter != nil {
+		s.seriesLimiter.forgetAll(s.counterEvents)
+	}
 }
 
 func (s *Store) GetValueMetrics() ValueMetrics {
 	valueMetrics := ValueMetrics{}
-	for _, valueMetric := range s.valueMetrics.Items() {
-		if !valueMetric.Expired() {
-			valueMetrics = append(valueMetrics, valueMetric.Object.(ValueMetric))
+	valueAggregations := s.valueMetricAggregations()
+	now := time.Now()
+	for _, item := range s.valueMetrics.Items() {
+		if !item.Expired() {
+			valueMetric := item.Object.(ValueMetric)
+			if valueMetric.samples != nil {
+				valueAggregations.aggregate(&valueMetric, now)
+			}
+			valueMetrics = append(valueMetrics, valueMetric)
 		}
 	}
 	return valueMetrics
@@ -183,6 +609,120 @@ func (s *Store) GetValueMetrics() ValueMetrics {
 
 func (s *Store) FlushValueMetrics() {
 	s.valueMetrics.Flush()
+	if s.seriesLimiter != nil {
+		s.seriesLimiter.forgetAll(s.valueMetrics)
+	}
+}
+
+func (s *Store) GetHttpStartStops() HttpStartStops {
+	httpStartStops := HttpStartStops{}
+	for _, httpStartStop := range s.httpStartStops.Items() {
+		if !httpStartStop.Expired() {
+			httpStartStops = append(httpStartStops, httpStartStop.Object.(HttpStartStop))
+		}
+	}
+	return httpStartStops
+}
+
+func (s *Store) FlushHttpStartStops() {
+	s.httpStartStops.Flush()
+}
+
+func (s *Store) GetHttpRoutes() HttpRoutes {
+	httpRoutes := HttpRoutes{}
+	for _, httpRoute := range s.httpRoutes.Items() {
+		if !httpRoute.Expired() {
+			httpRoutes = append(httpRoutes, httpRoute.Object.(HttpRoute))
+		}
+	}
+	return httpRoutes
+}
+
+func (s *Store) FlushHttpRoutes() {
+	s.httpRoutes.Flush()
+}
+
+func (s *Store) GetLogMessages() LogMessages {
+	logMessages := LogMessages{}
+	for _, logMessage := range s.logMessages.Items() {
+		if !logMessage.Expired() {
+			logMessages = append(logMessages, logMessage.Object.(LogMessage))
+		}
+	}
+	return logMessages
+}
+
+func (s *Store) FlushLogMessages() {
+	s.logMessages.Flush()
+}
+
+func (s *Store) GetErrors() Errors {
+	errors := Errors{}
+	for _, errorEvent := range s.errors.Items() {
+		if !errorEvent.Expired() {
+			errors = append(errors, errorEvent.Object.(Error))
+		}
+	}
+	return errors
+}
+
+func (s *Store) FlushErrors() {
+	s.errors.Flush()
+}
+
+func (s *Store) enabled(envelope *events.Envelope) bool {
+	if filter := s.disabledBy(envelope); filter != "" {
+		s.addFilteredEnvelope(filter, envelope.GetOrigin())
+		return false
+	}
+	return true
+}
+
+func (s *Store) metricEnabled(envelope *events.Envelope) bool {
+	if !s.enabled(envelope) {
+		return false
+	}
+
+	s.settingsLock.RLock()
+	enabled := s.metricNameFilter.Enabled(envelope)
+	s.settingsLock.RUnlock()
+
+	if !enabled {
+		s.addFilteredEnvelope("metric_name", envelope.GetOrigin())
+	}
+	return enabled
+}
+
+// disabledBy returns the filter dropping an envelope, or an empty string when the envelope is enabled.
+func (s *Store) disabledBy(envelope *events.Envelope) string {
+	s.settingsLock.RLock()
+	defer s.settingsLock.RUnlock()
+
+	if !s.deploymentFilter.Enabled(envelope.GetDeployment()) {
+		return "deployment"
+	}
+	if !s.eventFilter.Enabled(envelope) {
+		return "event_type"
+	}
+	if field := s.envelopeFilter.DisabledBy(envelope); field != "" {
+		return field + "_pattern"
+	}
+	if !s.expressionFilter.Enabled(envelope) {
+		return "expression"
+	}
+	return ""
+}
+
+func (s *Store) expiration(envelope *events.Envelope) time.Duration {
+	s.settingsLock.RLock()
+	defer s.settingsLock.RUnlock()
+	return s.expirationPolicies.Expiration(envelope)
+}
+
+func (s *Store) valueMetricAggregations() *ValueMetricAggregations {
+	s.settingsLock.RLock()
+	defer s.settingsLock.RUnlock()
+	return s.valueAggregations
 }
 
 func (s *Store) addContainerMetric(envelope *events.Envelope) {
@@ -191,7 +731,7 @@ func (s *Store) addContainerMetric(envelope *events.Envelope) {
 	s.internalMetrics.IncrementInt64(TotalContainerMetricsReceivedKey, 1)
 	s.internalMetrics.Set(LastContainerMetricReceivedTimestampKey, time.Now().Unix(), cache.NoExpiration)
 
-	if s.deploymentFilter.Enabled(envelope.GetDeployment()) && s.eventFilter.Enabled(envelope) {
+	if s.enabled(envelope) {
 		s.internalMetrics.IncrementInt64(TotalContainerMetricsProcessedKey, 1)
 
 		containerMetric := ContainerMetric{
@@ -210,7 +750,7 @@ func (s *Store) addContainerMetric(envelope *events.Envelope) {
 			MemoryBytesQuota: envelope.GetContainerMetric().GetMemoryBytesQuota(),
 			DiskBytesQuota:   envelope.GetContainerMetric().GetDiskBytesQuota(),
 		}
-		s.containerMetrics.Set(s.metricKey(envelope), containerMetric, cache.DefaultExpiration)
+		s.containerMetrics.Set(s.metricKey(envelope), containerMetric, s.expiration(envelope))
 	}
 }
 
@@ -220,9 +760,17 @@ func (s *Store) addCounterEvent(envelope *events.Envelope) {
 	s.internalMetrics.IncrementInt64(TotalCounterEventsReceivedKey, 1)
 	s.internalMetrics.Set(LastCounterEventReceivedTimestampKey, time.Now().Unix(), cache.NoExpiration)
 
-	if s.deploymentFilter.Enabled(envelope.GetDeployment()) && s.eventFilter.Enabled(envelope) {
+	if s.metricEnabled(envelope) {
+		key := s.metricKey(envelope)
+		if !s.admitSeries(s.counterEvents, key, envelope.GetOrigin(), envelope.GetCounterEvent().GetName()) {
+			return
+		}
+
 		s.internalMetrics.IncrementInt64(TotalCounterEventsProcessedKey, 1)
 
+		s.aggregationLock.Lock()
+		defer s.aggregationLock.Unlock()
+
 		counterEvent := CounterEvent{
 			Origin:     envelope.GetOrigin(),
 			Timestamp:  envelope.GetTimestamp(),
@@ -235,7 +783,34 @@ func (s *Store) addCounterEvent(envelope *events.Envelope) {
 			Delta:      envelope.GetCounterEvent().GetDelta(),
 			Total:      envelope.GetCounterEvent().GetTotal(),
 		}
-		s.counterEvents.Set(s.metricKey(envelope), counterEvent, cache.NoExpiration)
+		if cached, ok := s.counterEvents.Get(key); ok {
+			previous := cached.(CounterEvent)
+			increase, reset := counterIncrease(previous, counterEvent)
+			if reset {
+				s.internalMetrics.IncrementInt64(TotalCounterEventResetsKey, 1)
+			}
+			counterEvent.Counter = previous.Counter + increase
+		} else if counterEvent.Total > 0 {
+			counterEvent.Counter = counterEvent.Total
+		} else {
+			counterEvent.Counter = counterEvent.Delta
+		}
+		s.counterEvents.Set(key, counterEvent, s.expiration(envelope))
+	}
+}
+
+// counterIncrease returns how much a Counter Event series increased since its previous event, and
+// whether its emitter reset the total in between. The totals account for the events lost on the way,
+// while emitters only sending deltas are added up delta by delta. When a series starts sending totals,
+// the first total is the baseline of the next ones.
+func counterIncrease(previous CounterEvent, current CounterEvent) (uint64, bool) {
+	switch {
+	case previous.Total == 0:
+		return current.Delta, false
+	case current.Total < previous.Total:
+		return current.Total, true
+	default:
+		return current.Total - previous.Total, false
 	}
 }
 
@@ -245,7 +820,12 @@ func (s *Store) addValueMetric(envelope *events.Envelope) {
 	s.internalMetrics.IncrementInt64(TotalValueMetricsReceivedKey, 1)
 	s.internalMetrics.Set(LastValueMetricReceivedTimestampKey, time.Now().Unix(), cache.NoExpiration)
 
-	if s.deploymentFilter.Enabled(envelope.GetDeployment()) && s.eventFilter.Enabled(envelope) {
+	if s.metricEnabled(envelope) {
+		key := s.metricKey(envelope)
+		if !s.admitSeries(s.valueMetrics, key, envelope.GetOrigin(), envelope.GetValueMetric().GetName()) {
+			return
+		}
+
 		s.internalMetrics.IncrementInt64(TotalValueMetricsProcessedKey, 1)
 
 		valueMetric := ValueMetric{
@@ -260,14 +840,184 @@ func (s *Store) addValueMetric(envelope *events.Envelope) {
 			Value:      envelope.GetValueMetric().GetValue(),
 			Unit:       envelope.GetValueMetric().GetUnit(),
 		}
-		s.valueMetrics.Set(s.metricKey(envelope), valueMetric, cache.NoExpiration)
+		valueAggregations := s.valueMetricAggregations()
+		if valueAggregations.enabled(valueMetric.Origin, valueMetric.Name) {
+			s.aggregationLock.Lock()
+			defer s.aggregationLock.Unlock()
+
+			var samples []valueMetricSample
+			if cached, ok := s.valueMetrics.Get(key); ok {
+				samples = cached.(ValueMetric).samples
+			}
+			valueMetric.samples = valueAggregations.addSample(samples, valueMetric.Value, time.Now())
+		}
+		s.valueMetrics.Set(key, valueMetric, s.expiration(envelope))
+	}
+}
+
+func (s *Store) addHttpStartStop(envelope *events.Envelope) {
+	s.internalMetrics.IncrementInt64(TotalHttpStartStopsReceivedKey, 1)
+	s.internalMetrics.Set(LastHttpStartStopReceivedTimestampKey, time.Now().Unix(), cache.NoExpiration)
+
+	// Both the gorouter and the application emit an event for each request, only the gorouter one is
+	// counted so that requests are not counted twice.
+	if !s.enabled(envelope) || envelope.GetOrigin() != "gorouter" || envelope.GetHttpStartStop().GetPeerType() != events.PeerType_Client {
+		return
+	}
+
+	addedApplication := s.addHttpApplication(envelope)
+	addedRoute := s.addHttpRoute(envelope)
+	if addedApplication || addedRoute {
+		s.internalMetrics.IncrementInt64(TotalHttpStartStopsProcessedKey, 1)
+	}
+}
+
+func (s *Store) addHttpApplication(envelope *events.Envelope) bool {
+	applicationId := utils.FormatUUID(envelope.GetHttpStartStop().GetApplicationId())
+	if applicationId == "" {
+		return false
+	}
+
+	method := envelope.GetHttpStartStop().GetMethod().String()
+	key := seriesKey(applicationId, method)
+
+	s.aggregationLock.Lock()
+	defer s.aggregationLock.Unlock()
+
+	httpStartStop := HttpStartStop{
+		ApplicationId:   applicationId,
+		Method:          method,
+		Requests:        map[string]uint64{},
+		DurationBuckets: newDurationBuckets(),
+	}
+	if cached, ok := s.httpStartStops.Get(key); ok {
+		httpStartStop = copyHttpStartStop(cached.(HttpStartStop))
+	}
+	httpStartStop.Timestamp = envelope.GetTimestamp()
+	httpStartStop.Requests[statusCodeClass(envelope.GetHttpStartStop().GetStatusCode())]++
+
+	if duration, ok := httpDuration(envelope.GetHttpStartStop()); ok {
+		httpStartStop.DurationCount++
+		httpStartStop.DurationSum += duration
+		observeDuration(httpStartStop.DurationBuckets, duration)
+	}
+
+	s.httpStartStops.Set(key, httpStartStop, s.expiration(envelope))
+	return true
+}
+
+func (s *Store) addHttpRoute(envelope *events.Envelope) bool {
+	host, path := parseHttpUri(envelope.GetHttpStartStop().GetUri())
+	if host == "" {
+		return false
+	}
+
+	path = s.routeTemplates.Match(path)
+	method := envelope.GetHttpStartStop().GetMethod().String()
+	key := seriesKey(host, path, method)
+
+	s.aggregationLock.Lock()
+	defer s.aggregationLock.Unlock()
+
+	httpRoute := HttpRoute{
+		Host:            host,
+		Path:            path,
+		Method:          method,
+		Requests:        map[string]uint64{},
+		DurationBuckets: newDurationBuckets(),
+	}
+	if cached, ok := s.httpRoutes.Get(key); ok {
+		httpRoute = copyHttpRoute(cached.(HttpRoute))
+	}
+	httpRoute.Timestamp = envelope.GetTimestamp()
+	httpRoute.Requests[statusCodeClass(envelope.GetHttpStartStop().GetStatusCode())]++
+	if contentLength := envelope.GetHttpStartStop().GetContentLength(); contentLength > 0 {
+		httpRoute.ResponseBytes += uint64(contentLength)
+	}
+
+	if duration, ok := httpDuration(envelope.GetHttpStartStop()); ok {
+		httpRoute.DurationCount++
+		httpRoute.DurationSum += duration
+		observeDuration(httpRoute.DurationBuckets, duration)
+	}
+
+	s.httpRoutes.Set(key, httpRoute, s.expiration(envelope))
+	return true
+}
+
+func (s *Store) addLogMessage(envelope *events.Envelope) {
+	s.internalMetrics.IncrementInt64(TotalLogMessagesReceivedKey, 1)
+	s.internalMetrics.Set(LastLogMessageReceivedTimestampKey, time.Now().Unix(), cache.NoExpiration)
+
+	if s.enabled(envelope) {
+		s.internalMetrics.IncrementInt64(TotalLogMessagesProcessedKey, 1)
+
+		applicationId := envelope.GetLogMessage().GetAppId()
+		sourceType := envelope.GetLogMessage().GetSourceType()
+		messageType := envelope.GetLogMessage().GetMessageType().String()
+		key := seriesKey(applicationId, sourceType, messageType)
+
+		s.aggregationLock.Lock()
+		defer s.aggregationLock.Unlock()
+
+		logMessage := LogMessage{
+			ApplicationId: applicationId,
+			SourceType:    sourceType,
+			MessageType:   messageType,
+		}
+		if cached, ok := s.logMessages.Get(key); ok {
+			logMessage = cached.(LogMessage)
+		}
+		logMessage.Timestamp = envelope.GetTimestamp()
+		logMessage.Messages++
+		logMessage.Bytes += uint64(len(envelope.GetLogMessage().GetMessage()))
+
+		s.logMessages.Set(key, logMessage, s.expiration(envelope))
 	}
 }
 
+func (s *Store) addError(envelope *events.Envelope) {
+	s.internalMetrics.IncrementInt64(TotalErrorsReceivedKey, 1)
+	s.internalMetrics.Set(LastErrorReceivedTimestampKey, time.Now().Unix(), cache.NoExpiration)
+
+	if s.enabled(envelope) {
+		s.internalMetrics.IncrementInt64(TotalErrorsProcessedKey, 1)
+
+		origin := envelope.GetOrigin()
+		source := envelope.GetError().GetSource()
+		code := envelope.GetError().GetCode()
+		key := seriesKey(origin, source, strconv.Itoa(int(code)))
+
+		s.aggregationLock.Lock()
+		defer s.aggregationLock.Unlock()
+
+		errorEvent := Error{
+			Origin: origin,
+			Source: source,
+			Code:   code,
+		}
+		if cached, ok := s.errors.Get(key); ok {
+			errorEvent = cached.(Error)
+		}
+		errorEvent.Timestamp = envelope.GetTimestamp()
+		errorEvent.Message = envelope.GetError().GetMessage()
+		errorEvent.Count++
+
+		s.errors.Set(key, errorEvent, s.expiration(envelope))
+	}
+}
+
+// seriesKey joins the label values of a series with a separator they cannot contain, so that different
+// label values never give the same key.
+func seriesKey(values ...string) string {
+	return strings.Join(values, "\x00")
+}
+
 func (s *Store) metricKey(envelope *events.Envelope) string {
 	var buffer bytes.Buffer
 
 	buffer.WriteString(envelope.GetOrigin())
+
 	buffer.WriteString(envelope.GetDeployment())
 	buffer.WriteString(envelope.GetJob())
 	buffer.WriteString(envelope.GetIndex())
@@ -285,3 +1035,89 @@ func (s *Store) metricKey(envelope *events.Envelope) string {
 
 	return buffer.String()
 }
+
+func copyHttpStartStop(httpStartStop HttpStartStop) HttpStartStop {
+	httpStartStop.Requests = copyRequests(httpStartStop.Requests)
+	httpStartStop.DurationBuckets = copyDurationBuckets(httpStartStop.DurationBuckets)
+	return httpStartStop
+}
+
+func copyHttpRoute(httpRoute HttpRoute) HttpRoute {
+	httpRoute.Requests = copyRequests(httpRoute.Requests)
+	httpRoute.DurationBuckets = copyDurationBuckets(httpRoute.DurationBuckets)
+	return httpRoute
+}
+
+func copyReasons(reasons map[string]int64) map[string]int64 {
+	reasonsCopy := make(map[string]int64, len(reasons))
+	for reason, count := range reasons {
+		reasonsCopy[reason] = count
+	}
+	return reasonsCopy
+}
+
+func copyRequests(requests map[string]uint64) map[string]uint64 {
+	requestsCopy := make(map[string]uint64, len(requests))
+	for statusCode, count := range requests {
+		requestsCopy[statusCode] = count
+	}
+	return requestsCopy
+}
+
+func copyDurationBuckets(durationBuckets map[float64]uint64) map[float64]uint64 {
+	durationBucketsCopy := make(map[float64]uint64, len(durationBuckets))
+	for bucket, count := range durationBuckets {
+		durationBucketsCopy[bucket] = count
+	}
+	return durationBucketsCopy
+}
+
+func newDurationBuckets() map[float64]uint64 {
+	durationBuckets := make(map[float64]uint64, len(HttpStartStopDurationBuckets))
+	for _, bucket := range HttpStartStopDurationBuckets {
+		durationBuckets[bucket] = 0
+	}
+	return durationBuckets
+}
+
+func observeDuration(durationBuckets map[float64]uint64, duration float64) {
+	for _, bucket := range HttpStartStopDurationBuckets {
+		if duration <= bucket {
+			durationBuckets[bucket]++
+		}
+	}
+}
+
+func httpDuration(httpStartStop *events.HttpStartStop) (float64, bool) {
+	startTimestamp := httpStartStop.GetStartTimestamp()
+	stopTimestamp := httpStartStop.GetStopTimestamp()
+	if stopTimestamp < startTimestamp {
+		return 0, false
+	}
+	return time.Duration(stopTimestamp - startTimestamp).Seconds(), true
+}
+
+func parseHttpUri(uri string) (string, string) {
+	if !strings.Contains(uri, "://") {
+		uri = "//" + uri
+	}
+
+	parsedUri, err := url.Parse(uri)
+	if err != nil {
+		return "", ""
+	}
+
+	path := parsedUri.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.ToLower(parsedUri.Host), path
+}
+
+func statusCodeClass(statusCode int32) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
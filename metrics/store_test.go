@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/gogo/protobuf/proto"
 
@@ -20,6 +21,7 @@ var _ = Describe("Store", func() {
 		metricsCleanupInterval time.Duration
 		deploymentFilter       *filters.DeploymentFilter
 		eventFilter            *filters.EventFilter
+		routeTemplates         *utils.RouteTemplates
 
 		origin          = "fake-origin"
 		boshDeployment  = "fake-deployment-name"
@@ -45,20 +47,33 @@ var _ = Describe("Store", func() {
 		valueMetricValue = float64(2000)
 		valueMetricUnit  = "kb"
 
+		httpStartStopApplicationId   = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+		httpStartStopApplicationUUID = &events.UUID{
+			Low:  proto.Uint64(0x7243cc580bc17af4),
+			High: proto.Uint64(0x79d4c3b2020e67a5),
+		}
+		httpStartStopStartTimestamp = time.Now().UnixNano()
+
 		containerMetric ContainerMetric
 		counterEvent    CounterEvent
 		valueMetric     ValueMetric
+		httpStartStop   HttpStartStop
 
 		internalMetrics  InternalMetrics
 		containerMetrics ContainerMetrics
 		counterEvents    CounterEvents
 		valueMetrics     ValueMetrics
+		httpStartStops   HttpStartStops
+		httpRoutes       HttpRoutes
+		logMessages      LogMessages
+		errors           Errors
 	)
 
 	BeforeEach(func() {
 		deploymentFilter = filters.NewDeploymentFilter([]string{})
 		eventFilter, _ = filters.NewEventFilter([]string{})
-		metricsStore = NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter)
+		routeTemplates, _ = utils.NewRouteTemplates([]string{})
+		metricsStore = NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
 	})
 
 	Describe("GetInternalMetrics", func() {
@@ -118,6 +133,90 @@ var _ = Describe("Store", func() {
 			Expect(internalMetrics.LastValueMetricReceivedTimestamp).To(Equal(int64(0)))
 		})
 
+		It("returns the TotalHttpStartStopsReceived", func() {
+			Expect(internalMetrics.TotalHttpStartStopsReceived).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalHttpStartStopsProcessed", func() {
+			Expect(internalMetrics.TotalHttpStartStopsProcessed).To(Equal(int64(0)))
+		})
+
+		It("returns the LastHttpStartStopReceivedTimestamp", func() {
+			Expect(internalMetrics.LastHttpStartStopReceivedTimestamp).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalLogMessagesReceived", func() {
+			Expect(internalMetrics.TotalLogMessagesReceived).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalLogMessagesProcessed", func() {
+			Expect(internalMetrics.TotalLogMessagesProcessed).To(Equal(int64(0)))
+		})
+
+		It("returns the LastLogMessageReceivedTimestamp", func() {
+			Expect(internalMetrics.LastLogMessageReceivedTimestamp).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalErrorsReceived", func() {
+			Expect(internalMetrics.TotalErrorsReceived).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalErrorsProcessed", func() {
+			Expect(internalMetrics.TotalErrorsProcessed).To(Equal(int64(0)))
+		})
+
+		It("returns the LastErrorReceivedTimestamp", func() {
+			Expect(internalMetrics.LastErrorReceivedTimestamp).To(Equal(int64(0)))
+		})
+
+		It("returns the FirehoseConnected", func() {
+			Expect(internalMetrics.FirehoseConnected).To(BeFalse())
+		})
+
+		It("returns the TotalFirehoseReconnects", func() {
+			Expect(internalMetrics.TotalFirehoseReconnects).To(Equal(int64(0)))
+		})
+
+		It("returns the LastFirehoseReconnectTimestamp", func() {
+			Expect(internalMetrics.LastFirehoseReconnectTimestamp).To(Equal(int64(0)))
+		})
+
+		It("returns the EnvelopeQueueDepth", func() {
+			Expect(internalMetrics.EnvelopeQueueDepth).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalEnvelopesDequeued", func() {
+			Expect(internalMetrics.TotalEnvelopesDequeued).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalEnvelopeQueueLatencyNanoseconds", func() {
+			Expect(internalMetrics.TotalEnvelopeQueueLatencyNanoseconds).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalEnvelopesDropped", func() {
+			Expect(internalMetrics.TotalEnvelopesDropped).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalFirehoseConnections", func() {
+			Expect(internalMetrics.TotalFirehoseConnections).To(Equal(int64(0)))
+		})
+
+		It("returns the LastFirehoseConnectTimestamp", func() {
+			Expect(internalMetrics.LastFirehoseConnectTimestamp).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalEnvelopeBytesDecoded", func() {
+			Expect(internalMetrics.TotalEnvelopeBytesDecoded).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalDopplerEndpointFailovers", func() {
+			Expect(internalMetrics.TotalDopplerEndpointFailovers).To(Equal(int64(0)))
+		})
+
+		It("returns the TotalCounterEventResets", func() {
+			Expect(internalMetrics.TotalCounterEventResets).To(Equal(int64(0)))
+		})
+
 		It("returns the SlowConsumerAlert", func() {
 			Expect(internalMetrics.SlowConsumerAlert).To(BeFalse())
 		})
@@ -142,6 +241,28 @@ var _ = Describe("Store", func() {
 			totalValueMetricsReceived            = int64(300)
 			totalValueMetricsProcessed           = int64(150)
 			lastValueMetricReceivedTimestamp     = time.Now().Unix()
+			totalHttpStartStopsReceived          = int64(400)
+			totalHttpStartStopsProcessed         = int64(200)
+			lastHttpStartStopReceivedTimestamp   = time.Now().Unix()
+			totalLogMessagesReceived             = int64(600)
+			totalLogMessagesProcessed            = int64(300)
+			lastLogMessageReceivedTimestamp      = time.Now().Unix()
+			totalErrorsReceived                  = int64(600)
+			totalErrorsProcessed                 = int64(300)
+			lastErrorReceivedTimestamp           = time.Now().Unix()
+			firehoseConnected                    = true
+			totalFirehoseReconnects              = int64(5)
+			lastFirehoseReconnectTimestamp       = time.Now().Unix()
+			envelopeQueueDepth                   = int64(50)
+			totalEnvelopesDequeued               = int64(1000)
+			totalEnvelopeQueueLatencyNanoseconds = int64(2500000000)
+			totalEnvelopesDropped                = int64(10)
+			totalFirehoseConnections             = int64(3)
+			lastFirehoseConnectTimestamp         = time.Now().Unix()
+			totalEnvelopeBytesDecoded            = int64(4096)
+			totalDopplerEndpointFailovers        = int64(2)
+			totalCounterEventResets              = int64(2)
+			totalEnvelopesFiltered               = map[FilteredEnvelopes]int64{{Filter: "deployment", Origin: "gorouter"}: 4}
 			slowConsumerAlert                    = true
 			lastSlowConsumerAlertTimestamp       = time.Now().Unix()
 		)
@@ -161,6 +282,28 @@ var _ = Describe("Store", func() {
 				TotalValueMetricsReceived:            totalValueMetricsReceived,
 				TotalValueMetricsProcessed:           totalValueMetricsProcessed,
 				LastValueMetricReceivedTimestamp:     lastValueMetricReceivedTimestamp,
+				TotalHttpStartStopsReceived:          totalHttpStartStopsReceived,
+				TotalHttpStartStopsProcessed:         totalHttpStartStopsProcessed,
+				LastHttpStartStopReceivedTimestamp:   lastHttpStartStopReceivedTimestamp,
+				TotalLogMessagesReceived:             totalLogMessagesReceived,
+				TotalLogMessagesProcessed:            totalLogMessagesProcessed,
+				LastLogMessageReceivedTimestamp:      lastLogMessageReceivedTimestamp,
+				TotalErrorsReceived:                  totalErrorsReceived,
+				TotalErrorsProcessed:                 totalErrorsProcessed,
+				LastErrorReceivedTimestamp:           lastErrorReceivedTimestamp,
+				FirehoseConnected:                    firehoseConnected,
+				TotalFirehoseReconnects:              totalFirehoseReconnects,
+				LastFirehoseReconnectTimestamp:       lastFirehoseReconnectTimestamp,
+				EnvelopeQueueDepth:                   envelopeQueueDepth,
+				TotalEnvelopesDequeued:               totalEnvelopesDequeued,
+				TotalEnvelopeQueueLatencyNanoseconds: totalEnvelopeQueueLatencyNanoseconds,
+				TotalEnvelopesDropped:                totalEnvelopesDropped,
+				TotalFirehoseConnections:             totalFirehoseConnections,
+				LastFirehoseConnectTimestamp:         lastFirehoseConnectTimestamp,
+				TotalEnvelopeBytesDecoded:            totalEnvelopeBytesDecoded,
+				TotalDopplerEndpointFailovers:        totalDopplerEndpointFailovers,
+				TotalCounterEventResets:              totalCounterEventResets,
+				TotalEnvelopesFiltered:               totalEnvelopesFiltered,
 				SlowConsumerAlert:                    slowConsumerAlert,
 				LastSlowConsumerAlertTimestamp:       lastSlowConsumerAlertTimestamp,
 			})
@@ -172,6 +315,10 @@ var _ = Describe("Store", func() {
 			Expect(internalMetrics.TotalEnvelopesReceived).To(Equal(totalEnvelopesReceived))
 		})
 
+		It("sets the TotalEnvelopesFiltered", func() {
+			Expect(internalMetrics.TotalEnvelopesFiltered).To(Equal(totalEnvelopesFiltered))
+		})
+
 		It("sets the LastEnvelopReceivedTimestamp", func() {
 			Expect(internalMetrics.LastEnvelopReceivedTimestamp).To(Equal(lastEnvelopeReceivedTimestamp))
 		})
@@ -220,6 +367,90 @@ var _ = Describe("Store", func() {
 			Expect(internalMetrics.LastValueMetricReceivedTimestamp).To(Equal(lastValueMetricReceivedTimestamp))
 		})
 
+		It("sets the TotalHttpStartStopsReceived", func() {
+			Expect(internalMetrics.TotalHttpStartStopsReceived).To(Equal(totalHttpStartStopsReceived))
+		})
+
+		It("sets the TotalHttpStartStopsProcessed", func() {
+			Expect(internalMetrics.TotalHttpStartStopsProcessed).To(Equal(totalHttpStartStopsProcessed))
+		})
+
+		It("sets the LastHttpStartStopReceivedTimestamp", func() {
+			Expect(internalMetrics.LastHttpStartStopReceivedTimestamp).To(Equal(lastHttpStartStopReceivedTimestamp))
+		})
+
+		It("sets the TotalLogMessagesReceived", func() {
+			Expect(internalMetrics.TotalLogMessagesReceived).To(Equal(totalLogMessagesReceived))
+		})
+
+		It("sets the TotalLogMessagesProcessed", func() {
+			Expect(internalMetrics.TotalLogMessagesProcessed).To(Equal(totalLogMessagesProcessed))
+		})
+
+		It("sets the LastLogMessageReceivedTimestamp", func() {
+			Expect(internalMetrics.LastLogMessageReceivedTimestamp).To(Equal(lastLogMessageReceivedTimestamp))
+		})
+
+		It("sets the TotalErrorsReceived", func() {
+			Expect(internalMetrics.TotalErrorsReceived).To(Equal(totalErrorsReceived))
+		})
+
+		It("sets the TotalErrorsProcessed", func() {
+			Expect(internalMetrics.TotalErrorsProcessed).To(Equal(totalErrorsProcessed))
+		})
+
+		It("sets the LastErrorReceivedTimestamp", func() {
+			Expect(internalMetrics.LastErrorReceivedTimestamp).To(Equal(lastErrorReceivedTimestamp))
+		})
+
+		It("sets the FirehoseConnected", func() {
+			Expect(internalMetrics.FirehoseConnected).To(Equal(firehoseConnected))
+		})
+
+		It("sets the TotalFirehoseReconnects", func() {
+			Expect(internalMetrics.TotalFirehoseReconnects).To(Equal(totalFirehoseReconnects))
+		})
+
+		It("sets the LastFirehoseReconnectTimestamp", func() {
+			Expect(internalMetrics.LastFirehoseReconnectTimestamp).To(Equal(lastFirehoseReconnectTimestamp))
+		})
+
+		It("sets the EnvelopeQueueDepth", func() {
+			Expect(internalMetrics.EnvelopeQueueDepth).To(Equal(envelopeQueueDepth))
+		})
+
+		It("sets the TotalEnvelopesDequeued", func() {
+			Expect(internalMetrics.TotalEnvelopesDequeued).To(Equal(totalEnvelopesDequeued))
+		})
+
+		It("sets the TotalEnvelopeQueueLatencyNanoseconds", func() {
+			Expect(internalMetrics.TotalEnvelopeQueueLatencyNanoseconds).To(Equal(totalEnvelopeQueueLatencyNanoseconds))
+		})
+
+		It("sets the TotalEnvelopesDropped", func() {
+			Expect(internalMetrics.TotalEnvelopesDropped).To(Equal(totalEnvelopesDropped))
+		})
+
+		It("sets the TotalFirehoseConnections", func() {
+			Expect(internalMetrics.TotalFirehoseConnections).To(Equal(totalFirehoseConnections))
+		})
+
+		It("sets the LastFirehoseConnectTimestamp", func() {
+			Expect(internalMetrics.LastFirehoseConnectTimestamp).To(Equal(lastFirehoseConnectTimestamp))
+		})
+
+		It("sets the TotalEnvelopeBytesDecoded", func() {
+			Expect(internalMetrics.TotalEnvelopeBytesDecoded).To(Equal(totalEnvelopeBytesDecoded))
+		})
+
+		It("sets the TotalDopplerEndpointFailovers", func() {
+			Expect(internalMetrics.TotalDopplerEndpointFailovers).To(Equal(totalDopplerEndpointFailovers))
+		})
+
+		It("sets the TotalCounterEventResets", func() {
+			Expect(internalMetrics.TotalCounterEventResets).To(Equal(totalCounterEventResets))
+		})
+
 		It("sets the SlowConsumerAlert", func() {
 			Expect(internalMetrics.SlowConsumerAlert).To(Equal(slowConsumerAlert))
 		})
@@ -229,6 +460,53 @@ var _ = Describe("Store", func() {
 		})
 	})
 
+	Describe("RunCleanup", func() {
+		var cleanupDone chan error
+
+		BeforeEach(func() {
+			metricsStore = NewStore(10*time.Millisecond, 10*time.Millisecond, deploymentFilter, eventFilter, routeTemplates)
+			metricsStore.AddMetric(
+				&events.Envelope{
+					Origin:     proto.String(origin),
+					EventType:  events.Envelope_ContainerMetric.Enum(),
+					Timestamp:  proto.Int64(metricTimestamp),
+					Deployment: proto.String(boshDeployment),
+					Job:        proto.String(boshJob),
+					Index:      proto.String(boshIndex0),
+					Ip:         proto.String(boshIP),
+					ContainerMetric: &events.ContainerMetric{
+						ApplicationId: proto.String(containerMetricApplicationId),
+						InstanceIndex: proto.Int32(containerMetricInstanceIndex),
+						CpuPercentage: proto.Float64(containerMetricCpuPercentage),
+						MemoryBytes:   proto.Uint64(containerMetricMemoryBytes),
+						DiskBytes:     proto.Uint64(containerMetricDiskBytes),
+					},
+				},
+			)
+
+			cleanupDone = make(chan error, 1)
+			go func(metricsStore *Store, cleanupDone chan error) {
+				cleanupDone <- metricsStore.RunCleanup()
+			}(metricsStore, cleanupDone)
+		})
+
+		AfterEach(func() {
+			metricsStore.StopCleanup()
+		})
+
+		It("deletes the expired metrics", func() {
+			time.Sleep(50 * time.Millisecond)
+			metricsStore.StopCleanup()
+			Eventually(cleanupDone).Should(Receive())
+			Expect(metricsStore.GetContainerMetrics()).To(BeEmpty())
+		})
+
+		It("returns when stopped", func() {
+			metricsStore.StopCleanup()
+			Eventually(cleanupDone).Should(Receive(BeNil()))
+		})
+	})
+
 	Describe("AlertSlowConsumerError", func() {
 		BeforeEach(func() {
 			metricsStore.AlertSlowConsumerError()
@@ -245,6 +523,76 @@ var _ = Describe("Store", func() {
 		})
 	})
 
+	Describe("AddFirehoseConnect", func() {
+		BeforeEach(func() {
+			metricsStore.AddFirehoseConnect()
+			metricsStore.AddFirehoseConnect()
+
+			internalMetrics = metricsStore.GetInternalMetrics()
+		})
+
+		It("increments the TotalFirehoseConnections", func() {
+			Expect(internalMetrics.TotalFirehoseConnections).To(Equal(int64(2)))
+		})
+
+		It("sets the LastFirehoseConnectTimestamp", func() {
+			Expect(internalMetrics.LastFirehoseConnectTimestamp).ToNot(Equal(int64(0)))
+		})
+
+		It("sets the FirehoseConnected", func() {
+			Expect(internalMetrics.FirehoseConnected).To(BeTrue())
+		})
+	})
+
+	Describe("AddFirehoseDisconnect", func() {
+		BeforeEach(func() {
+			metricsStore.AddFirehoseConnect()
+			metricsStore.AddFirehoseDisconnect(1000)
+			metricsStore.AddFirehoseDisconnect(1006)
+			metricsStore.AddFirehoseDisconnect(1006)
+
+			internalMetrics = metricsStore.GetInternalMetrics()
+		})
+
+		It("increments the TotalFirehoseDisconnects by close code", func() {
+			Expect(internalMetrics.TotalFirehoseDisconnects).To(Equal(map[int]int64{1000: 1, 1006: 2}))
+		})
+
+		It("unsets the FirehoseConnected", func() {
+			Expect(internalMetrics.FirehoseConnected).To(BeFalse())
+		})
+	})
+
+	Describe("AddEnvelopeBytesDecoded", func() {
+		BeforeEach(func() {
+			metricsStore.AddEnvelopeBytesDecoded(100)
+			metricsStore.AddEnvelopeBytesDecoded(50)
+
+			internalMetrics = metricsStore.GetInternalMetrics()
+		})
+
+		It("increments the TotalEnvelopeBytesDecoded", func() {
+			Expect(internalMetrics.TotalEnvelopeBytesDecoded).To(Equal(int64(150)))
+		})
+	})
+
+	Describe("SetActiveDopplerEndpoint", func() {
+		BeforeEach(func() {
+			metricsStore.SetActiveDopplerEndpoint("wss://doppler.example.com:443")
+			metricsStore.AddDopplerEndpointFailover()
+
+			internalMetrics = metricsStore.GetInternalMetrics()
+		})
+
+		It("sets the ActiveDopplerEndpoint", func() {
+			Expect(internalMetrics.ActiveDopplerEndpoint).To(Equal("wss://doppler.example.com:443"))
+		})
+
+		It("increments the TotalDopplerEndpointFailovers", func() {
+			Expect(internalMetrics.TotalDopplerEndpointFailovers).To(Equal(int64(1)))
+		})
+	})
+
 	Describe("AddMetric", func() {
 		BeforeEach(func() {
 			metricsStore.AddMetric(
@@ -333,6 +681,7 @@ var _ = Describe("Store", func() {
 				Name:       counterEventName,
 				Delta:      counterEventDelta,
 				Total:      counterEventTotal,
+				Counter:    counterEventTotal,
 			}
 
 			metricsStore.AddMetric(
@@ -426,6 +775,18 @@ var _ = Describe("Store", func() {
 			Expect(internalMetrics.LastValueMetricReceivedTimestamp).ToNot(Equal(int64(0)))
 		})
 
+		It("increments the TotalErrorsReceived", func() {
+			Expect(internalMetrics.TotalErrorsReceived).To(Equal(int64(1)))
+		})
+
+		It("increments the TotalErrorsProcessed", func() {
+			Expect(internalMetrics.TotalErrorsProcessed).To(Equal(int64(1)))
+		})
+
+		It("sets the LastErrorReceivedTimestamp", func() {
+			Expect(internalMetrics.LastErrorReceivedTimestamp).ToNot(Equal(int64(0)))
+		})
+
 		It("adds a container metric", func() {
 			Expect(len(containerMetrics)).To(Equal(1))
 			Expect(containerMetrics).To(ContainElement(containerMetric))
@@ -756,6 +1117,7 @@ var _ = Describe("Store", func() {
 				Name:       counterEventName,
 				Delta:      counterEventDelta,
 				Total:      counterEventTotal,
+				Counter:    counterEventTotal,
 			}
 		})
 
@@ -770,6 +1132,295 @@ var _ = Describe("Store", func() {
 			})
 		})
 
+		Describe("SetEnvelopeFilter", func() {
+			BeforeEach(func() {
+				jobFilter, err := filters.NewPatternFilter(nil, []string{boshJob})
+				Expect(err).ToNot(HaveOccurred())
+				metricsStore.SetEnvelopeFilter(filters.NewEnvelopeFilter(nil, jobFilter, nil))
+
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_CounterEvent.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String(boshJob),
+						Index:      proto.String(boshIndex1),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						CounterEvent: &events.CounterEvent{
+							Name:  proto.String(counterEventName),
+							Delta: proto.Uint64(counterEventDelta),
+							Total: proto.Uint64(counterEventTotal),
+						},
+					},
+				)
+			})
+
+			It("does not process the filtered counter events", func() {
+				Expect(metricsStore.GetCounterEvents()).To(ConsistOf(counterEvent))
+				Expect(metricsStore.GetInternalMetrics().TotalCounterEventsProcessed).To(Equal(int64(1)))
+			})
+
+			It("accounts the filtered envelopes by filter and origin", func() {
+				Expect(metricsStore.GetInternalMetrics().TotalEnvelopesFiltered).To(Equal(map[FilteredEnvelopes]int64{
+					{Filter: "job_pattern", Origin: origin}: 1,
+				}))
+			})
+		})
+
+		Describe("SetDeploymentFilter", func() {
+			BeforeEach(func() {
+				metricsStore.SetDeploymentFilter(filters.NewDeploymentFilter([]string{"other-deployment"}))
+
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_CounterEvent.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String(boshJob),
+						Index:      proto.String(boshIndex1),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						CounterEvent: &events.CounterEvent{
+							Name:  proto.String(counterEventName),
+							Delta: proto.Uint64(counterEventDelta),
+							Total: proto.Uint64(counterEventTotal),
+						},
+					},
+				)
+			})
+
+			It("replaces the deployment filter", func() {
+				Expect(metricsStore.GetCounterEvents()).To(ConsistOf(counterEvent))
+				Expect(metricsStore.GetInternalMetrics().TotalEnvelopesFiltered).To(Equal(map[FilteredEnvelopes]int64{
+					{Filter: "deployment", Origin: origin}: 1,
+				}))
+			})
+		})
+
+		Describe("SetEventFilter", func() {
+			BeforeEach(func() {
+				eventFilter, err := filters.NewEventFilter([]string{"ValueMetric"})
+				Expect(err).ToNot(HaveOccurred())
+				metricsStore.SetEventFilter(eventFilter)
+
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_CounterEvent.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String(boshJob),
+						Index:      proto.String(boshIndex1),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						CounterEvent: &events.CounterEvent{
+							Name:  proto.String(counterEventName),
+							Delta: proto.Uint64(counterEventDelta),
+							Total: proto.Uint64(counterEventTotal),
+						},
+					},
+				)
+			})
+
+			It("replaces the event filter", func() {
+				Expect(metricsStore.GetCounterEvents()).To(ConsistOf(counterEvent))
+				Expect(metricsStore.GetInternalMetrics().TotalEnvelopesFiltered).To(Equal(map[FilteredEnvelopes]int64{
+					{Filter: "event_type", Origin: origin}: 1,
+				}))
+			})
+		})
+
+		Describe("replacing the settings while adding metrics", func() {
+			It("does not race with the metrics being added", func() {
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					for i := 0; i < 100; i++ {
+						expressionFilter, _ := filters.NewExpressionFilter(`origin != "uaa"`)
+						metricsStore.SetDeploymentFilter(filters.NewDeploymentFilter([]string{}))
+						metricsStore.SetEnvelopeFilter(filters.NewEnvelopeFilter(nil, nil, nil))
+						metricsStore.SetMetricNameFilter(filters.NewMetricNameFilter(nil, nil))
+						metricsStore.SetExpressionFilter(expressionFilter)
+						metricsStore.SetExpirationPolicies(NewExpirationPolicies(metricsExpiration))
+						metricsStore.SetValueMetricAggregations(nil)
+					}
+				}()
+
+				for i := 0; i < 100; i++ {
+					metricsStore.AddMetric(
+						&events.Envelope{
+							Origin:     proto.String(origin),
+							EventType:  events.Envelope_CounterEvent.Enum(),
+							Timestamp:  proto.Int64(metricTimestamp),
+							Deployment: proto.String(boshDeployment),
+							Job:        proto.String(boshJob),
+							Index:      proto.String(boshIndex0),
+							Ip:         proto.String(boshIP),
+							Tags:       map[string]string{},
+							CounterEvent: &events.CounterEvent{
+								Name:  proto.String(counterEventName),
+								Delta: proto.Uint64(counterEventDelta),
+								Total: proto.Uint64(counterEventTotal),
+							},
+						},
+					)
+				}
+				Eventually(done).Should(BeClosed())
+
+				Expect(metricsStore.GetInternalMetrics().TotalCounterEventsProcessed).To(Equal(int64(101)))
+			})
+		})
+
+		Describe("SetExpressionFilter", func() {
+			BeforeEach(func() {
+				expressionFilter, err := filters.NewExpressionFilter(`event_type != "CounterEvent" or index != "` + boshIndex1 + `"`)
+				Expect(err).ToNot(HaveOccurred())
+				metricsStore.SetExpressionFilter(expressionFilter)
+
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_CounterEvent.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String(boshJob),
+						Index:      proto.String(boshIndex1),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						CounterEvent: &events.CounterEvent{
+							Name:  proto.String(counterEventName),
+							Delta: proto.Uint64(counterEventDelta),
+							Total: proto.Uint64(counterEventTotal),
+						},
+					},
+				)
+			})
+
+			It("does not process the counter events for which the expression is false", func() {
+				Expect(metricsStore.GetCounterEvents()).To(ConsistOf(counterEvent))
+				Expect(metricsStore.GetInternalMetrics().TotalCounterEventsProcessed).To(Equal(int64(1)))
+			})
+
+			It("accounts the filtered envelopes by filter and origin", func() {
+				Expect(metricsStore.GetInternalMetrics().TotalEnvelopesFiltered).To(Equal(map[FilteredEnvelopes]int64{
+					{Filter: "expression", Origin: origin}: 1,
+				}))
+			})
+		})
+
+		Describe("SetMetricNameFilter", func() {
+			BeforeEach(func() {
+				counterEventFilter, err := filters.NewPatternFilter(nil, []string{counterEventName + "_debug"})
+				Expect(err).ToNot(HaveOccurred())
+				metricsStore.SetMetricNameFilter(filters.NewMetricNameFilter(counterEventFilter, nil))
+
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_CounterEvent.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String(boshJob),
+						Index:      proto.String(boshIndex0),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						CounterEvent: &events.CounterEvent{
+							Name:  proto.String(counterEventName + "_debug"),
+							Delta: proto.Uint64(counterEventDelta),
+							Total: proto.Uint64(counterEventTotal),
+						},
+					},
+				)
+			})
+
+			It("does not store the denied counter events", func() {
+				Expect(metricsStore.GetCounterEvents()).To(ConsistOf(counterEvent))
+			})
+
+			It("accounts the filtered envelopes by filter and origin", func() {
+				Expect(metricsStore.GetInternalMetrics().TotalEnvelopesFiltered).To(Equal(map[FilteredEnvelopes]int64{
+					{Filter: "metric_name", Origin: origin}: 1,
+				}))
+			})
+		})
+
+		Describe("Counter", func() {
+			var (
+				addCounterEvent = func(delta uint64, total uint64) {
+					metricsStore.AddMetric(
+						&events.Envelope{
+							Origin:     proto.String(origin),
+							EventType:  events.Envelope_CounterEvent.Enum(),
+							Timestamp:  proto.Int64(metricTimestamp),
+							Deployment: proto.String(boshDeployment),
+							Job:        proto.String(boshJob),
+							Index:      proto.String(boshIndex0),
+							Ip:         proto.String(boshIP),
+							Tags:       map[string]string{},
+							CounterEvent: &events.CounterEvent{
+								Name:  proto.String(counterEventName),
+								Delta: proto.Uint64(delta),
+								Total: proto.Uint64(total),
+							},
+						},
+					)
+				}
+
+				counter = func() uint64 {
+					counterEvents := metricsStore.GetCounterEvents()
+					Expect(counterEvents).To(HaveLen(1))
+					return counterEvents[0].Counter
+				}
+			)
+
+			It("adds up the increases of the total", func() {
+				addCounterEvent(counterEventDelta, counterEventTotal+counterEventDelta)
+				addCounterEvent(counterEventDelta, counterEventTotal+3*counterEventDelta)
+				Expect(counter()).To(Equal(counterEventTotal + 3*counterEventDelta))
+			})
+
+			Context("when the emitter resets the total", func() {
+				BeforeEach(func() {
+					addCounterEvent(counterEventDelta, counterEventDelta)
+					addCounterEvent(counterEventDelta, 2*counterEventDelta)
+				})
+
+				It("keeps the counter monotonic", func() {
+					Expect(counter()).To(Equal(counterEventTotal + 2*counterEventDelta))
+				})
+
+				It("increments the TotalCounterEventResets", func() {
+					Expect(metricsStore.GetInternalMetrics().TotalCounterEventResets).To(Equal(int64(1)))
+				})
+			})
+
+			Context("when the emitter only sends deltas", func() {
+				BeforeEach(func() {
+					metricsStore.FlushCounterEvents()
+				})
+
+				It("adds up the deltas", func() {
+					addCounterEvent(counterEventDelta, 0)
+					addCounterEvent(counterEventDelta, 0)
+					addCounterEvent(1, 0)
+					Expect(counter()).To(Equal(2*counterEventDelta + 1))
+				})
+
+				It("uses the first total as the baseline once the emitter sends totals", func() {
+					addCounterEvent(counterEventDelta, 0)
+					addCounterEvent(counterEventDelta, 0)
+					addCounterEvent(counterEventDelta, counterEventTotal)
+					Expect(counter()).To(Equal(3 * counterEventDelta))
+
+					addCounterEvent(counterEventDelta, counterEventTotal+counterEventDelta)
+					Expect(counter()).To(Equal(4 * counterEventDelta))
+				})
+			})
+		})
+
 		Describe("FlushCounterEvents", func() {
 			BeforeEach(func() {
 				metricsStore.FlushCounterEvents()
@@ -838,4 +1489,445 @@ var _ = Describe("Store", func() {
 			})
 		})
 	})
+
+	Context("HttpStartStops", func() {
+		var (
+			addHttpStartStop = func(applicationId *events.UUID, method events.Method, statusCode int32, duration time.Duration) {
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String("gorouter"),
+						EventType:  events.Envelope_HttpStartStop.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String("router"),
+						Index:      proto.String(boshIndex0),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						HttpStartStop: &events.HttpStartStop{
+							StartTimestamp: proto.Int64(httpStartStopStartTimestamp),
+							StopTimestamp:  proto.Int64(httpStartStopStartTimestamp + duration.Nanoseconds()),
+							PeerType:       events.PeerType_Client.Enum(),
+							Method:         method.Enum(),
+							Uri:            proto.String("http://fake-host/fake-path"),
+							StatusCode:     proto.Int32(statusCode),
+							ContentLength:  proto.Int64(100),
+							ApplicationId:  applicationId,
+						},
+					},
+				)
+			}
+		)
+
+		BeforeEach(func() {
+			addHttpStartStop(httpStartStopApplicationUUID, events.Method_GET, 200, 20*time.Millisecond)
+			addHttpStartStop(httpStartStopApplicationUUID, events.Method_GET, 201, 200*time.Millisecond)
+			addHttpStartStop(httpStartStopApplicationUUID, events.Method_GET, 503, 2*time.Second)
+
+			httpStartStop = HttpStartStop{
+				Timestamp:     metricTimestamp,
+				ApplicationId: httpStartStopApplicationId,
+				Method:        "GET",
+				Requests:      map[string]uint64{"2xx": 2, "5xx": 1},
+				DurationCount: 3,
+				DurationSum:   2.22,
+				DurationBuckets: map[float64]uint64{
+					.005: 0, .01: 0, .025: 1, .05: 1, .1: 1, .25: 2, .5: 2, 1: 2, 2.5: 3, 5: 3, 10: 3,
+				},
+			}
+		})
+
+		Describe("GetHttpStartStops", func() {
+			BeforeEach(func() {
+				internalMetrics = metricsStore.GetInternalMetrics()
+				httpStartStops = metricsStore.GetHttpStartStops()
+			})
+
+			It("increments the TotalHttpStartStopsReceived", func() {
+				Expect(internalMetrics.TotalHttpStartStopsReceived).To(Equal(int64(3)))
+			})
+
+			It("increments the TotalHttpStartStopsProcessed", func() {
+				Expect(internalMetrics.TotalHttpStartStopsProcessed).To(Equal(int64(3)))
+			})
+
+			It("sets the LastHttpStartStopReceivedTimestamp", func() {
+				Expect(internalMetrics.LastHttpStartStopReceivedTimestamp).ToNot(Equal(int64(0)))
+			})
+
+			It("returns the http start stops aggregated by application and method", func() {
+				Expect(len(httpStartStops)).To(Equal(1))
+				Expect(httpStartStops[0].ApplicationId).To(Equal(httpStartStop.ApplicationId))
+				Expect(httpStartStops[0].Method).To(Equal(httpStartStop.Method))
+				Expect(httpStartStops[0].Requests).To(Equal(httpStartStop.Requests))
+				Expect(httpStartStops[0].DurationCount).To(Equal(httpStartStop.DurationCount))
+				Expect(httpStartStops[0].DurationSum).To(BeNumerically("~", httpStartStop.DurationSum, 0.0001))
+				Expect(httpStartStops[0].DurationBuckets).To(Equal(httpStartStop.DurationBuckets))
+			})
+
+			Context("when the method is different", func() {
+				BeforeEach(func() {
+					addHttpStartStop(httpStartStopApplicationUUID, events.Method_POST, 200, 20*time.Millisecond)
+					httpStartStops = metricsStore.GetHttpStartStops()
+				})
+
+				It("adds a new http start stop", func() {
+					Expect(len(httpStartStops)).To(Equal(2))
+				})
+			})
+
+			Context("when there is no application id", func() {
+				BeforeEach(func() {
+					addHttpStartStop(nil, events.Method_GET, 200, 20*time.Millisecond)
+					httpStartStops = metricsStore.GetHttpStartStops()
+				})
+
+				It("does not add the http start stop", func() {
+					Expect(len(httpStartStops)).To(Equal(1))
+				})
+			})
+
+			Context("when there is no application id nor route", func() {
+				BeforeEach(func() {
+					metricsStore.AddMetric(
+						&events.Envelope{
+							Origin:    proto.String("gorouter"),
+							EventType: events.Envelope_HttpStartStop.Enum(),
+							Timestamp: proto.Int64(metricTimestamp),
+							HttpStartStop: &events.HttpStartStop{
+								PeerType:   events.PeerType_Client.Enum(),
+								Method:     events.Method_GET.Enum(),
+								StatusCode: proto.Int32(200),
+							},
+						},
+					)
+					internalMetrics = metricsStore.GetInternalMetrics()
+				})
+
+				It("does not increment the TotalHttpStartStopsProcessed", func() {
+					Expect(internalMetrics.TotalHttpStartStopsReceived).To(Equal(int64(4)))
+					Expect(internalMetrics.TotalHttpStartStopsProcessed).To(Equal(int64(3)))
+				})
+			})
+
+			Context("when the http start stop is not emitted by the gorouter as a client", func() {
+				BeforeEach(func() {
+					metricsStore.AddMetric(
+						&events.Envelope{
+							Origin:    proto.String("fake-origin"),
+							EventType: events.Envelope_HttpStartStop.Enum(),
+							Timestamp: proto.Int64(metricTimestamp),
+							HttpStartStop: &events.HttpStartStop{
+								StartTimestamp: proto.Int64(httpStartStopStartTimestamp),
+								StopTimestamp:  proto.Int64(httpStartStopStartTimestamp),
+								PeerType:       events.PeerType_Server.Enum(),
+								Method:         events.Method_GET.Enum(),
+								Uri:            proto.String("http://fake-host/fake-path"),
+								StatusCode:     proto.Int32(200),
+								ApplicationId:  httpStartStopApplicationUUID,
+							},
+						},
+					)
+					internalMetrics = metricsStore.GetInternalMetrics()
+					httpStartStops = metricsStore.GetHttpStartStops()
+				})
+
+				It("does not count the request again", func() {
+					Expect(len(httpStartStops)).To(Equal(1))
+					Expect(httpStartStops[0].Requests).To(Equal(httpStartStop.Requests))
+				})
+
+				It("does not increment the TotalHttpStartStopsProcessed", func() {
+					Expect(internalMetrics.TotalHttpStartStopsProcessed).To(Equal(int64(3)))
+				})
+			})
+		})
+
+		Describe("FlushHttpStartStops", func() {
+			BeforeEach(func() {
+				metricsStore.FlushHttpStartStops()
+				httpStartStops = metricsStore.GetHttpStartStops()
+			})
+
+			It("returns empty http start stops", func() {
+				Expect(len(httpStartStops)).To(Equal(0))
+			})
+		})
+	})
+
+	Context("HttpRoutes", func() {
+		var (
+			addHttpRoute = func(origin string, peerType events.PeerType, uri string, statusCode int32) {
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_HttpStartStop.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String("router"),
+						Index:      proto.String(boshIndex0),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						HttpStartStop: &events.HttpStartStop{
+							StartTimestamp: proto.Int64(httpStartStopStartTimestamp),
+							StopTimestamp:  proto.Int64(httpStartStopStartTimestamp + (100 * time.Millisecond).Nanoseconds()),
+							PeerType:       peerType.Enum(),
+							Method:         events.Method_GET.Enum(),
+							Uri:            proto.String(uri),
+							StatusCode:     proto.Int32(statusCode),
+							ContentLength:  proto.Int64(100),
+						},
+					},
+				)
+			}
+		)
+
+		BeforeEach(func() {
+			routeTemplates, _ = utils.NewRouteTemplates([]string{"/v2/apps/{guid}/stats"})
+			metricsStore = NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+
+			addHttpRoute("gorouter", events.PeerType_Client, "http://API.fake-domain.com/v2/apps/fake-guid-1/stats", 200)
+			addHttpRoute("gorouter", events.PeerType_Client, "api.fake-domain.com/v2/apps/fake-guid-2/stats", 500)
+			addHttpRoute("gorouter", events.PeerType_Client, "http://api.fake-domain.com/v2/info", 200)
+			addHttpRoute("gorouter", events.PeerType_Server, "http://api.fake-domain.com/v2/info", 200)
+			addHttpRoute("fake-origin", events.PeerType_Client, "http://api.fake-domain.com/v2/info", 200)
+		})
+
+		Describe("GetHttpRoutes", func() {
+			BeforeEach(func() {
+				httpRoutes = metricsStore.GetHttpRoutes()
+			})
+
+			It("only returns gorouter client routes aggregated by host and path template", func() {
+				Expect(len(httpRoutes)).To(Equal(2))
+			})
+
+			It("templates matching paths", func() {
+				var httpRoute HttpRoute
+				for _, route := range httpRoutes {
+					if route.Path != utils.UnmatchedRouteTemplate {
+						httpRoute = route
+					}
+				}
+
+				Expect(httpRoute.Host).To(Equal("api.fake-domain.com"))
+				Expect(httpRoute.Path).To(Equal("/v2/apps/{guid}/stats"))
+				Expect(httpRoute.Method).To(Equal("GET"))
+				Expect(httpRoute.Requests).To(Equal(map[string]uint64{"2xx": 1, "5xx": 1}))
+				Expect(httpRoute.ResponseBytes).To(Equal(uint64(200)))
+				Expect(httpRoute.DurationCount).To(Equal(uint64(2)))
+			})
+
+			It("labels paths without a matching template as unmatched", func() {
+				var httpRoute HttpRoute
+				for _, route := range httpRoutes {
+					if route.Path == utils.UnmatchedRouteTemplate {
+						httpRoute = route
+					}
+				}
+
+				Expect(httpRoute.Host).To(Equal("api.fake-domain.com"))
+				Expect(httpRoute.Requests).To(Equal(map[string]uint64{"2xx": 1}))
+			})
+		})
+
+		Describe("FlushHttpRoutes", func() {
+			BeforeEach(func() {
+				metricsStore.FlushHttpRoutes()
+				httpRoutes = metricsStore.GetHttpRoutes()
+			})
+
+			It("returns empty http routes", func() {
+				Expect(len(httpRoutes)).To(Equal(0))
+			})
+		})
+	})
+
+	Context("LogMessages", func() {
+		var (
+			addLogMessage = func(applicationId string, sourceType string, messageType events.LogMessage_MessageType, message string) {
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_LogMessage.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String(boshJob),
+						Index:      proto.String(boshIndex0),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						LogMessage: &events.LogMessage{
+							Message:     []byte(message),
+							MessageType: messageType.Enum(),
+							Timestamp:   proto.Int64(metricTimestamp),
+							AppId:       proto.String(applicationId),
+							SourceType:  proto.String(sourceType),
+						},
+					},
+				)
+			}
+		)
+
+		JustBeforeEach(func() {
+			addLogMessage("fake-application-id", "APP", events.LogMessage_OUT, "fake-message-1")
+			addLogMessage("fake-application-id", "APP", events.LogMessage_OUT, "fake-message-22")
+			addLogMessage("fake-application-id", "RTR", events.LogMessage_OUT, "fake-message-3")
+
+			internalMetrics = metricsStore.GetInternalMetrics()
+			logMessages = metricsStore.GetLogMessages()
+		})
+
+		Describe("GetLogMessages", func() {
+			It("increments the TotalLogMessagesReceived", func() {
+				Expect(internalMetrics.TotalLogMessagesReceived).To(Equal(int64(3)))
+			})
+
+			It("increments the TotalLogMessagesProcessed", func() {
+				Expect(internalMetrics.TotalLogMessagesProcessed).To(Equal(int64(3)))
+			})
+
+			It("sets the LastLogMessageReceivedTimestamp", func() {
+				Expect(internalMetrics.LastLogMessageReceivedTimestamp).ToNot(Equal(int64(0)))
+			})
+
+			It("returns the log messages aggregated by application, source type and message type", func() {
+				Expect(len(logMessages)).To(Equal(2))
+				Expect(logMessages).To(ContainElement(LogMessage{
+					Timestamp:     metricTimestamp,
+					ApplicationId: "fake-application-id",
+					SourceType:    "APP",
+					MessageType:   "OUT",
+					Messages:      2,
+					Bytes:         29,
+				}))
+				Expect(logMessages).To(ContainElement(LogMessage{
+					Timestamp:     metricTimestamp,
+					ApplicationId: "fake-application-id",
+					SourceType:    "RTR",
+					MessageType:   "OUT",
+					Messages:      1,
+					Bytes:         14,
+				}))
+			})
+
+			Context("when the label values of different series concatenate to the same string", func() {
+				JustBeforeEach(func() {
+					addLogMessage("fake-application-id-", "APP", events.LogMessage_OUT, "fake-message")
+					addLogMessage("fake-application-id", "-APP", events.LogMessage_OUT, "fake-message")
+					logMessages = metricsStore.GetLogMessages()
+				})
+
+				It("keeps the series apart", func() {
+					Expect(len(logMessages)).To(Equal(4))
+				})
+			})
+
+			Context("when log messages are filtered", func() {
+				BeforeEach(func() {
+					eventFilter, _ = filters.NewEventFilter([]string{"ValueMetric"})
+					metricsStore = NewStore(metricsExpiration, metricsCleanupInterval, deploymentFilter, eventFilter, routeTemplates)
+				})
+
+				It("does not increment the TotalLogMessagesProcessed", func() {
+					Expect(internalMetrics.TotalLogMessagesProcessed).To(Equal(int64(0)))
+				})
+
+				It("accounts the log messages filtered by event type", func() {
+					Expect(internalMetrics.TotalEnvelopesFiltered[FilteredEnvelopes{Filter: "event_type", Origin: origin}]).To(BeNumerically(">", 0))
+				})
+
+				It("does not return the log messages", func() {
+					Expect(len(logMessages)).To(Equal(0))
+				})
+			})
+		})
+
+		Describe("FlushLogMessages", func() {
+			JustBeforeEach(func() {
+				metricsStore.FlushLogMessages()
+				logMessages = metricsStore.GetLogMessages()
+			})
+
+			It("returns empty log messages", func() {
+				Expect(len(logMessages)).To(Equal(0))
+			})
+		})
+	})
+
+	Context("Errors", func() {
+		var (
+			addError = func(source string, code int32, message string) {
+				metricsStore.AddMetric(
+					&events.Envelope{
+						Origin:     proto.String(origin),
+						EventType:  events.Envelope_Error.Enum(),
+						Timestamp:  proto.Int64(metricTimestamp),
+						Deployment: proto.String(boshDeployment),
+						Job:        proto.String(boshJob),
+						Index:      proto.String(boshIndex0),
+						Ip:         proto.String(boshIP),
+						Tags:       map[string]string{},
+						Error: &events.Error{
+							Source:  proto.String(source),
+							Code:    proto.Int32(code),
+							Message: proto.String(message),
+						},
+					},
+				)
+			}
+		)
+
+		BeforeEach(func() {
+			addError("fake-source", 127, "fake-message-1")
+			addError("fake-source", 127, "fake-message-2")
+			addError("fake-source", 500, "fake-message-3")
+		})
+
+		Describe("GetErrors", func() {
+			BeforeEach(func() {
+				errors = metricsStore.GetErrors()
+			})
+
+			It("returns the errors aggregated by origin, source and code", func() {
+				Expect(len(errors)).To(Equal(2))
+				Expect(errors).To(ContainElement(Error{
+					Origin:    origin,
+					Timestamp: metricTimestamp,
+					Source:    "fake-source",
+					Code:      127,
+					Message:   "fake-message-2",
+					Count:     2,
+				}))
+				Expect(errors).To(ContainElement(Error{
+					Origin:    origin,
+					Timestamp: metricTimestamp,
+					Source:    "fake-source",
+					Code:      500,
+					Message:   "fake-message-3",
+					Count:     1,
+				}))
+			})
+
+			Context("when the label values of different series concatenate to the same string", func() {
+				BeforeEach(func() {
+					addError("fake-source1", 27, "fake-message-4")
+					errors = metricsStore.GetErrors()
+				})
+
+				It("keeps the series apart", func() {
+					Expect(len(errors)).To(Equal(3))
+				})
+			})
+		})
+
+		Describe("FlushErrors", func() {
+			BeforeEach(func() {
+				metricsStore.FlushErrors()
+				errors = metricsStore.GetErrors()
+			})
+
+			It("returns empty errors", func() {
+				Expect(len(errors)).To(Equal(0))
+			})
+		})
+	})
 })
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// ValueMetricAggregations selects the Value Metrics whose samples are aggregated over a sliding
+// window, so that the values received between two scrapes are not lost.
+type ValueMetricAggregations struct {
+	patterns []string
+	window   time.Duration
+}
+
+type valueMetricSample struct {
+	received time.Time
+	value    float64
+}
+
+// NewValueMetricAggregations aggregates the Value Metrics whose `origin/name` matches one of the glob
+// patterns over the last window.
+func NewValueMetricAggregations(patterns []string, window time.Duration) (*ValueMetricAggregations, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("Value metric aggregation window `%s` must be positive", window)
+	}
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Value metric aggregation pattern `%s` is invalid", pattern)
+		}
+	}
+
+	return &ValueMetricAggregations{patterns: patterns, window: window}, nil
+}
+
+func (a *ValueMetricAggregations) enabled(origin string, name string) bool {
+	if a == nil {
+		return false
+	}
+
+	for _, pattern := range a.patterns {
+		if matched, _ := path.Match(pattern, origin+"/"+name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// addSample returns the samples received during the window, including a new one.
+func (a *ValueMetricAggregations) addSample(samples []valueMetricSample, value float64, now time.Time) []valueMetricSample {
+	windowSamples := a.windowSamples(samples, now)
+
+	newSamples := make([]valueMetricSample, 0, len(windowSamples)+1)
+	newSamples = append(newSamples, windowSamples...)
+	return append(newSamples, valueMetricSample{received: now, value: value})
+}
+
+func (a *ValueMetricAggregations) windowSamples(samples []valueMetricSample, now time.Time) []valueMetricSample {
+	start := now.Add(-a.window)
+	for i, sample := range samples {
+		if sample.received.After(start) {
+			return samples[i:]
+		}
+	}
+	return nil
+}
+
+// aggregate sets the aggregates of a Value Metric from the samples received during the window.
+func (a *ValueMetricAggregations) aggregate(valueMetric *ValueMetric, now time.Time) {
+	if a == nil {
+		return
+	}
+
+	samples := a.windowSamples(valueMetric.samples, now)
+	if len(samples) == 0 {
+		return
+	}
+
+	aggregate := &ValueMetricAggregate{Min: samples[0].value, Max: samples[0].value}
+	for _, sample := range samples {
+		if sample.value < aggregate.Min {
+			aggregate.Min = sample.value
+		}
+		if sample.value > aggregate.Max {
+			aggregate.Max = sample.value
+		}
+		aggregate.Sum += sample.value
+		aggregate.Count++
+	}
+	valueMetric.Aggregate = aggregate
+}
@@ -0,0 +1,106 @@
+package metrics_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/firehose_exporter/filters"
+	"github.com/cloudfoundry-community/firehose_exporter/utils"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+var _ = Describe("ValueMetricAggregations", func() {
+	var (
+		metricsStore *Store
+		window       time.Duration
+
+		addValueMetric = func(origin string, name string, value float64) {
+			metricsStore.AddMetric(&events.Envelope{
+				Origin:     proto.String(origin),
+				EventType:  events.Envelope_ValueMetric.Enum(),
+				Timestamp:  proto.Int64(time.Now().UnixNano()),
+				Deployment: proto.String("fake-deployment-name"),
+				Job:        proto.String("fake-job-name"),
+				Index:      proto.String("0"),
+				Ip:         proto.String("1.2.3.4"),
+				ValueMetric: &events.ValueMetric{
+					Name:  proto.String(name),
+					Value: proto.Float64(value),
+					Unit:  proto.String("ms"),
+				},
+			})
+		}
+
+		valueMetric = func(name string) ValueMetric {
+			for _, valueMetric := range metricsStore.GetValueMetrics() {
+				if valueMetric.Name == name {
+					return valueMetric
+				}
+			}
+			Fail("value metric " + name + " not found")
+			return ValueMetric{}
+		}
+	)
+
+	BeforeEach(func() {
+		deploymentFilter := filters.NewDeploymentFilter([]string{})
+		eventFilter, _ := filters.NewEventFilter([]string{})
+		routeTemplates, _ := utils.NewRouteTemplates([]string{})
+		metricsStore = NewStore(0, 0, deploymentFilter, eventFilter, routeTemplates)
+
+		window = 1 * time.Minute
+	})
+
+	JustBeforeEach(func() {
+		valueAggregations, err := NewValueMetricAggregations([]string{"gorouter/latency*"}, window)
+		Expect(err).ToNot(HaveOccurred())
+		metricsStore.SetValueMetricAggregations(valueAggregations)
+
+		for _, value := range []float64{20, 5, 50, 25} {
+			addValueMetric("gorouter", "latency.uaa", value)
+			addValueMetric("gorouter", "total_routes", value)
+		}
+	})
+
+	It("aggregates the samples of the selected value metrics", func() {
+		Expect(valueMetric("latency.uaa").Value).To(Equal(float64(25)))
+		Expect(valueMetric("latency.uaa").Aggregate).To(Equal(&ValueMetricAggregate{Min: 5, Max: 50, Sum: 100, Count: 4}))
+	})
+
+	It("does not aggregate the other value metrics", func() {
+		Expect(valueMetric("total_routes").Value).To(Equal(float64(25)))
+		Expect(valueMetric("total_routes").Aggregate).To(BeNil())
+	})
+
+	Context("when the samples are older than the window", func() {
+		BeforeEach(func() {
+			window = 50 * time.Millisecond
+		})
+
+		It("drops them from the aggregate", func() {
+			Eventually(func() *ValueMetricAggregate {
+				return valueMetric("latency.uaa").Aggregate
+			}).Should(BeNil())
+
+			addValueMetric("gorouter", "latency.uaa", 10)
+			Expect(valueMetric("latency.uaa").Aggregate).To(Equal(&ValueMetricAggregate{Min: 10, Max: 10, Sum: 10, Count: 1}))
+		})
+	})
+
+	Describe("NewValueMetricAggregations", func() {
+		It("returns an error when a pattern is invalid", func() {
+			_, err := NewValueMetricAggregations([]string{"["}, window)
+			Expect(err).To(MatchError("Value metric aggregation pattern `[` is invalid"))
+		})
+
+		It("returns an error when the window is not positive", func() {
+			_, err := NewValueMetricAggregations([]string{}, 0)
+			Expect(err).To(MatchError("Value metric aggregation window `0s` must be positive"))
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package rungroup
+
+// RunGroup runs a set of actors and stops all of them as soon as one returns.
+// Each actor is made of an execute function, which must block until the actor
+// stops, and an interrupt function, which must cause execute to return.
+type RunGroup struct {
+	actors []actor
+}
+
+type actor struct {
+	execute   func() error
+	interrupt func(error)
+}
+
+func New() *RunGroup {
+	return &RunGroup{}
+}
+
+func (g *RunGroup) Add(execute func() error, interrupt func(error)) {
+	g.actors = append(g.actors, actor{execute: execute, interrupt: interrupt})
+}
+
+// Run starts all actors and waits until the first one returns. The rest of actors are then
+// interrupted in the order they were added, and Run waits until all of them have returned.
+// It returns the error of the first actor returning.
+func (g *RunGroup) Run() error {
+	if len(g.actors) == 0 {
+		return nil
+	}
+
+	errs := make(chan error, len(g.actors))
+	for _, a := range g.actors {
+		go func(a actor) {
+			errs <- a.execute()
+		}(a)
+	}
+
+	err := <-errs
+	for _, a := range g.actors {
+		a.interrupt(err)
+	}
+
+	for i := 1; i < len(g.actors); i++ {
+		<-errs
+	}
+
+	return err
+}
@@ -0,0 +1,76 @@
+package rungroup_test
+
+import (
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/rungroup"
+)
+
+var _ = Describe("RunGroup", func() {
+	var (
+		runGroup *RunGroup
+
+		lock        sync.Mutex
+		interrupted []string
+
+		blockingActor = func(name string) (func() error, func(error)) {
+			stop := make(chan struct{})
+			var once sync.Once
+			return func() error {
+					<-stop
+					return nil
+				}, func(error) {
+					lock.Lock()
+					interrupted = append(interrupted, name)
+					lock.Unlock()
+					once.Do(func() { close(stop) })
+				}
+		}
+	)
+
+	BeforeEach(func() {
+		runGroup = New()
+		interrupted = []string{}
+	})
+
+	Context("when there are no actors", func() {
+		It("returns nil", func() {
+			Expect(runGroup.Run()).To(Succeed())
+		})
+	})
+
+	Context("when an actor returns", func() {
+		var (
+			actorErr = errors.New("fake-error")
+		)
+
+		BeforeEach(func() {
+			execute, interrupt := blockingActor("first")
+			runGroup.Add(execute, interrupt)
+
+			runGroup.Add(func() error {
+				return actorErr
+			}, func(error) {
+				lock.Lock()
+				interrupted = append(interrupted, "failing")
+				lock.Unlock()
+			})
+
+			execute, interrupt = blockingActor("last")
+			runGroup.Add(execute, interrupt)
+		})
+
+		It("returns the error of the first actor returning", func() {
+			Expect(runGroup.Run()).To(Equal(actorErr))
+		})
+
+		It("interrupts all actors in order", func() {
+			runGroup.Run()
+			Expect(interrupted).To(Equal([]string{"first", "failing", "last"}))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package rungroup_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRunGroup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RunGroup Suite")
+}
@@ -1,6 +1,7 @@
 package fakes
 
 import (
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -29,6 +30,15 @@ func (f *FakeUAA) Start() {
 	f.server.Start()
 }
 
+func (f *FakeUAA) StartTLS() {
+	f.server = httptest.NewUnstartedServer(f)
+	f.server.StartTLS()
+}
+
+func (f *FakeUAA) Certificate() *x509.Certificate {
+	return f.server.Certificate()
+}
+
 func (f *FakeUAA) Close() {
 	f.server.Close()
 }
@@ -1,40 +1,59 @@
 package uaatokenrefresher
 
 import (
-	"github.com/cloudfoundry-incubator/uaago"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/firehose_exporter/httpclient"
 )
 
+const requestTimeout = 30 * time.Second
+
 type UAATokenRefresher struct {
-	url               string
-	clientID          string
-	clientSecret      string
-	skipSSLValidation bool
-	client            *uaago.Client
+	url          string
+	clientID     string
+	clientSecret string
+	client       *http.Client
+}
+
+type tokenResponse struct {
+	TokenType   string `json:"token_type"`
+	AccessToken string `json:"access_token"`
 }
 
 func New(
-	url string,
+	uaaURL string,
 	clientID string,
 	clientSecret string,
-	skipSSLValidation bool,
+	tlsConfig *tls.Config,
+	proxy func(*http.Request) (*url.URL, error),
 ) (*UAATokenRefresher, error) {
-	client, err := uaago.NewClient(url)
-	if err != nil {
+	if uaaURL == "" {
+		return &UAATokenRefresher{}, errors.New("client: missing url")
+	}
+
+	if _, err := url.Parse(uaaURL); err != nil {
 		return &UAATokenRefresher{}, err
 	}
 
 	return &UAATokenRefresher{
-		url:               url,
-		clientID:          clientID,
-		clientSecret:      clientSecret,
-		skipSSLValidation: skipSSLValidation,
-		client:            client,
+		url:          strings.TrimSuffix(uaaURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		client:       httpclient.New(tlsConfig, proxy, requestTimeout),
 	}, nil
 }
 
 func (uaa *UAATokenRefresher) RefreshAuthToken() (string, error) {
-	authToken, err := uaa.client.GetAuthToken(uaa.clientID, uaa.clientSecret, uaa.skipSSLValidation)
+	authToken, err := uaa.fetchAuthToken()
 	if err != nil {
 		log.Errorf("Error getting oauth token: %s. Please check your Client ID and Secret.", err.Error())
 		return "", err
@@ -42,3 +61,34 @@ func (uaa *UAATokenRefresher) RefreshAuthToken() (string, error) {
 
 	return authToken, nil
 }
+
+func (uaa *UAATokenRefresher) fetchAuthToken() (string, error) {
+	data := url.Values{
+		"client_id":  {uaa.clientID},
+		"grant_type": {"client_credentials"},
+	}
+
+	request, err := http.NewRequest("POST", uaa.url+"/oauth/token", strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.SetBasicAuth(uaa.clientID, uaa.clientSecret)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := uaa.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Received a status code %v", resp.Status)
+	}
+
+	token := tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s", token.TokenType, token.AccessToken), nil
+}
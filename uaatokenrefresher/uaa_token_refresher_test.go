@@ -1,9 +1,20 @@
 package uaatokenrefresher_test
 
 import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/cloudfoundry-community/firehose_exporter/httpclient"
 	"github.com/cloudfoundry-community/firehose_exporter/uaatokenrefresher/fakes"
 
 	. "github.com/cloudfoundry-community/firehose_exporter/uaatokenrefresher"
@@ -13,6 +24,8 @@ var _ = Describe("UAATokenRefresher", func() {
 	var (
 		err       error
 		fakeToken string
+		tlsConfig *tls.Config
+		proxy     func(*http.Request) (*url.URL, error)
 
 		fakeUAA            *fakes.FakeUAA
 		authTokenRefresher *UAATokenRefresher
@@ -23,9 +36,15 @@ var _ = Describe("UAATokenRefresher", func() {
 		fakeToken = fakeUAA.AuthToken()
 		fakeUAA.Start()
 
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+		proxy = nil
+	})
+
+	JustBeforeEach(func() {
 		authTokenRefresher, err = New(
-			fakeUAA.URL(), "client-id", "client-secret", true,
+			fakeUAA.URL(), "client-id", "client-secret", tlsConfig, proxy,
 		)
+		Expect(err).ToNot(HaveOccurred())
 	})
 
 	AfterEach(func() {
@@ -38,4 +57,91 @@ var _ = Describe("UAATokenRefresher", func() {
 		Expect(authToken).To(Equal(fakeToken))
 		Expect(err).ToNot(HaveOccurred())
 	})
+
+	Context("when the UAA URL is missing", func() {
+		It("returns an error", func() {
+			_, err := New("", "client-id", "client-secret", tlsConfig, proxy)
+			Expect(err).To(MatchError("client: missing url"))
+		})
+	})
+
+	Context("when the UAA uses a certificate signed by a custom CA", func() {
+		var (
+			tmpDir string
+		)
+
+		BeforeEach(func() {
+			fakeUAA.Close()
+			fakeUAA.StartTLS()
+
+			tmpDir, err = ioutil.TempDir("", "uaa_token_refresher_test")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		Context("and the CA is trusted", func() {
+			BeforeEach(func() {
+				caCertFile := filepath.Join(tmpDir, "ca.pem")
+				caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: fakeUAA.Certificate().Raw})
+				Expect(ioutil.WriteFile(caCertFile, caCert, 0600)).To(Succeed())
+
+				tlsConfig, err = httpclient.Config{CACertFile: caCertFile}.TLSConfig()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("fetches a token from the UAA", func() {
+				authToken, err := authTokenRefresher.RefreshAuthToken()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(authToken).To(Equal(fakeToken))
+			})
+		})
+
+		Context("and the CA is not trusted", func() {
+			BeforeEach(func() {
+				tlsConfig = &tls.Config{}
+			})
+
+			It("returns an error", func() {
+				_, err := authTokenRefresher.RefreshAuthToken()
+				Expect(err).To(HaveOccurred())
+				Expect(fakeUAA.Requested()).To(BeFalse())
+			})
+		})
+	})
+
+	Context("when using a proxy", func() {
+		var (
+			proxied     chan string
+			proxyServer *httptest.Server
+		)
+
+		BeforeEach(func() {
+			uaaURL, err := url.Parse(fakeUAA.URL())
+			Expect(err).ToNot(HaveOccurred())
+
+			proxied = make(chan string, 1)
+			reverseProxy := httputil.NewSingleHostReverseProxy(uaaURL)
+			proxyServer = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				proxied <- r.URL.String()
+				reverseProxy.ServeHTTP(rw, r)
+			}))
+
+			proxy, err = httpclient.Config{ProxyURL: proxyServer.URL}.Proxy()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			proxyServer.Close()
+		})
+
+		It("fetches a token from the UAA through the proxy", func() {
+			authToken, err := authTokenRefresher.RefreshAuthToken()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authToken).To(Equal(fakeToken))
+			Expect(<-proxied).To(Equal(fakeUAA.URL() + "/oauth/token"))
+		})
+	})
 })
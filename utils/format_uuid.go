@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+)
+
+func FormatUUID(uuid *events.UUID) string {
+	if uuid == nil {
+		return ""
+	}
+
+	var bytes [16]byte
+	binary.LittleEndian.PutUint64(bytes[:8], uuid.GetLow())
+	binary.LittleEndian.PutUint64(bytes[8:], uuid.GetHigh())
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:])
+}
+
+func ParseUUID(uuid string) *events.UUID {
+	bytes, err := hex.DecodeString(strings.Replace(uuid, "-", "", -1))
+	if err != nil || len(bytes) != 16 {
+		return nil
+	}
+
+	return &events.UUID{
+		Low:  proto.Uint64(binary.LittleEndian.Uint64(bytes[:8])),
+		High: proto.Uint64(binary.LittleEndian.Uint64(bytes[8:])),
+	}
+}
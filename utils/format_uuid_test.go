@@ -0,0 +1,40 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/utils"
+)
+
+var _ = Describe("FormatUUID", func() {
+	It("formats an uuid", func() {
+		uuid := &events.UUID{
+			Low:  proto.Uint64(0x7243cc580bc17af4),
+			High: proto.Uint64(0x79d4c3b2020e67a5),
+		}
+		Expect(FormatUUID(uuid)).To(Equal("f47ac10b-58cc-4372-a567-0e02b2c3d479"))
+	})
+
+	It("returns an empty string when uuid is nil", func() {
+		Expect(FormatUUID(nil)).To(Equal(""))
+	})
+})
+
+var _ = Describe("ParseUUID", func() {
+	It("parses an uuid", func() {
+		uuid := &events.UUID{
+			Low:  proto.Uint64(0x7243cc580bc17af4),
+			High: proto.Uint64(0x79d4c3b2020e67a5),
+		}
+		Expect(ParseUUID("f47ac10b-58cc-4372-a567-0e02b2c3d479")).To(Equal(uuid))
+	})
+
+	It("returns nil when uuid is not valid", func() {
+		Expect(ParseUUID("not-an-uuid")).To(BeNil())
+		Expect(ParseUUID("")).To(BeNil())
+	})
+})
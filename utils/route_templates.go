@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UnmatchedRouteTemplate is returned by Match for the paths no template matches. Templates start with a
+// `/`, so it cannot be confused with one of them.
+const UnmatchedRouteTemplate = "unmatched"
+
+var (
+	templateParameterRE = regexp.MustCompile(`^\{[a-zA-Z0-9_]+\}$`)
+)
+
+type RouteTemplates struct {
+	templates []string
+	patterns  []*regexp.Regexp
+}
+
+// NewRouteTemplates compiles URI path templates like `/v2/apps/{guid}/stats`, where `{name}`
+// matches a single path segment and a trailing `**` matches any remaining segments.
+func NewRouteTemplates(templates []string) (*RouteTemplates, error) {
+	routeTemplates := &RouteTemplates{}
+
+	for _, template := range templates {
+		pattern, err := compileRouteTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+
+		routeTemplates.templates = append(routeTemplates.templates, template)
+		routeTemplates.patterns = append(routeTemplates.patterns, pattern)
+	}
+
+	return routeTemplates, nil
+}
+
+// Match returns the first template matching the path, or UnmatchedRouteTemplate if none matches.
+func (t *RouteTemplates) Match(path string) string {
+	for i, pattern := range t.patterns {
+		if pattern.MatchString(path) {
+			return t.templates[i]
+		}
+	}
+
+	return UnmatchedRouteTemplate
+}
+
+func compileRouteTemplate(template string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("Route template `%s` must start with `/`", template)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(template, "/"), "/")
+
+	var buffer []string
+	for i, segment := range segments {
+		switch {
+		case segment == "**":
+			if i != len(segments)-1 {
+				return nil, fmt.Errorf("Route template `%s` can only use `**` as last segment", template)
+			}
+			buffer = append(buffer, "(/.*)?")
+		case templateParameterRE.MatchString(segment):
+			buffer = append(buffer, "/[^/]+")
+		default:
+			buffer = append(buffer, "/"+regexp.QuoteMeta(segment))
+		}
+	}
+
+	return regexp.Compile("^" + strings.Join(buffer, "") + "$")
+}
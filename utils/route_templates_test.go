@@ -0,0 +1,81 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/firehose_exporter/utils"
+)
+
+var _ = Describe("RouteTemplates", func() {
+	var (
+		err       error
+		templates []string
+
+		routeTemplates *RouteTemplates
+	)
+
+	BeforeEach(func() {
+		templates = []string{"/v2/apps/{guid}/stats", "/v2/apps/{guid}", "/static/**"}
+	})
+
+	JustBeforeEach(func() {
+		routeTemplates, err = NewRouteTemplates(templates)
+	})
+
+	Describe("New", func() {
+		It("does not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when a template does not start with a slash", func() {
+			BeforeEach(func() {
+				templates = []string{"v2/apps"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Route template `v2/apps` must start with `/`"))
+			})
+		})
+
+		Context("when a template uses `**` before the last segment", func() {
+			BeforeEach(func() {
+				templates = []string{"/static/**/images"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Route template `/static/**/images` can only use `**` as last segment"))
+			})
+		})
+	})
+
+	Describe("Match", func() {
+		It("matches a template with parameters", func() {
+			Expect(routeTemplates.Match("/v2/apps/fake-guid/stats")).To(Equal("/v2/apps/{guid}/stats"))
+			Expect(routeTemplates.Match("/v2/apps/fake-guid")).To(Equal("/v2/apps/{guid}"))
+		})
+
+		It("matches a template with a trailing wildcard", func() {
+			Expect(routeTemplates.Match("/static")).To(Equal("/static/**"))
+			Expect(routeTemplates.Match("/static/css/main.css")).To(Equal("/static/**"))
+		})
+
+		It("does not match other paths", func() {
+			Expect(routeTemplates.Match("/v2/apps")).To(Equal(UnmatchedRouteTemplate))
+			Expect(routeTemplates.Match("/v2/apps/fake-guid/env/extra")).To(Equal(UnmatchedRouteTemplate))
+			Expect(routeTemplates.Match("/staticfiles")).To(Equal(UnmatchedRouteTemplate))
+		})
+
+		Context("when there are no templates", func() {
+			BeforeEach(func() {
+				templates = []string{}
+			})
+
+			It("does not match any path", func() {
+				Expect(routeTemplates.Match("/v2/apps")).To(Equal(UnmatchedRouteTemplate))
+			})
+		})
+	})
+})